@@ -0,0 +1,108 @@
+package alert
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bldsoft/gost/alert/notify"
+	"github.com/bldsoft/gost/breaker"
+	"github.com/bldsoft/gost/utils/poly"
+)
+
+const (
+	// BreakerMetaKey is the alert metadata key holding the circuit breaker name.
+	BreakerMetaKey = "breaker"
+	// BreakerSourceIDPrefix prefixes the breaker name in the alert source ID.
+	BreakerSourceIDPrefix = "breaker:"
+
+	defaultBreakerSourceInterval = 10 * time.Second
+	// maxBreakerResolvedAlerts limits the resolved alerts waiting for the evaluation, the oldest ones are dropped.
+	maxBreakerResolvedAlerts = 1000
+)
+
+// BreakerSource raises an alert while a circuit breaker of the registry is open.
+// The alert fires when the breaker opens and is resolved when it closes, half-open breakers keep it firing.
+// The breakers are local to the instance, so BreakerSource is a LocalSource and isn't sharded.
+type BreakerSource struct {
+	severity  SeverityLevel
+	interval  time.Duration
+	receivers []poly.Poly[notify.Receiver]
+
+	mtx      sync.Mutex
+	firing   map[string]time.Time
+	resolved []Alert
+}
+
+func NewBreakerSource(registry *breaker.Registry, severity SeverityLevel) *BreakerSource {
+	s := &BreakerSource{
+		severity: severity,
+		interval: defaultBreakerSourceInterval,
+		firing:   make(map[string]time.Time),
+	}
+	registry.OnStateChange(s.onStateChange)
+	return s
+}
+
+// SetInterval sets the evaluation interval, i.e. how soon the state changes are handled.
+func (s *BreakerSource) SetInterval(d time.Duration) *BreakerSource {
+	s.interval = d
+	return s
+}
+
+func (s *BreakerSource) SetReceivers(receivers ...poly.Poly[notify.Receiver]) *BreakerSource {
+	s.receivers = receivers
+	return s
+}
+
+func (s *BreakerSource) onStateChange(name string, from, to breaker.State) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	now := time.Now()
+	switch to {
+	case breaker.StateOpen:
+		if _, ok := s.firing[name]; !ok {
+			s.firing[name] = now
+		}
+	case breaker.StateClosed:
+		if firingFrom, ok := s.firing[name]; ok {
+			a := s.alert(name, firingFrom)
+			a.To = now
+			s.resolved = append(s.resolved, a)
+			if len(s.resolved) > maxBreakerResolvedAlerts {
+				s.resolved = slices.Delete(s.resolved, 0, 1)
+			}
+			delete(s.firing, name)
+		}
+	}
+}
+
+func (s *BreakerSource) alert(name string, from time.Time) Alert {
+	return Alert{
+		SourceID:  BreakerSourceIDPrefix + name,
+		Severity:  s.severity,
+		From:      from,
+		Receivers: s.receivers,
+		MetaData:  map[string]any{BreakerMetaKey: name},
+	}
+}
+
+// Local reports that the source watches the breakers of its own instance, see LocalSource.
+func (s *BreakerSource) Local() bool {
+	return true
+}
+
+func (s *BreakerSource) EvaluateAlerts(ctx context.Context) ([]Alert, time.Time, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	alerts := s.resolved
+	s.resolved = nil
+	firing := make([]Alert, 0, len(s.firing))
+	for name, from := range s.firing {
+		firing = append(firing, s.alert(name, from))
+	}
+	slices.SortFunc(firing, func(a, b Alert) int { return strings.Compare(a.SourceID, b.SourceID) })
+	return append(alerts, firing...), time.Now().Add(s.interval), nil
+}
@@ -0,0 +1,57 @@
+package alert
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bldsoft/gost/breaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakerSource(t *testing.T) {
+	ctx := context.Background()
+	registry := breaker.NewRegistry()
+	source := NewBreakerSource(registry, SeverityLevelHigh)
+	cb := registry.NewCircuitBreaker(breaker.Settings{Name: "db"})
+
+	alerts, _, err := source.EvaluateAlerts(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+
+	cb.ForceOpen()
+	alerts, _, _ = source.EvaluateAlerts(ctx)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "breaker:db", alerts[0].SourceID)
+	assert.Equal(t, SeverityLevelHigh, alerts[0].Severity)
+	assert.Equal(t, "db", alerts[0].MetaData[BreakerMetaKey])
+	assert.True(t, alerts[0].To.IsZero())
+	from := alerts[0].From
+
+	alerts, _, _ = source.EvaluateAlerts(ctx)
+	require.Len(t, alerts, 1, "keeps firing")
+	assert.Equal(t, from, alerts[0].From)
+
+	cb.Reset()
+	alerts, _, _ = source.EvaluateAlerts(ctx)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, from, alerts[0].From)
+	assert.False(t, alerts[0].To.IsZero(), "resolved")
+
+	alerts, _, _ = source.EvaluateAlerts(ctx)
+	assert.Empty(t, alerts)
+}
+
+func TestBreakerSourceResolvedLimit(t *testing.T) {
+	ctx := context.Background()
+	registry := breaker.NewRegistry()
+	source := NewBreakerSource(registry, SeverityLevelHigh)
+	cb := registry.NewCircuitBreaker(breaker.Settings{Name: "db"})
+	for range maxBreakerResolvedAlerts + 10 {
+		cb.ForceOpen()
+		cb.Reset()
+	}
+	alerts, _, err := source.EvaluateAlerts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, alerts, maxBreakerResolvedAlerts, "the resolved alerts are capped if nobody evaluates them")
+}
@@ -0,0 +1,52 @@
+package clickhouse
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/bldsoft/gost/alert/threshold"
+	"github.com/bldsoft/gost/clickhouse"
+)
+
+// Querier runs the threshold rule queries in ClickHouse.
+type Querier struct {
+	rep clickhouse.BaseRepository
+}
+
+func NewQuerier(storage *clickhouse.Storage) *Querier {
+	return &Querier{rep: clickhouse.NewBaseRepository(storage)}
+}
+
+// Query runs the query as a subquery, so the rule can use any SELECT statement.
+func (q *Querier) Query(ctx context.Context, query string) ([]threshold.Row, error) {
+	rows, err := q.rep.RunSelectTraced(ctx, sq.Select("*").From("("+query+")"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var res []threshold.Row
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(threshold.Row, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+// Compile time checks to ensure your type satisfies an interface
+var _ threshold.Querier = (*Querier)(nil)
@@ -0,0 +1,83 @@
+package alert
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bldsoft/gost/controller"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// HistoryController serves the alert history.
+type HistoryController struct {
+	controller.BaseController
+	rep HistoryRepository
+}
+
+func NewHistoryController(rep HistoryRepository) *HistoryController {
+	return &HistoryController{rep: rep}
+}
+
+func (c *HistoryController) responseError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrObjectNotFound):
+		c.ResponseError(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	default:
+		log.FromContext(r.Context()).Error(err.Error())
+		c.ResponseError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// GetAlertsHandler lists the alerts, the most recent first.
+// @Summary list alerts
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param offset query int false "Offset"
+// @Param limit query int false "Limit"
+// @Param sourceID query []string false "Source IDs"
+// @Param severity query []string false "Severities"
+// @Param state query []string false "States: pending, firing or resolved"
+// @Param from query int false "Start of the time range, unix seconds"
+// @Param to query int false "End of the time range, unix seconds"
+// @Produce json
+// @Success 200 {object} History "OK"
+// @Router /alerts [get]
+func (c *HistoryController) GetAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := utils.FromRequest[HistoryParams](r)
+	if err != nil {
+		c.ResponseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	history, err := c.rep.FindAlerts(r.Context(), params)
+	if err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, history)
+}
+
+// GetAlertHandler gets a single alert.
+// @Summary get alert
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param id path string true "Alert ID"
+// @Produce json
+// @Success 200 {object} AlertRecord "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /alerts/{id} [get]
+func (c *HistoryController) GetAlertHandler(w http.ResponseWriter, r *http.Request) {
+	record, err := c.rep.FindAlertByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, record)
+}
+
+// Mount it with r.Route("/alerts", c.Mount).
+func (c *HistoryController) Mount(r chi.Router) {
+	r.Get("/", c.GetAlertsHandler)
+	r.Get("/{id}", c.GetAlertHandler)
+}
@@ -0,0 +1,347 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bldsoft/gost/alert/notify"
+	"github.com/bldsoft/gost/auth"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/repository"
+	"github.com/bldsoft/gost/utils"
+	"github.com/bldsoft/gost/utils/poly"
+)
+
+const (
+	// EscalationIDMsgKey is the alert metadata key holding the escalation ID, use it to acknowledge the alert.
+	EscalationIDMsgKey = "escalationID"
+	// EscalationStepMsgKey is the alert metadata key holding the escalation step of a re-notification.
+	EscalationStepMsgKey = "escalationStep"
+
+	defaultEscalationPollInterval = 30 * time.Second
+)
+
+// EscalationPolicy re-notifies Receivers if an alert with severity ≥ MinSeverity
+// isn't acknowledged within AckTimeout. It's repeated every AckTimeout up to MaxSteps times.
+type EscalationPolicy struct {
+	MinSeverity SeverityLevel                `bson:"minSeverity" json:"minSeverity"`
+	AckTimeout  time.Duration                `bson:"ackTimeout" json:"ackTimeout"`
+	MaxSteps    int                          `bson:"maxSteps" json:"maxSteps"`
+	Receivers   []poly.Poly[notify.Receiver] `bson:"receivers" json:"receivers"`
+}
+
+// Escalation is the escalation state of an alert.
+// NextAt is nil if the alert is acknowledged or all escalation steps are done.
+type Escalation struct {
+	ID          string           `bson:"_id" json:"id"`
+	ProcessorID string           `bson:"processorID" json:"processorID"`
+	Alert       Alert            `bson:"alert" json:"alert"`
+	Policy      EscalationPolicy `bson:"policy" json:"policy"`
+	Step        int              `bson:"step" json:"step"`
+	NextAt      *time.Time       `bson:"nextAt,omitempty" json:"nextAt,omitempty"`
+	AckedAt     *time.Time       `bson:"ackedAt,omitempty" json:"ackedAt,omitempty"`
+	AckedBy     string           `bson:"ackedBy,omitempty" json:"ackedBy,omitempty"`
+}
+
+func (e *Escalation) scheduleNext(now time.Time) {
+	if e.AckedAt != nil || e.Step >= e.Policy.MaxSteps {
+		e.NextAt = nil
+		return
+	}
+	next := now.Add(e.Policy.AckTimeout)
+	e.NextAt = &next
+}
+
+// EscalationID returns the ID of the escalation of the alert raised by the processor.
+func EscalationID(processorID string, a Alert) string {
+	return fmt.Sprintf("%s:%s:%s", processorID, a.SourceID, a.Severity)
+}
+
+// EscalationStore persists the escalation state, so it survives restarts. The escalations are shared
+// by the replicas, so every update changes only the fields it's responsible for.
+// Get, Ack and Unack return utils.ErrObjectNotFound if there is no escalation with the ID.
+type EscalationStore interface {
+	Get(ctx context.Context, id string) (*Escalation, error)
+	// Track inserts the escalation if it doesn't exist, otherwise it only updates the alert,
+	// so the step and the acknowledgement saved by other instances are kept.
+	Track(ctx context.Context, e *Escalation) error
+	// Ack saves the acknowledgement and stops the escalation.
+	Ack(ctx context.Context, id, by string, at time.Time) error
+	// Unack removes the acknowledgement and schedules the next step AckTimeout after now,
+	// unless all escalation steps are done.
+	Unack(ctx context.Context, id string, now time.Time) error
+	Delete(ctx context.Context, id string) error
+	// Due returns the escalations with NextAt ≤ now.
+	Due(ctx context.Context, now time.Time) ([]*Escalation, error)
+	// Claim atomically saves the step and NextAt of the escalation if it's still at prevStep and due at now.
+	// It returns false if the escalation has changed, e.g. it's claimed by another instance or acknowledged,
+	// so every step is sent by one instance only.
+	Claim(ctx context.Context, e *Escalation, prevStep int, now time.Time) (bool, error)
+	List(ctx context.Context) ([]*Escalation, error)
+}
+
+// EscalationNotifier sends the alert to the escalation receivers. NotifyServiceAdapter implements it.
+type EscalationNotifier interface {
+	SendTo(ctx context.Context, alert Alert, receivers ...poly.Poly[notify.Receiver]) error
+}
+
+// Escalator tracks the alerts of the processors with an escalation policy and re-notifies the
+// escalation receivers until the alert is acknowledged or resolved.
+type Escalator struct {
+	store        EscalationStore
+	notifier     EscalationNotifier
+	pollInterval time.Duration
+	mtx          sync.Mutex // serializes the claims of the due escalations
+}
+
+func NewEscalator(store EscalationStore, notifier EscalationNotifier) *Escalator {
+	return &Escalator{
+		store:        store,
+		notifier:     notifier,
+		pollInterval: defaultEscalationPollInterval,
+	}
+}
+
+// SetPollInterval sets how often the due escalations are checked.
+func (e *Escalator) SetPollInterval(d time.Duration) *Escalator {
+	e.pollInterval = d
+	return e
+}
+
+// Middleware starts the escalation of the alerts matching the escalation policy of the processor
+// and stops it when they are resolved. The escalation ID is added to the alert metadata under EscalationIDMsgKey.
+// The processor is taken from the context, see WithProcessor. Put the middleware after the ones dropping the alerts,
+// e.g. silences, inhibition and deduplication, so the dropped alerts aren't escalated.
+func (e *Escalator) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, alerts ...Alert) {
+			p := ProcessorFromContext(ctx)
+			if p == nil || p.Escalation == nil {
+				next.Handle(ctx, alerts...)
+				return
+			}
+			res := make([]Alert, 0, len(alerts))
+			for _, a := range alerts {
+				if a.Severity < p.Escalation.MinSeverity {
+					res = append(res, a)
+					continue
+				}
+				id := EscalationID(p.ID, a)
+				if err := e.track(ctx, id, p.ID, a, *p.Escalation); err != nil {
+					log.FromContext(ctx).ErrorWithFields(log.Fields{"id": id, "error": err}, "Failed to track alert escalation")
+				}
+				res = append(res, withMetaData(a, EscalationIDMsgKey, id))
+			}
+			next.Handle(ctx, res...)
+		})
+	}
+}
+
+func (e *Escalator) track(ctx context.Context, id, processorID string, a Alert, policy EscalationPolicy) error {
+	if !a.To.IsZero() {
+		err := e.store.Delete(ctx, id)
+		if errors.Is(err, utils.ErrObjectNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	esc := &Escalation{ID: id, ProcessorID: processorID, Alert: a, Policy: policy}
+	esc.scheduleNext(time.Now())
+	return e.store.Track(ctx, esc)
+}
+
+// Ack acknowledges the alert on behalf of the user of the context, the escalation stops.
+func (e *Escalator) Ack(ctx context.Context, id string) error {
+	var by string
+	if user, ok := auth.UserFromContext(ctx).(repository.IEntityID); ok {
+		by = user.StringID()
+	}
+	return e.store.Ack(ctx, id, by, time.Now())
+}
+
+// Unack withdraws the acknowledgement, the escalation continues after the ack timeout.
+func (e *Escalator) Unack(ctx context.Context, id string) error {
+	return e.store.Unack(ctx, id, time.Now())
+}
+
+func (e *Escalator) Get(ctx context.Context, id string) (*Escalation, error) {
+	return e.store.Get(ctx, id)
+}
+
+func (e *Escalator) List(ctx context.Context) ([]*Escalation, error) {
+	return e.store.List(ctx)
+}
+
+// Run re-notifies the due escalations until ctx is done.
+func (e *Escalator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := e.escalate(ctx, time.Now()); err != nil {
+				log.FromContext(ctx).ErrorWithFields(log.Fields{"error": err}, "Failed to escalate alerts")
+			}
+		}
+	}
+}
+
+func (e *Escalator) escalate(ctx context.Context, now time.Time) error {
+	claimed, errs := e.claimDue(ctx, now)
+	// the escalations are sent without the lock, so a slow receiver doesn't block the middleware
+	for _, esc := range claimed {
+		a := withMetaData(esc.Alert, EscalationIDMsgKey, esc.ID)
+		a = withMetaData(a, EscalationStepMsgKey, esc.Step)
+		if err := e.notifier.SendTo(ctx, a, esc.Policy.Receivers...); err != nil {
+			// the step is counted anyway, the notify service retries failed notifications
+			errs = errors.Join(errs, fmt.Errorf("escalation %s: %w", esc.ID, err))
+		}
+	}
+	return errs
+}
+
+// claimDue claims the next step of the due escalations.
+func (e *Escalator) claimDue(ctx context.Context, now time.Time) ([]*Escalation, error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	due, err := e.store.Due(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		claimed []*Escalation
+		errs    error
+	)
+	for _, esc := range due {
+		prevStep := esc.Step
+		esc.Step++
+		esc.scheduleNext(now)
+		ok, err := e.store.Claim(ctx, esc, prevStep, now)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("escalation %s: %w", esc.ID, err))
+			continue
+		}
+		if ok {
+			claimed = append(claimed, esc)
+		}
+	}
+	return claimed, errs
+}
+
+// withMetaData returns a copy of the alert with the metadata set, the original metadata isn't modified.
+func withMetaData(a Alert, key string, value any) Alert {
+	a.MetaData = maps.Clone(a.MetaData)
+	return a.AddMetaData(key, value)
+}
+
+// MemoryEscalationStore is an EscalationStore that doesn't survive restarts, use it in tests or single instance setups.
+type MemoryEscalationStore struct {
+	mtx         sync.Mutex
+	escalations map[string]Escalation
+}
+
+func NewMemoryEscalationStore() *MemoryEscalationStore {
+	return &MemoryEscalationStore{escalations: make(map[string]Escalation)}
+}
+
+func (s *MemoryEscalationStore) Get(ctx context.Context, id string) (*Escalation, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	esc, ok := s.escalations[id]
+	if !ok {
+		return nil, utils.ErrObjectNotFound
+	}
+	return &esc, nil
+}
+
+func (s *MemoryEscalationStore) Track(ctx context.Context, e *Escalation) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if cur, ok := s.escalations[e.ID]; ok {
+		cur.Alert = e.Alert
+		s.escalations[e.ID] = cur
+		return nil
+	}
+	s.escalations[e.ID] = *e
+	return nil
+}
+
+func (s *MemoryEscalationStore) Ack(ctx context.Context, id, by string, at time.Time) error {
+	return s.update(id, func(esc *Escalation) {
+		esc.AckedAt, esc.AckedBy = &at, by
+		esc.scheduleNext(at)
+	})
+}
+
+func (s *MemoryEscalationStore) Unack(ctx context.Context, id string, now time.Time) error {
+	return s.update(id, func(esc *Escalation) {
+		esc.AckedAt, esc.AckedBy = nil, ""
+		esc.scheduleNext(now)
+	})
+}
+
+func (s *MemoryEscalationStore) update(id string, f func(esc *Escalation)) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	esc, ok := s.escalations[id]
+	if !ok {
+		return utils.ErrObjectNotFound
+	}
+	f(&esc)
+	s.escalations[id] = esc
+	return nil
+}
+
+func (s *MemoryEscalationStore) Delete(ctx context.Context, id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.escalations[id]; !ok {
+		return utils.ErrObjectNotFound
+	}
+	delete(s.escalations, id)
+	return nil
+}
+
+func (s *MemoryEscalationStore) Due(ctx context.Context, now time.Time) ([]*Escalation, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var res []*Escalation
+	for _, esc := range s.escalations {
+		if esc.NextAt != nil && !esc.NextAt.After(now) {
+			res = append(res, &esc)
+		}
+	}
+	return res, nil
+}
+
+func (s *MemoryEscalationStore) Claim(ctx context.Context, e *Escalation, prevStep int, now time.Time) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	cur, ok := s.escalations[e.ID]
+	if !ok || cur.Step != prevStep || cur.NextAt == nil || cur.NextAt.After(now) {
+		return false, nil
+	}
+	cur.Step, cur.NextAt = e.Step, e.NextAt
+	s.escalations[e.ID] = cur
+	return true, nil
+}
+
+func (s *MemoryEscalationStore) List(ctx context.Context) ([]*Escalation, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	res := make([]*Escalation, 0, len(s.escalations))
+	for _, id := range slices.Sorted(maps.Keys(s.escalations)) {
+		esc := s.escalations[id]
+		res = append(res, &esc)
+	}
+	return res, nil
+}
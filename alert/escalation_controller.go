@@ -0,0 +1,105 @@
+package alert
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bldsoft/gost/controller"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// EscalationController lists the escalations and acknowledges the alerts.
+type EscalationController struct {
+	controller.BaseController
+	escalator *Escalator
+}
+
+func NewEscalationController(escalator *Escalator) *EscalationController {
+	return &EscalationController{escalator: escalator}
+}
+
+func (c *EscalationController) responseError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrObjectNotFound):
+		c.ResponseError(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	default:
+		log.FromContext(r.Context()).Error(err.Error())
+		c.ResponseError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// GetEscalationsHandler lists the escalations.
+// @Summary list alert escalations
+// @Tags admin
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} Escalation "OK"
+// @Router /alerts/escalations [get]
+func (c *EscalationController) GetEscalationsHandler(w http.ResponseWriter, r *http.Request) {
+	escalations, err := c.escalator.List(r.Context())
+	if err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, escalations)
+}
+
+// GetEscalationHandler gets a single escalation.
+// @Summary get alert escalation
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param id path string true "Escalation ID"
+// @Produce json
+// @Success 200 {object} Escalation "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /alerts/escalations/{id} [get]
+func (c *EscalationController) GetEscalationHandler(w http.ResponseWriter, r *http.Request) {
+	escalation, err := c.escalator.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, escalation)
+}
+
+// AckHandler acknowledges the alert on behalf of the authenticated user, its escalation stops.
+// @Summary acknowledge alert
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param id path string true "Escalation ID"
+// @Success 200 {string} string "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /alerts/escalations/{id}/ack [post]
+func (c *EscalationController) AckHandler(w http.ResponseWriter, r *http.Request) {
+	if err := c.escalator.Ack(r.Context(), chi.URLParam(r, "id")); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseOK(w)
+}
+
+// UnackHandler withdraws the acknowledgement, the escalation continues.
+// @Summary unacknowledge alert
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param id path string true "Escalation ID"
+// @Success 200 {string} string "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /alerts/escalations/{id}/unack [post]
+func (c *EscalationController) UnackHandler(w http.ResponseWriter, r *http.Request) {
+	if err := c.escalator.Unack(r.Context(), chi.URLParam(r, "id")); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseOK(w)
+}
+
+// Mount it with r.Route("/alerts/escalations", c.Mount).
+func (c *EscalationController) Mount(r chi.Router) {
+	r.Get("/", c.GetEscalationsHandler)
+	r.Get("/{id}", c.GetEscalationHandler)
+	r.Post("/{id}/ack", c.AckHandler)
+	r.Post("/{id}/unack", c.UnackHandler)
+}
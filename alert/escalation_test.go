@@ -0,0 +1,232 @@
+package alert
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/alert/notify"
+	"github.com/bldsoft/gost/alert/notify/channel/webhook"
+	"github.com/bldsoft/gost/auth"
+	"github.com/bldsoft/gost/utils"
+	"github.com/bldsoft/gost/utils/poly"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sentEscalation struct {
+	alert     Alert
+	receivers []poly.Poly[notify.Receiver]
+}
+
+type testEscalationNotifier struct {
+	mtx  sync.Mutex
+	sent []sentEscalation
+}
+
+func (n *testEscalationNotifier) SendTo(ctx context.Context, alert Alert, receivers ...poly.Poly[notify.Receiver]) error {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.sent = append(n.sent, sentEscalation{alert, receivers})
+	return nil
+}
+
+type testUser struct{ id string }
+
+func (u *testUser) RawID() interface{}              { return u.id }
+func (u *testUser) StringID() string                { return u.id }
+func (u *testUser) IsZeroID() bool                  { return u.id == "" }
+func (u *testUser) SetIDFromString(id string) error { u.id = id; return nil }
+func (u *testUser) GenerateID()                     {}
+
+func TestEscalation(t *testing.T) {
+	ctx := context.Background()
+	notifier := &testEscalationNotifier{}
+	escalator := NewEscalator(NewMemoryEscalationStore(), notifier)
+	receivers := []poly.Poly[notify.Receiver]{{Value: webhook.Receiver{}}}
+	policy := EscalationPolicy{
+		MinSeverity: SeverityLevelHigh,
+		AckTimeout:  time.Minute,
+		MaxSteps:    2,
+		Receivers:   receivers,
+	}
+
+	var handled []Alert
+	handler := escalator.Middleware()(HandlerFunc(func(ctx context.Context, alerts ...Alert) {
+		handled = append(handled, alerts...)
+	}))
+	ctx = WithProcessor(ctx, &Processor{ID: "p", Escalation: &policy})
+
+	low := Alert{SourceID: "src", Severity: SeverityLevelLow, From: time.Now()}
+	high := Alert{SourceID: "src", Severity: SeverityLevelHigh, From: time.Now()}
+	handler.Handle(ctx, low, high)
+	require.Len(t, handled, 2)
+	assert.NotContains(t, handled[0].MetaData, EscalationIDMsgKey)
+	id := EscalationID("p", high)
+	assert.Equal(t, id, handled[1].MetaData[EscalationIDMsgKey])
+	assert.Nil(t, high.MetaData, "the original alert isn't modified")
+
+	escalations, err := escalator.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, escalations, 1)
+
+	now := time.Now()
+	require.NoError(t, escalator.escalate(ctx, now))
+	assert.Empty(t, notifier.sent, "ack timeout hasn't expired")
+
+	for step := 1; step <= 3; step++ {
+		now = now.Add(time.Minute)
+		require.NoError(t, escalator.escalate(ctx, now))
+	}
+	require.Len(t, notifier.sent, policy.MaxSteps)
+	for i, sent := range notifier.sent {
+		assert.Equal(t, receivers, sent.receivers)
+		assert.Equal(t, i+1, sent.alert.MetaData[EscalationStepMsgKey])
+		assert.Equal(t, id, sent.alert.MetaData[EscalationIDMsgKey])
+	}
+
+	esc, err := escalator.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 2, esc.Step)
+	assert.Nil(t, esc.NextAt)
+
+	resolved := high
+	resolved.To = time.Now()
+	handler.Handle(ctx, resolved)
+	_, err = escalator.Get(ctx, id)
+	assert.ErrorIs(t, err, utils.ErrObjectNotFound)
+}
+
+func TestEscalationAck(t *testing.T) {
+	ctx := context.Background()
+	notifier := &testEscalationNotifier{}
+	escalator := NewEscalator(NewMemoryEscalationStore(), notifier)
+	policy := EscalationPolicy{AckTimeout: time.Minute, MaxSteps: 3}
+	handler := escalator.Middleware()(HandlerFunc(func(ctx context.Context, alerts ...Alert) {}))
+	ctx = WithProcessor(ctx, &Processor{ID: "p", Escalation: &policy})
+
+	a := Alert{SourceID: "src", From: time.Now()}
+	id := EscalationID("p", a)
+	handler.Handle(ctx, a)
+
+	user := &testUser{id: "admin"}
+	require.NoError(t, escalator.Ack(context.WithValue(ctx, auth.UserEntryCtxKey, user), id))
+	esc, err := escalator.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", esc.AckedBy, "the user is taken from the context")
+	assert.NotNil(t, esc.AckedAt)
+
+	handler.Handle(ctx, a)
+	require.NoError(t, escalator.escalate(ctx, time.Now().Add(time.Hour)))
+	assert.Empty(t, notifier.sent, "acknowledged alert isn't escalated")
+
+	require.NoError(t, escalator.Unack(ctx, id))
+	require.NoError(t, escalator.escalate(ctx, time.Now().Add(time.Hour)))
+	assert.Len(t, notifier.sent, 1)
+
+	assert.ErrorIs(t, escalator.Ack(ctx, "unknown"), utils.ErrObjectNotFound)
+}
+
+func TestEscalationPipeline(t *testing.T) {
+	ctx := context.Background()
+	escalator := NewEscalator(NewMemoryEscalationStore(), &testEscalationNotifier{})
+	policy := EscalationPolicy{AckTimeout: time.Minute, MaxSteps: 1}
+	silence := func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, alerts ...Alert) {
+			var res []Alert
+			for _, a := range alerts {
+				if a.SourceID != "silenced" {
+					res = append(res, a)
+				}
+			}
+			next.Handle(ctx, res...)
+		})
+	}
+	handler := Middlewares(silence, escalator.Middleware())(HandlerFunc(func(ctx context.Context, alerts ...Alert) {}))
+
+	handler.Handle(WithProcessor(ctx, &Processor{ID: "p", Escalation: &policy}),
+		Alert{SourceID: "silenced", From: time.Now()}, Alert{SourceID: "src", From: time.Now()})
+	handler.Handle(WithProcessor(ctx, &Processor{ID: "other"}), Alert{SourceID: "src", From: time.Now()})
+
+	escalations, err := escalator.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, escalations, 1, "the dropped alerts and the processors without a policy aren't escalated")
+	assert.Equal(t, EscalationID("p", Alert{SourceID: "src"}), escalations[0].ID)
+}
+
+func TestEscalationReplicas(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEscalationStore()
+	notifier := &testEscalationNotifier{}
+	// the escalators sharing the store emulate the replicas
+	replicas := []*Escalator{NewEscalator(store, notifier), NewEscalator(store, notifier), NewEscalator(store, notifier)}
+	policy := EscalationPolicy{AckTimeout: time.Minute, MaxSteps: 2}
+	handler := replicas[0].Middleware()(HandlerFunc(func(ctx context.Context, alerts ...Alert) {}))
+	handler.Handle(WithProcessor(ctx, &Processor{ID: "p", Escalation: &policy}), Alert{SourceID: "src", From: time.Now()})
+
+	now := time.Now().Add(time.Minute)
+	var wg sync.WaitGroup
+	for _, e := range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, e.escalate(ctx, now))
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, notifier.sent, 1, "the step is sent once")
+}
+
+func TestEscalationTrackKeepsState(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEscalationStore()
+	notifier := &testEscalationNotifier{}
+	a, b := NewEscalator(store, notifier), NewEscalator(store, notifier)
+	policy := EscalationPolicy{AckTimeout: time.Minute, MaxSteps: 2}
+	ctx = WithProcessor(ctx, &Processor{ID: "p", Escalation: &policy})
+	handler := a.Middleware()(HandlerFunc(func(ctx context.Context, alerts ...Alert) {}))
+	alert := Alert{SourceID: "src", From: time.Now()}
+	id := EscalationID("p", alert)
+	handler.Handle(ctx, alert)
+
+	require.NoError(t, b.escalate(ctx, time.Now().Add(time.Minute)))
+	require.NoError(t, b.Ack(ctx, id))
+	handler.Handle(ctx, alert)
+
+	esc, err := store.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 1, esc.Step, "the claimed step isn't rolled back")
+	assert.NotNil(t, esc.AckedAt, "the acknowledgement of another replica is kept")
+	assert.Nil(t, esc.NextAt)
+}
+
+type blockingEscalationNotifier struct {
+	sending, release chan struct{}
+}
+
+func (n *blockingEscalationNotifier) SendTo(ctx context.Context, alert Alert, receivers ...poly.Poly[notify.Receiver]) error {
+	close(n.sending)
+	<-n.release
+	return nil
+}
+
+func TestEscalationSendUnlocked(t *testing.T) {
+	ctx := context.Background()
+	notifier := &blockingEscalationNotifier{sending: make(chan struct{}), release: make(chan struct{})}
+	escalator := NewEscalator(NewMemoryEscalationStore(), notifier)
+	policy := EscalationPolicy{AckTimeout: time.Minute, MaxSteps: 1}
+	ctx = WithProcessor(ctx, &Processor{ID: "p", Escalation: &policy})
+	handler := escalator.Middleware()(HandlerFunc(func(ctx context.Context, alerts ...Alert) {}))
+	handler.Handle(ctx, Alert{SourceID: "src", From: time.Now()})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, escalator.escalate(ctx, time.Now().Add(time.Minute)))
+	}()
+	<-notifier.sending
+	handler.Handle(ctx, Alert{SourceID: "other", From: time.Now()}) // blocks forever if the send holds the lock
+	close(notifier.release)
+	<-done
+}
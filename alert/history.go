@@ -0,0 +1,134 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/bldsoft/gost/utils"
+)
+
+// AlertState is a stage of the alert lifecycle: pending → firing → resolved.
+type AlertState string
+
+const (
+	// AlertStatePending is an alert that isn't active long enough to fire.
+	AlertStatePending AlertState = "pending"
+	AlertStateFiring  AlertState = "firing"
+	// AlertStateResolved is final, a new record is created if the alert is raised again.
+	AlertStateResolved AlertState = "resolved"
+)
+
+var ErrInvalidStateTransition = errors.New("alert: invalid state transition")
+
+// CanTransitionTo reports whether the lifecycle allows to move from s to next.
+func (s AlertState) CanTransitionTo(next AlertState) bool {
+	switch s {
+	case AlertStatePending:
+		return true
+	case AlertStateFiring:
+		return next != AlertStatePending
+	default:
+		return false
+	}
+}
+
+// AlertRecord is an alert with its lifecycle state, stored by HistoryRepository.
+type AlertRecord struct {
+	ID         string `json:"id" bson:"-"`
+	Alert      `bson:",inline"`
+	State      AlertState `json:"state" bson:"state"`
+	FiredAt    *time.Time `json:"firedAt,omitempty" bson:"firedAt,omitempty"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty" bson:"resolvedAt,omitempty"`
+	UpdatedAt  time.Time  `json:"updatedAt" bson:"updatedAt"`
+}
+
+// NewAlertRecord starts the lifecycle of the alert. The alert is pending until it's active for pendingFor,
+// with zero pendingFor it fires immediately. An alert with To set is created resolved.
+func NewAlertRecord(a Alert, pendingFor time.Duration, now time.Time) AlertRecord {
+	r := AlertRecord{Alert: a, State: AlertStatePending, UpdatedAt: now}
+	r.State = r.nextState(a, pendingFor, now)
+	r.setTimes(now)
+	return r
+}
+
+// Update moves the record to the state of the updated alert: it resolves at a.To if it's set,
+// a pending alert fires once it's active for pendingFor. The start of the alert is kept.
+// ErrInvalidStateTransition is returned for a resolved record.
+func (r *AlertRecord) Update(a Alert, pendingFor time.Duration, now time.Time) error {
+	if a.From.IsZero() || r.From.Before(a.From) {
+		a.From = r.From
+	}
+	next := r.nextState(a, pendingFor, now)
+	if !r.State.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s → %s", ErrInvalidStateTransition, r.State, next)
+	}
+	r.Alert, r.State, r.UpdatedAt = a, next, now
+	r.setTimes(now)
+	return nil
+}
+
+func (r *AlertRecord) nextState(a Alert, pendingFor time.Duration, now time.Time) AlertState {
+	switch {
+	case !a.To.IsZero():
+		return AlertStateResolved
+	case r.State == AlertStateFiring || now.Sub(a.From) >= pendingFor:
+		return AlertStateFiring
+	default:
+		return AlertStatePending
+	}
+}
+
+func (r *AlertRecord) setTimes(now time.Time) {
+	if r.State == AlertStateFiring && r.FiredAt == nil {
+		r.FiredAt = &now
+	}
+	if r.State == AlertStateResolved && r.ResolvedAt == nil {
+		resolvedAt := r.To
+		r.ResolvedAt = &resolvedAt
+	}
+}
+
+// HistoryFilter selects the alert records. Empty fields match any record.
+// From and To select the records active at any moment of [From, To).
+type HistoryFilter struct {
+	SourceIDs  []string        `json:"sourceIDs,omitempty" schema:"sourceID,omitempty"`
+	Severities []SeverityLevel `json:"severities,omitempty" schema:"severity,omitempty"`
+	States     []AlertState    `json:"states,omitempty" schema:"state,omitempty"`
+	From       time.Time       `json:"from,omitempty" schema:"from,omitempty"`
+	To         time.Time       `json:"to,omitempty" schema:"to,omitempty"`
+}
+
+type HistoryParams struct {
+	Offset        int64 `json:"offset,omitempty" schema:"offset,omitempty"`
+	Limit         int64 `json:"limit,omitempty" schema:"limit,omitempty"`
+	HistoryFilter `schema:",omitempty"`
+}
+
+type History struct {
+	Alerts     []AlertRecord `json:"alerts"`
+	TotalCount int64         `json:"totalCount"`
+}
+
+// HistoryRepository is a Repository keeping the lifecycle history of the alerts.
+// The alert is identified by the source ID and the severity, GetAlert returns the latest record
+// and UpdateAlert updates the latest not resolved one.
+type HistoryRepository interface {
+	Repository
+	FindAlerts(ctx context.Context, params *HistoryParams) (*History, error)
+	FindAlertByID(ctx context.Context, id string) (*AlertRecord, error)
+}
+
+func init() {
+	utils.RegisterQueryConverter[SeverityLevel](func(v reflect.Value) string {
+		return v.Interface().(SeverityLevel).String()
+	}, func(s string) reflect.Value {
+		level, err := SeverityLevelString(s)
+		if err != nil {
+			return reflect.Value{}
+		}
+		return reflect.ValueOf(level)
+	})
+}
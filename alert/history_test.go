@@ -0,0 +1,70 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertRecordLifecycle(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := Alert{SourceID: "src", Severity: SeverityLevelHigh, From: start}
+
+	r := NewAlertRecord(a, time.Minute, start)
+	assert.Equal(t, AlertStatePending, r.State)
+	assert.Nil(t, r.FiredAt)
+
+	require.NoError(t, r.Update(a, time.Minute, start.Add(30*time.Second)))
+	assert.Equal(t, AlertStatePending, r.State)
+
+	later := a
+	later.From = start.Add(time.Minute)
+	now := start.Add(time.Minute)
+	require.NoError(t, r.Update(later, time.Minute, now))
+	assert.Equal(t, AlertStateFiring, r.State)
+	assert.Equal(t, start, r.From, "the start of the alert is kept")
+	require.NotNil(t, r.FiredAt)
+	assert.Equal(t, now, *r.FiredAt)
+
+	resolved := a
+	resolved.To = start.Add(5 * time.Minute)
+	require.NoError(t, r.Update(resolved, time.Minute, start.Add(6*time.Minute)))
+	assert.Equal(t, AlertStateResolved, r.State)
+	require.NotNil(t, r.ResolvedAt)
+	assert.Equal(t, resolved.To, *r.ResolvedAt)
+	assert.Equal(t, now, *r.FiredAt)
+
+	assert.ErrorIs(t, r.Update(a, time.Minute, start.Add(7*time.Minute)), ErrInvalidStateTransition)
+}
+
+func TestAlertRecordCreatedState(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, AlertStateFiring, NewAlertRecord(Alert{From: now}, 0, now).State)
+
+	r := NewAlertRecord(Alert{From: now.Add(-time.Minute), To: now}, 0, now)
+	assert.Equal(t, AlertStateResolved, r.State)
+	assert.Nil(t, r.FiredAt)
+	assert.Equal(t, now, *r.ResolvedAt)
+}
+
+func TestHistoryParamsFromQuery(t *testing.T) {
+	params, err := utils.FromQuery[HistoryParams](map[string][]string{
+		"limit":    {"10"},
+		"sourceID": {"a", "b"},
+		"severity": {"high", "critical"},
+		"state":    {"firing"},
+		"from":     {"1700000000"},
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, params.Limit)
+	assert.Equal(t, []string{"a", "b"}, params.SourceIDs)
+	assert.Equal(t, []SeverityLevel{SeverityLevelHigh, SeverityLevelCritical}, params.Severities)
+	assert.Equal(t, []AlertState{AlertStateFiring}, params.States)
+	assert.Equal(t, int64(1700000000), params.From.Unix())
+
+	_, err = utils.FromQuery[HistoryParams](map[string][]string{"severity": {"unknown"}})
+	assert.Error(t, err)
+}
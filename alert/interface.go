@@ -67,6 +67,19 @@ type Source interface {
 	EvaluateAlerts(ctx context.Context) (alerts []Alert, next time.Time, err error)
 }
 
+// LocalSource is a Source evaluating the state of its own instance, e.g. BreakerSource.
+// The manager evaluates its processors on every instance, the Sharder doesn't apply to them.
+// Sources wrapping a LocalSource, e.g. MultiSource, aren't local.
+type LocalSource interface {
+	Source
+	Local() bool
+}
+
+func isLocalSource(s Source) bool {
+	local, ok := s.(LocalSource)
+	return ok && local.Local()
+}
+
 type SourceFunc func(ctx context.Context) ([]Alert, time.Time, error)
 
 func (f SourceFunc) EvaluateAlerts(ctx context.Context) ([]Alert, time.Time, error) {
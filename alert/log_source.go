@@ -0,0 +1,131 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bldsoft/gost/alert/notify"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/repository"
+	"github.com/bldsoft/gost/utils/poly"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// LogCountMetaKey is the alert metadata key holding the number of the matching log records in the window.
+	LogCountMetaKey = "logCount"
+	// LogSamplesMetaKey is the alert metadata key holding the latest matching log messages.
+	LogSamplesMetaKey = "logSamples"
+	// LogRequestIDsMetaKey is the alert metadata key holding the request IDs of the matching log records.
+	LogRequestIDsMetaKey = "requestIDs"
+	// LogRequestCountMetaKey is the alert metadata key holding the number of the requests with matching log records.
+	LogRequestCountMetaKey = "requestCount"
+
+	defaultLogSampleN    = 5
+	defaultLogRequestIDN = 20
+)
+
+// LogRule fires when more than Threshold log records match the Filter during the last Window.
+// Filter.From and Filter.To are set on every evaluation, the other fields, including the search expression,
+// are used as is. MinLevel, if set, replaces Filter.Levels with all levels ≥ MinLevel.
+type LogRule struct {
+	SourceID  string
+	Severity  SeverityLevel
+	Filter    log.Filter
+	MinLevel  *log.Level
+	Window    time.Duration
+	Threshold int64
+	// Interval is the evaluation interval, Window is used if it isn't set.
+	Interval  time.Duration
+	Receivers []poly.Poly[notify.Receiver]
+	// SampleN is the number of the latest messages added to the alert, 5 by default.
+	SampleN int
+	// RequestIDN is the max number of the request IDs added to the alert, 20 by default.
+	RequestIDN int
+}
+
+// LogSource is a Source evaluating a LogRule over the exported logs, e.g. clickhouse.ClickHouseLogExporter.
+type LogSource struct {
+	exporter   log.LogExporter
+	rule       LogRule
+	firingFrom time.Time
+}
+
+func NewLogSource(exporter log.LogExporter, rule LogRule) *LogSource {
+	if rule.Interval <= 0 {
+		rule.Interval = rule.Window
+	}
+	if rule.SampleN <= 0 {
+		rule.SampleN = defaultLogSampleN
+	}
+	if rule.RequestIDN <= 0 {
+		rule.RequestIDN = defaultLogRequestIDN
+	}
+	if rule.MinLevel != nil {
+		rule.Filter.Levels = nil
+		for lvl := *rule.MinLevel; lvl <= zerolog.PanicLevel; lvl++ {
+			rule.Filter.Levels = append(rule.Filter.Levels, lvl)
+		}
+	}
+	return &LogSource{exporter: exporter, rule: rule}
+}
+
+func (s *LogSource) EvaluateAlerts(ctx context.Context) ([]Alert, time.Time, error) {
+	if s.rule.Window <= 0 {
+		return nil, time.Time{}, errors.New("log alert rule: window isn't set")
+	}
+	now := time.Now()
+	next := now.Add(s.rule.Interval)
+
+	filter := s.rule.Filter
+	filter.From, filter.To = now.Add(-s.rule.Window), now
+	logs, err := s.exporter.Logs(ctx, log.LogsParams{
+		Limit:  s.rule.SampleN,
+		Filter: &filter,
+		Sort:   log.Sort{Field: log.SortFieldTimestamp, Order: repository.SortOrderDESC},
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if logs.TotalCount <= s.rule.Threshold {
+		if s.firingFrom.IsZero() {
+			return nil, next, nil
+		}
+		a := s.alert(logs)
+		a.To = now
+		s.firingFrom = time.Time{}
+		return []Alert{a}, next, nil
+	}
+
+	if s.firingFrom.IsZero() {
+		s.firingFrom = now
+	}
+	a := s.alert(logs)
+	requestIDs, requestCount, err := s.exporter.RequestIDs(ctx, filter, &s.rule.RequestIDN)
+	if err != nil {
+		log.FromContext(ctx).ErrorWithFields(log.Fields{"sourceID": s.rule.SourceID, "error": err}, "Failed to get request IDs of log alert")
+	} else {
+		a.MetaData[LogRequestIDsMetaKey] = requestIDs
+		a.MetaData[LogRequestCountMetaKey] = requestCount
+	}
+	return []Alert{a}, next, nil
+}
+
+func (s *LogSource) alert(logs *log.Logs) Alert {
+	samples := make([]string, 0, len(logs.Records))
+	for _, r := range logs.Records {
+		samples = append(samples, r.Msg)
+	}
+	return Alert{
+		SourceID:  s.rule.SourceID,
+		Severity:  s.rule.Severity,
+		From:      s.firingFrom,
+		Receivers: s.rule.Receivers,
+		MetaData: map[string]any{
+			LogCountMetaKey:   logs.TotalCount,
+			LogSamplesMetaKey: samples,
+		},
+	}
+}
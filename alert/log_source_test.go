@@ -0,0 +1,75 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/log"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLogExporter struct {
+	log.LogExporter
+	logs   log.Logs
+	params log.LogsParams
+}
+
+func (e *testLogExporter) Logs(ctx context.Context, params log.LogsParams) (*log.Logs, error) {
+	e.params = params
+	logs := e.logs
+	if len(logs.Records) > params.Limit {
+		logs.Records = logs.Records[:params.Limit]
+	}
+	return &logs, nil
+}
+
+func (e *testLogExporter) RequestIDs(ctx context.Context, filter log.Filter, limit *int) ([]string, int64, error) {
+	return []string{"req-1", "req-2"}, 2, nil
+}
+
+func TestLogSource(t *testing.T) {
+	ctx := context.Background()
+	exporter := &testLogExporter{}
+	search := "payment"
+	minLevel := zerolog.ErrorLevel
+	source := NewLogSource(exporter, LogRule{
+		SourceID:  "billing-errors",
+		Severity:  SeverityLevelHigh,
+		Filter:    log.Filter{Services: []string{"billing"}, Search: &search},
+		MinLevel:  &minLevel,
+		Window:    5 * time.Minute,
+		Threshold: 50,
+		SampleN:   2,
+	})
+
+	exporter.logs = log.Logs{TotalCount: 50}
+	alerts, next, err := source.EvaluateAlerts(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), next, time.Second)
+	assert.Equal(t, []log.Level{zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel}, exporter.params.Levels)
+	assert.Equal(t, []string{"billing"}, exporter.params.Services)
+	assert.Equal(t, 5*time.Minute, exporter.params.To.Sub(exporter.params.From))
+
+	exporter.logs = log.Logs{TotalCount: 51, Records: []log.LogRecord{{Msg: "a"}, {Msg: "b"}, {Msg: "c"}}}
+	alerts, _, err = source.EvaluateAlerts(ctx)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	firing := alerts[0]
+	assert.Equal(t, "billing-errors", firing.SourceID)
+	assert.Equal(t, SeverityLevelHigh, firing.Severity)
+	assert.True(t, firing.To.IsZero())
+	assert.Equal(t, int64(51), firing.MetaData[LogCountMetaKey])
+	assert.Equal(t, []string{"a", "b"}, firing.MetaData[LogSamplesMetaKey])
+	assert.Equal(t, []string{"req-1", "req-2"}, firing.MetaData[LogRequestIDsMetaKey])
+
+	exporter.logs = log.Logs{TotalCount: 3}
+	alerts, _, err = source.EvaluateAlerts(ctx)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, firing.From, alerts[0].From)
+	assert.False(t, alerts[0].To.IsZero(), "resolved")
+}
@@ -3,6 +3,7 @@ package alert
 import (
 	"context"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/bldsoft/gost/log"
@@ -15,34 +16,101 @@ type Processor struct {
 	ID      string
 	Source  Source
 	Handler Handler
+	// Escalation is applied by Escalator.Middleware in the Handler pipeline.
+	Escalation *EscalationPolicy
+}
+
+type processorCtxKey struct{}
+
+// WithProcessor returns the context of the alerts handled by the processor.
+// The manager calls the processor Handler with it.
+func WithProcessor(ctx context.Context, p *Processor) context.Context {
+	return context.WithValue(ctx, processorCtxKey{}, p)
+}
+
+// ProcessorFromContext returns the processor handling the alerts or nil.
+func ProcessorFromContext(ctx context.Context) *Processor {
+	p, _ := ctx.Value(processorCtxKey{}).(*Processor)
+	return p
 }
 
 type Config struct {
 	WorkerN int
 }
 
+// EvaluationHook is called after every Source.EvaluateAlerts call.
+type EvaluationHook func(processorID string, duration time.Duration, err error)
+
 type Manager struct {
-	queue *queue[Processor]
+	queue *queue[*Processor]
 	wp    *wp.WorkerPool
+
+	mtx        sync.Mutex
+	processors map[string]*Processor // the processors being evaluated aren't in the queue
+
+	onEvaluation EvaluationHook
+	sharder      *Sharder
+	firing       map[string][]Alert // the alerts last fired by the owned processors, see releaseOwnership
 }
 
 func NewManager(cfg Config) *Manager {
 	return &Manager{
-		queue: newQueue[Processor](),
-		wp:    new(wp.WorkerPool).SetWorkerN(int64(cfg.WorkerN)),
+		queue:      newQueue[*Processor](),
+		wp:         new(wp.WorkerPool).SetWorkerN(int64(cfg.WorkerN)),
+		processors: make(map[string]*Processor),
+		firing:     make(map[string][]Alert),
 	}
 }
 
+// OnEvaluation sets the hook observing alert evaluations, e.g. to collect metrics.
+// It must be set before Run.
+func (m *Manager) OnEvaluation(hook EvaluationHook) *Manager {
+	m.onEvaluation = hook
+	return m
+}
+
+// AddProcessor adds the processor. The processor with the same ID is replaced.
 func (m *Manager) AddProcessor(p Processor) {
-	m.queue.Push(p, time.Now())
+	m.RemoveProcessor(p.ID)
+	m.mtx.Lock()
+	m.processors[p.ID] = &p
+	m.mtx.Unlock()
+	m.queue.Push(&p, time.Now())
+}
+
+// SetSharder shares the processors with the other instances of the service, each processor is evaluated
+// by its owner instance only. The processors of a LocalSource are evaluated by every instance.
+// It must be set before Run. The sharder itself should be run separately.
+//
+// When an instance loses a processor, it resolves the alerts the processor fired last, so they don't stay firing.
+// The source state isn't handed off: the new owner evaluates the source from scratch, raises the alerts that
+// are still firing again and restarts the pending For timers.
+func (m *Manager) SetSharder(sharder *Sharder) *Manager {
+	m.sharder = sharder
+	return m
 }
 
+// RemoveProcessor removes the processor. The processor being evaluated isn't evaluated again.
 func (m *Manager) RemoveProcessor(id string) {
-	m.queue.RemoveFirstFunc(func(p Processor) bool {
+	m.mtx.Lock()
+	delete(m.processors, id)
+	delete(m.firing, id)
+	m.mtx.Unlock()
+	m.queue.RemoveFirstFunc(func(p *Processor) bool {
 		return p.ID == id
 	})
 }
 
+// reschedule pushes the processor back to the queue unless it has been removed or replaced.
+func (m *Manager) reschedule(p *Processor, next time.Time) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.processors[p.ID] != p {
+		return
+	}
+	m.queue.Push(p, next)
+}
+
 func (m *Manager) Run(ctx context.Context) {
 	for p := range m.queue.SyncSeq(ctx) {
 		m.wp.In() <- func() {
@@ -51,7 +119,7 @@ func (m *Manager) Run(ctx context.Context) {
 				if next.IsZero() {
 					next = time.Now().Add(failedCheckRetryInterval)
 				}
-				m.queue.Push(p, next)
+				m.reschedule(p, next)
 			}()
 			defer func() {
 				if err := recover(); err != nil {
@@ -62,7 +130,18 @@ func (m *Manager) Run(ctx context.Context) {
 				}
 			}()
 
+			sharded := m.sharder != nil && !isLocalSource(p.Source)
+			if sharded && !m.sharder.Owns(p.ID) {
+				m.releaseOwnership(ctx, p)
+				next = time.Now().Add(m.sharder.recheckInterval)
+				return
+			}
+
+			start := time.Now()
 			alerts, next, err := p.Source.EvaluateAlerts(ctx)
+			if m.onEvaluation != nil {
+				m.onEvaluation(p.ID, time.Since(start), err)
+			}
 			if err != nil {
 				log.FromContext(ctx).ErrorfWithFields(log.Fields{
 					"error": err,
@@ -70,8 +149,46 @@ func (m *Manager) Run(ctx context.Context) {
 				return
 			}
 
-			p.Handler.Handle(ctx, alerts...)
+			p.Handler.Handle(WithProcessor(ctx, p), alerts...)
+			if sharded {
+				m.setFiring(p, alerts)
+			}
 		}
 	}
 	m.wp.CloseAndWait()
 }
+
+func (m *Manager) setFiring(p *Processor, alerts []Alert) {
+	var firing []Alert
+	for _, a := range alerts {
+		if a.To.IsZero() {
+			firing = append(firing, a)
+		}
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if len(firing) == 0 || m.processors[p.ID] != p {
+		delete(m.firing, p.ID)
+		return
+	}
+	m.firing[p.ID] = firing
+}
+
+// releaseOwnership resolves the alerts the processor fired last before it moved to another instance.
+func (m *Manager) releaseOwnership(ctx context.Context, p *Processor) {
+	m.mtx.Lock()
+	firing := m.firing[p.ID]
+	delete(m.firing, p.ID)
+	m.mtx.Unlock()
+	if len(firing) == 0 {
+		return
+	}
+	log.FromContext(ctx).InfoWithFields(log.Fields{"processor": p.ID, "alerts": len(firing)}, "Alert processor moved to another instance, its firing alerts are resolved")
+	now := time.Now()
+	resolved := make([]Alert, 0, len(firing))
+	for _, a := range firing {
+		a.To = now
+		resolved = append(resolved, a)
+	}
+	p.Handler.Handle(WithProcessor(ctx, p), resolved...)
+}
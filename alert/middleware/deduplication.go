@@ -13,14 +13,7 @@ import (
 
 func Deduplication(duplicatedCache cache.Repository[*alert.Alert], uniqueKey ...func(alert alert.Alert) string) alert.Middleware {
 	if len(uniqueKey) == 0 {
-		uniqueKey = []func(alert alert.Alert) string{
-			func(alert alert.Alert) string {
-				if alert.To.IsZero() {
-					return fmt.Sprintf("%s-%s-%d-s", alert.SourceID, alert.Severity, alert.From.Unix())
-				}
-				return fmt.Sprintf("%s-%s-%d-e", alert.SourceID, alert.Severity, alert.To.Unix())
-			},
-		}
+		uniqueKey = []func(alert alert.Alert) string{deduplicationKey}
 	}
 
 	return func(next alert.Handler) alert.Handler {
@@ -51,3 +44,10 @@ func Deduplication(duplicatedCache cache.Repository[*alert.Alert], uniqueKey ...
 		})
 	}
 }
+
+func deduplicationKey(alert alert.Alert) string {
+	if alert.To.IsZero() {
+		return fmt.Sprintf("%s-%s-%d-s", alert.SourceID, alert.Severity, alert.From.Unix())
+	}
+	return fmt.Sprintf("%s-%s-%d-e", alert.SourceID, alert.Severity, alert.To.Unix())
+}
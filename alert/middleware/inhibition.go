@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/bldsoft/gost/alert"
+	"github.com/bldsoft/gost/cache"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/utils"
+)
+
+// InhibitRule drops the target alerts while a source alert is firing.
+// The source alert matches SourceMatchers and has severity >= SourceMinSeverity,
+// the target alert matches TargetMatchers, has a lower severity than the source alert
+// and the same values of the Equal labels.
+// Empty matchers match all alerts, labels are the same as in Matcher.
+type InhibitRule struct {
+	SourceMatchers    []Matcher
+	SourceMinSeverity alert.SeverityLevel
+	TargetMatchers    []Matcher
+	Equal             []string
+}
+
+func (r *InhibitRule) isSource(a alert.Alert) bool {
+	return a.Severity >= r.SourceMinSeverity && matchAll(r.SourceMatchers, a)
+}
+
+func (r *InhibitRule) inhibits(source, target alert.Alert) bool {
+	if source.Severity <= target.Severity || !matchAll(r.TargetMatchers, target) {
+		return false
+	}
+	for _, label := range r.Equal {
+		if labelValue(source, label) != labelValue(target, label) {
+			return false
+		}
+	}
+	return true
+}
+
+// compileInhibitRules returns a copy of the rules with the matcher regexps compiled.
+// The invalid matchers are left as is, they match nothing.
+func compileInhibitRules(rules []InhibitRule) []InhibitRule {
+	res := slices.Clone(rules)
+	for i := range res {
+		var err error
+		if res[i].SourceMatchers, err = compileMatchers(res[i].SourceMatchers); err != nil {
+			log.ErrorWithFields(log.Fields{"err": err}, "invalid inhibit rule source matcher")
+		}
+		if res[i].TargetMatchers, err = compileMatchers(res[i].TargetMatchers); err != nil {
+			log.ErrorWithFields(log.Fields{"err": err}, "invalid inhibit rule target matcher")
+		}
+	}
+	return res
+}
+
+func matchAll(matchers []Matcher, a alert.Alert) bool {
+	for _, m := range matchers {
+		if !m.matches(a) {
+			return false
+		}
+	}
+	return true
+}
+
+type inhibition struct {
+	rules []InhibitRule
+
+	// onReleased is called for the still firing targets whose inhibition has ended
+	onReleased func(ctx context.Context, a alert.Alert)
+
+	mtx       sync.Mutex
+	firing    map[string]alert.Alert // source alerts by key
+	inhibited map[string]alert.Alert // targets whose firing alert was dropped by key
+}
+
+// Inhibition drops the alerts inhibited by the firing higher-severity alerts, e.g. the symptoms of a database outage.
+// The resolving alert of a target is dropped only if its firing alert was dropped.
+// An alert never inhibits itself. Use after deduplication middleware, see InhibitionWithDeduplication.
+func Inhibition(rules ...InhibitRule) alert.Middleware {
+	return inhibitionMiddleware(nil, rules)
+}
+
+// InhibitionWithDeduplication is Inhibition that removes the inhibited alert from the deduplication cache
+// when its inhibition ends, so the target that is still firing passes the deduplication and is notified.
+// duplicatedCache and uniqueKey must be the ones of the Deduplication middleware, nil uniqueKey is its default key.
+func InhibitionWithDeduplication(duplicatedCache cache.Repository[*alert.Alert], uniqueKey func(alert alert.Alert) string, rules ...InhibitRule) alert.Middleware {
+	if uniqueKey == nil {
+		uniqueKey = deduplicationKey
+	}
+	return inhibitionMiddleware(func(ctx context.Context, a alert.Alert) {
+		err := duplicatedCache.Delete(uniqueKey(a))
+		if err != nil && !errors.Is(err, utils.ErrObjectNotFound) {
+			log.FromContext(ctx).ErrorWithFields(log.Fields{"err": err}, "failed to delete released alert from deduplication cache")
+		}
+	}, rules)
+}
+
+func inhibitionMiddleware(onReleased func(ctx context.Context, a alert.Alert), rules []InhibitRule) alert.Middleware {
+	rules = compileInhibitRules(rules)
+	return func(next alert.Handler) alert.Handler {
+		i := &inhibition{
+			rules:      rules,
+			onReleased: onReleased,
+			firing:     make(map[string]alert.Alert),
+			inhibited:  make(map[string]alert.Alert),
+		}
+		return alert.HandlerFunc(func(ctx context.Context, alerts ...alert.Alert) {
+			logger := log.FromContext(ctx).WithFields(log.Fields{"component": "alerts inhibition"})
+			passed := i.filter(ctx, alerts, func(a alert.Alert) {
+				logger.DebugWithFields(log.Fields{"alert": a}, "alert is inhibited")
+			})
+			if len(passed) == 0 {
+				return
+			}
+			next.Handle(ctx, passed...)
+		})
+	}
+}
+
+func inhibitionKey(a alert.Alert) string {
+	return fmt.Sprintf("%s-%s", a.SourceID, a.Severity)
+}
+
+func (i *inhibition) filter(ctx context.Context, alerts []alert.Alert, onInhibited func(a alert.Alert)) []alert.Alert {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	// update the sources first, so the alerts of the same batch inhibit each other
+	sourceResolved := false
+	for _, a := range alerts {
+		if !i.isSource(a) {
+			continue
+		}
+		if a.To.IsZero() {
+			i.firing[inhibitionKey(a)] = a
+		} else if _, ok := i.firing[inhibitionKey(a)]; ok {
+			delete(i.firing, inhibitionKey(a))
+			sourceResolved = true
+		}
+	}
+	if sourceResolved {
+		i.release(ctx, alerts)
+	}
+
+	passed := make([]alert.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		key := inhibitionKey(a)
+		if !a.To.IsZero() {
+			if _, ok := i.inhibited[key]; ok {
+				delete(i.inhibited, key)
+				onInhibited(a)
+				continue
+			}
+			passed = append(passed, a)
+			continue
+		}
+		if i.isInhibited(a) {
+			i.inhibited[key] = a
+			onInhibited(a)
+			continue
+		}
+		delete(i.inhibited, key)
+		passed = append(passed, a)
+	}
+	return passed
+}
+
+func (i *inhibition) isSource(a alert.Alert) bool {
+	for _, r := range i.rules {
+		if r.isSource(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// release notifies onReleased of the inhibited targets that aren't inhibited anymore.
+// The targets of the batch are skipped, they are handled by the filter.
+func (i *inhibition) release(ctx context.Context, batch []alert.Alert) {
+	if i.onReleased == nil {
+		return
+	}
+	for key, target := range i.inhibited {
+		if i.isInhibited(target) || slices.ContainsFunc(batch, func(a alert.Alert) bool { return inhibitionKey(a) == key }) {
+			continue
+		}
+		i.onReleased(ctx, target)
+	}
+}
+
+// isInhibited reports whether the target is inhibited by a firing source with a higher severity,
+// so an alert never inhibits itself.
+func (i *inhibition) isInhibited(target alert.Alert) bool {
+	for _, source := range i.firing {
+		for _, r := range i.rules {
+			if r.isSource(source) && r.inhibits(source, target) {
+				return true
+			}
+		}
+	}
+	return false
+}
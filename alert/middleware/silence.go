@@ -0,0 +1,229 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/bldsoft/gost/alert"
+	"github.com/bldsoft/gost/log"
+)
+
+// SilencedByMetaKey is the metadata key holding the ID of the silence that suppressed the alert.
+const SilencedByMetaKey = "silencedBy"
+
+// SourceIDLabel matches the alert SourceID, other matcher labels match the alert MetaData.
+const SourceIDLabel = "sourceID"
+
+var ErrInvalidSilence = errors.New("invalid silence")
+
+type MatchOperator string
+
+const (
+	MatchEqual     MatchOperator = "="
+	MatchNotEqual  MatchOperator = "!="
+	MatchRegexp    MatchOperator = "=~"
+	MatchNotRegexp MatchOperator = "!~"
+)
+
+// Matcher matches the alert label. The regexps are anchored, so "api-.*" matches "api-1" but not "my-api-1".
+type Matcher struct {
+	Label    string        `json:"label" bson:"label"`
+	Operator MatchOperator `json:"operator" bson:"operator"`
+	Value    string        `json:"value" bson:"value"`
+
+	re *regexp.Regexp // compiled Value of the regexp operators, see SilenceRule.Compile
+}
+
+func (m *Matcher) compile() error {
+	if m.Label == "" {
+		return fmt.Errorf("%w: empty matcher label", ErrInvalidSilence)
+	}
+	switch m.Operator {
+	case MatchEqual, MatchNotEqual:
+	case MatchRegexp, MatchNotRegexp:
+		re, err := compileMatcherRegexp(m.Value)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSilence, err)
+		}
+		m.re = re
+	default:
+		return fmt.Errorf("%w: unknown matcher operator %q", ErrInvalidSilence, m.Operator)
+	}
+	return nil
+}
+
+// compileMatchers returns a copy of the matchers with the regexps compiled, so they aren't compiled per alert.
+func compileMatchers(matchers []Matcher) ([]Matcher, error) {
+	res := slices.Clone(matchers)
+	for i := range res {
+		if err := res[i].compile(); err != nil {
+			return matchers, err
+		}
+	}
+	return res, nil
+}
+
+// SilenceRule mutes the alerts matching all matchers during [StartsAt, EndsAt).
+type SilenceRule struct {
+	ID       string    `json:"id" bson:"-"`
+	Matchers []Matcher `json:"matchers" bson:"matchers"`
+	StartsAt time.Time `json:"startsAt" bson:"startsAt"`
+	EndsAt   time.Time `json:"endsAt" bson:"endsAt"`
+	// CreatedBy is the ID of the user who created the silence, it's taken from the request context.
+	CreatedBy string    `json:"createdBy" bson:"createdBy"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	Comment   string    `json:"comment,omitempty" bson:"comment,omitempty"`
+}
+
+// Validate checks the silence and compiles its regexps, see Compile.
+func (s *SilenceRule) Validate() error {
+	if len(s.Matchers) == 0 {
+		return fmt.Errorf("%w: no matchers", ErrInvalidSilence)
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("%w: endsAt must be after startsAt", ErrInvalidSilence)
+	}
+	return s.Compile()
+}
+
+// Compile compiles the regexps of the matchers, so they aren't compiled per alert.
+// SilenceCache compiles the silences once per refresh. It returns ErrInvalidSilence if a matcher is invalid.
+func (s *SilenceRule) Compile() error {
+	for i := range s.Matchers {
+		if err := s.Matchers[i].compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SilenceRule) IsActive(at time.Time) bool {
+	return !at.Before(s.StartsAt) && at.Before(s.EndsAt)
+}
+
+// Matches reports whether the alert matches all matchers. Invalid regexps match nothing.
+func (s *SilenceRule) Matches(a alert.Alert) bool {
+	for _, m := range s.Matchers {
+		if !m.matches(a) {
+			return false
+		}
+	}
+	return len(s.Matchers) > 0
+}
+
+func (m Matcher) matches(a alert.Alert) bool {
+	value := labelValue(a, m.Label)
+	switch m.Operator {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp, MatchNotRegexp:
+		re := m.re
+		if re == nil {
+			// the matcher isn't compiled, e.g. it's created in code
+			var err error
+			if re, err = compileMatcherRegexp(m.Value); err != nil {
+				return false
+			}
+		}
+		return re.MatchString(value) == (m.Operator == MatchRegexp)
+	default:
+		return false
+	}
+}
+
+// labelValue returns the alert SourceID for SourceIDLabel and the MetaData value otherwise, missing labels are empty.
+func labelValue(a alert.Alert, label string) string {
+	if label == SourceIDLabel {
+		return a.SourceID
+	}
+	if v, ok := a.MetaData[label]; ok {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+func compileMatcherRegexp(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// SilenceFilter selects the silences. Zero ActiveAt selects all silences.
+type SilenceFilter struct {
+	ActiveAt time.Time
+}
+
+// SilenceRepository stores the silence rules. Get, Update and Delete return utils.ErrObjectNotFound for unknown IDs.
+type SilenceRepository interface {
+	CreateSilence(ctx context.Context, silence *SilenceRule) error
+	UpdateSilence(ctx context.Context, silence *SilenceRule) error
+	DeleteSilence(ctx context.Context, id string) error
+	GetSilence(ctx context.Context, id string) (*SilenceRule, error)
+	FindSilences(ctx context.Context, filter SilenceFilter) ([]*SilenceRule, error)
+}
+
+// Silence drops the alerts matching an active silence rule.
+// The suppressed alerts are recorded to alertLog with the silence ID in the metadata to keep an audit trail,
+// alertLog can be nil. If the rules can't be loaded, the alerts aren't suppressed.
+// The active silences are cached, if rep isn't a SilenceCache, it's wrapped by NewSilenceCache with
+// DefaultSilenceRefreshInterval.
+func Silence(rep SilenceRepository, alertLog AlertLog) alert.Middleware {
+	if _, ok := rep.(*SilenceCache); !ok {
+		rep = NewSilenceCache(rep, DefaultSilenceRefreshInterval)
+	}
+	return func(next alert.Handler) alert.Handler {
+		return alert.HandlerFunc(func(ctx context.Context, alerts ...alert.Alert) {
+			if len(alerts) == 0 {
+				return
+			}
+			logger := log.FromContext(ctx).WithFields(log.Fields{"component": "alerts silence"})
+			silences, err := rep.FindSilences(ctx, SilenceFilter{ActiveAt: time.Now()})
+			if err != nil {
+				logger.ErrorWithFields(log.Fields{"err": err}, "failed to load silences")
+				next.Handle(ctx, alerts...)
+				return
+			}
+
+			passed := make([]alert.Alert, 0, len(alerts))
+			var suppressed []alert.Alert
+			for _, a := range alerts {
+				if silence := matchingSilence(silences, a); silence != nil {
+					a = withMetaData(a, SilencedByMetaKey, silence.ID)
+					suppressed = append(suppressed, a)
+					logger.DebugWithFields(log.Fields{"alert": a, "silence": silence.ID}, "alert is silenced")
+					continue
+				}
+				passed = append(passed, a)
+			}
+
+			if alertLog != nil && len(suppressed) > 0 {
+				if err := alertLog.UpsertMany(ctx, suppressed...); err != nil {
+					logger.ErrorWithFields(log.Fields{"err": err}, "failed to insert silenced alerts into log")
+				}
+			}
+			if len(passed) == 0 {
+				return
+			}
+			next.Handle(ctx, passed...)
+		})
+	}
+}
+
+func matchingSilence(silences []*SilenceRule, a alert.Alert) *SilenceRule {
+	for _, s := range silences {
+		if s.Matches(a) {
+			return s
+		}
+	}
+	return nil
+}
+
+func withMetaData(a alert.Alert, key string, value any) alert.Alert {
+	a.MetaData = maps.Clone(a.MetaData)
+	return a.AddMetaData(key, value)
+}
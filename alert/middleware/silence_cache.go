@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bldsoft/gost/log"
+)
+
+// DefaultSilenceRefreshInterval is how often SilenceCache reloads the active silences.
+const DefaultSilenceRefreshInterval = time.Minute
+
+// SilenceCache is a SilenceRepository caching the active silences of the underlying repository,
+// so the Silence middleware doesn't query the storage on every evaluation. The silences are reloaded
+// and compiled every refresh interval and after the writes made through the cache. The writes of other
+// instances and the silences starting in the future take effect on the next refresh.
+// Pass the same cache to the Silence middleware and NewSilenceController.
+type SilenceCache struct {
+	SilenceRepository
+	refreshInterval time.Duration
+
+	mtx      sync.Mutex
+	active   []*SilenceRule
+	loadedAt time.Time
+	stale    bool
+}
+
+// NewSilenceCache creates a cache of the active silences of rep. The refresh interval is DefaultSilenceRefreshInterval
+// if it isn't positive.
+func NewSilenceCache(rep SilenceRepository, refreshInterval time.Duration) *SilenceCache {
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultSilenceRefreshInterval
+	}
+	return &SilenceCache{
+		SilenceRepository: rep,
+		refreshInterval:   refreshInterval,
+		stale:             true,
+	}
+}
+
+func (c *SilenceCache) CreateSilence(ctx context.Context, silence *SilenceRule) error {
+	defer c.invalidate()
+	return c.SilenceRepository.CreateSilence(ctx, silence)
+}
+
+func (c *SilenceCache) UpdateSilence(ctx context.Context, silence *SilenceRule) error {
+	defer c.invalidate()
+	return c.SilenceRepository.UpdateSilence(ctx, silence)
+}
+
+func (c *SilenceCache) DeleteSilence(ctx context.Context, id string) error {
+	defer c.invalidate()
+	return c.SilenceRepository.DeleteSilence(ctx, id)
+}
+
+// FindSilences returns the cached silences if filter.ActiveAt is set, otherwise it queries the repository.
+func (c *SilenceCache) FindSilences(ctx context.Context, filter SilenceFilter) ([]*SilenceRule, error) {
+	if filter.ActiveAt.IsZero() {
+		return c.SilenceRepository.FindSilences(ctx, filter)
+	}
+	silences, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*SilenceRule, 0, len(silences))
+	for _, s := range silences {
+		if s.IsActive(filter.ActiveAt) {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+func (c *SilenceCache) invalidate() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.stale = true
+}
+
+// load returns the cached silences, reloading them if they are stale.
+// The lock is held during the reload, so the concurrent evaluations don't query the storage too.
+func (c *SilenceCache) load(ctx context.Context) ([]*SilenceRule, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := time.Now()
+	if !c.stale && now.Sub(c.loadedAt) < c.refreshInterval {
+		return c.active, nil
+	}
+	silences, err := c.SilenceRepository.FindSilences(ctx, SilenceFilter{ActiveAt: now})
+	if err != nil {
+		return nil, err
+	}
+	active := make([]*SilenceRule, 0, len(silences))
+	for _, s := range silences {
+		compiled := *s
+		if compiled.Matchers, err = compileMatchers(s.Matchers); err != nil {
+			// the stored silences are validated, the invalid matchers match nothing
+			log.FromContext(ctx).WarnWithFields(log.Fields{"id": s.ID, "err": err}, "Invalid alert silence")
+		}
+		active = append(active, &compiled)
+	}
+	c.active, c.loadedAt, c.stale = active, now, false
+	return active, nil
+}
+
+// Compile time checks to ensure your type satisfies an interface
+var _ SilenceRepository = (*SilenceCache)(nil)
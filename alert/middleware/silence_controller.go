@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bldsoft/gost/auth"
+	"github.com/bldsoft/gost/controller"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/repository"
+	"github.com/bldsoft/gost/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// SilenceController manages the silence rules.
+type SilenceController struct {
+	controller.BaseController
+	rep SilenceRepository
+}
+
+func NewSilenceController(rep SilenceRepository) *SilenceController {
+	return &SilenceController{rep: rep}
+}
+
+type SilencesParams struct {
+	// Active selects the silences active now only.
+	Active bool `json:"active,omitempty" schema:"active,omitempty"`
+}
+
+func (c *SilenceController) responseError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidSilence):
+		c.ResponseError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, utils.ErrObjectNotFound):
+		c.ResponseError(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	default:
+		log.FromContext(r.Context()).Error(err.Error())
+		c.ResponseError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// GetSilencesHandler lists the silences.
+// @Summary list alert silences
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param active query bool false "Active silences only"
+// @Produce json
+// @Success 200 {array} SilenceRule "OK"
+// @Router /alerts/silences [get]
+func (c *SilenceController) GetSilencesHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := utils.FromRequest[SilencesParams](r)
+	if err != nil {
+		c.ResponseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var filter SilenceFilter
+	if params.Active {
+		filter.ActiveAt = time.Now()
+	}
+	silences, err := c.rep.FindSilences(r.Context(), filter)
+	if err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, silences)
+}
+
+// GetSilenceHandler gets a single silence.
+// @Summary get alert silence
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param id path string true "Silence ID"
+// @Produce json
+// @Success 200 {object} SilenceRule "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /alerts/silences/{id} [get]
+func (c *SilenceController) GetSilenceHandler(w http.ResponseWriter, r *http.Request) {
+	silence, err := c.rep.GetSilence(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, silence)
+}
+
+// CreateSilenceHandler creates a silence.
+// @Summary create alert silence
+// @Tags admin
+// @Security ApiKeyAuth
+// @Accept json
+// @Param silence body SilenceRule true "Silence"
+// @Produce json
+// @Success 200 {object} SilenceRule "OK"
+// @Failure 400 {string} string "Bad request"
+// @Router /alerts/silences [post]
+func (c *SilenceController) CreateSilenceHandler(w http.ResponseWriter, r *http.Request) {
+	var silence SilenceRule
+	if !c.GetObjectFromBody(w, r, &silence) {
+		return
+	}
+	silence.ID = ""
+	silence.CreatedAt = time.Now()
+	silence.CreatedBy = ""
+	if user, ok := auth.UserFromContext(r.Context()).(repository.IEntityID); ok {
+		silence.CreatedBy = user.StringID()
+	}
+	if err := silence.Validate(); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	if err := c.rep.CreateSilence(r.Context(), &silence); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, silence)
+}
+
+// UpdateSilenceHandler updates a silence, e.g. to expire it early.
+// @Summary update alert silence
+// @Tags admin
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path string true "Silence ID"
+// @Param silence body SilenceRule true "Silence"
+// @Success 200 {string} string "OK"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Not found"
+// @Router /alerts/silences/{id} [put]
+func (c *SilenceController) UpdateSilenceHandler(w http.ResponseWriter, r *http.Request) {
+	var silence SilenceRule
+	if !c.GetObjectFromBody(w, r, &silence) {
+		return
+	}
+	silence.ID = chi.URLParam(r, "id")
+	if err := silence.Validate(); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	if err := c.rep.UpdateSilence(r.Context(), &silence); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseOK(w)
+}
+
+// DeleteSilenceHandler deletes a silence.
+// @Summary delete alert silence
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param id path string true "Silence ID"
+// @Success 200 {string} string "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /alerts/silences/{id} [delete]
+func (c *SilenceController) DeleteSilenceHandler(w http.ResponseWriter, r *http.Request) {
+	if err := c.rep.DeleteSilence(r.Context(), chi.URLParam(r, "id")); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseOK(w)
+}
+
+// Mount it with r.Route("/alerts/silences", c.Mount).
+func (c *SilenceController) Mount(r chi.Router) {
+	r.Get("/", c.GetSilencesHandler)
+	r.Post("/", c.CreateSilenceHandler)
+	r.Get("/{id}", c.GetSilenceHandler)
+	r.Put("/{id}", c.UpdateSilenceHandler)
+	r.Delete("/{id}", c.DeleteSilenceHandler)
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/alert"
+	"github.com/bldsoft/gost/alert/middleware"
+	"github.com/bldsoft/gost/cache"
+	"github.com/bldsoft/gost/cache/bigcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInhibition(t *testing.T) {
+	ctx := context.Background()
+	var sent []alert.Alert
+	handler := middleware.Inhibition(middleware.InhibitRule{
+		SourceMatchers:    []middleware.Matcher{{Label: "type", Operator: middleware.MatchEqual, Value: "db"}},
+		SourceMinSeverity: alert.SeverityLevelCritical,
+		TargetMatchers:    []middleware.Matcher{{Label: "type", Operator: middleware.MatchEqual, Value: "api"}},
+		Equal:             []string{"cluster"},
+	})(alert.HandlerFunc(func(ctx context.Context, alerts ...alert.Alert) {
+		sent = append(sent, alerts...)
+	}))
+
+	start := time.Now()
+	newAlert := func(sourceID, typ, cluster string, severity alert.SeverityLevel) alert.Alert {
+		return alert.Alert{
+			SourceID: sourceID,
+			Severity: severity,
+			From:     start,
+			MetaData: map[string]any{"type": typ, "cluster": cluster},
+		}
+	}
+	resolved := func(a alert.Alert) alert.Alert {
+		a.To = time.Now()
+		return a
+	}
+
+	db := newAlert("db", "db", "eu", alert.SeverityLevelCritical)
+	apiEU := newAlert("api-eu", "api", "eu", alert.SeverityLevelMedium)
+	apiUS := newAlert("api-us", "api", "us", alert.SeverityLevelMedium)
+	dbHigh := newAlert("db-replica", "db", "us", alert.SeverityLevelHigh)
+	apiEUEarly := newAlert("api-eu-2", "api", "eu", alert.SeverityLevelMedium)
+
+	handler.Handle(ctx, apiEUEarly)
+	handler.Handle(ctx, db, apiEU, apiUS, dbHigh)
+	require.Len(t, sent, 4)
+	assert.Equal(t, []string{"api-eu-2", "db", "api-us", "db-replica"}, sourceIDs(sent))
+
+	sent = nil
+	handler.Handle(ctx, resolved(apiEU), resolved(apiEUEarly))
+	assert.Equal(t, []string{"api-eu-2"}, sourceIDs(sent), "resolution of the inhibited alert is dropped")
+
+	sent = nil
+	handler.Handle(ctx, resolved(db), apiEU)
+	assert.Equal(t, []string{"db", "api-eu"}, sourceIDs(sent), "source is resolved")
+}
+
+func TestInhibitionSeverity(t *testing.T) {
+	ctx := context.Background()
+	var sent []alert.Alert
+	handler := middleware.Inhibition(middleware.InhibitRule{
+		SourceMinSeverity: alert.SeverityLevelMedium,
+		Equal:             []string{"cluster"},
+	})(alert.HandlerFunc(func(ctx context.Context, alerts ...alert.Alert) {
+		sent = append(sent, alerts...)
+	}))
+
+	newAlert := func(sourceID string, severity alert.SeverityLevel) alert.Alert {
+		return alert.Alert{SourceID: sourceID, Severity: severity, From: time.Now(), MetaData: map[string]any{"cluster": "eu"}}
+	}
+	handler.Handle(ctx, newAlert("a", alert.SeverityLevelHigh), newAlert("b", alert.SeverityLevelHigh))
+	assert.Equal(t, []string{"a", "b"}, sourceIDs(sent), "the alerts of the same severity don't inhibit each other")
+
+	sent = nil
+	handler.Handle(ctx, newAlert("c", alert.SeverityLevelMedium), newAlert("d", alert.SeverityLevelCritical))
+	assert.Equal(t, []string{"d"}, sourceIDs(sent), "only the lower severity is inhibited")
+}
+
+func TestInhibitionWithDeduplication(t *testing.T) {
+	ctx := context.Background()
+	var sent []alert.Alert
+	rep := cache.Typed[*alert.Alert](bigcache.NewExpiringRepository("{}"))
+	handler := alert.Middlewares(
+		middleware.Deduplication(rep),
+		middleware.InhibitionWithDeduplication(rep, nil, middleware.InhibitRule{
+			SourceMinSeverity: alert.SeverityLevelCritical,
+		}),
+	)(alert.HandlerFunc(func(ctx context.Context, alerts ...alert.Alert) {
+		sent = append(sent, alerts...)
+	}))
+
+	start := time.Now()
+	source := alert.Alert{SourceID: "db", Severity: alert.SeverityLevelCritical, From: start}
+	target := alert.Alert{SourceID: "api", Severity: alert.SeverityLevelMedium, From: start}
+	handler.Handle(ctx, source, target)
+	assert.Equal(t, []string{"db"}, sourceIDs(sent))
+
+	sent = nil
+	handler.Handle(ctx, target)
+	assert.Empty(t, sent, "deduplicated")
+
+	resolvedSource := source
+	resolvedSource.To = time.Now()
+	handler.Handle(ctx, resolvedSource)
+	handler.Handle(ctx, target)
+	assert.Equal(t, []string{"db", "api"}, sourceIDs(sent), "the still firing target is notified after the inhibition ends")
+
+	sent = nil
+	handler.Handle(ctx, target)
+	assert.Empty(t, sent, "deduplicated again")
+}
+
+func sourceIDs(alerts []alert.Alert) []string {
+	res := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		res = append(res, a.SourceID)
+	}
+	return res
+}
@@ -0,0 +1,190 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/alert"
+	"github.com/bldsoft/gost/alert/middleware"
+	"github.com/bldsoft/gost/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAlertLog struct {
+	alerts []alert.Alert
+}
+
+func (l *testAlertLog) UpsertMany(ctx context.Context, alerts ...alert.Alert) error {
+	l.alerts = append(l.alerts, alerts...)
+	return nil
+}
+
+func TestSilenceRuleMatches(t *testing.T) {
+	a := alert.Alert{SourceID: "api-1", MetaData: map[string]any{"env": "prod", "code": 500}}
+	testcases := []struct {
+		name     string
+		matchers []middleware.Matcher
+		matches  bool
+	}{
+		{"source regexp", []middleware.Matcher{{Label: middleware.SourceIDLabel, Operator: middleware.MatchRegexp, Value: "api-.*"}}, true},
+		{"regexp is anchored", []middleware.Matcher{{Label: middleware.SourceIDLabel, Operator: middleware.MatchRegexp, Value: "api"}}, false},
+		{"metadata equal", []middleware.Matcher{{Label: "env", Operator: middleware.MatchEqual, Value: "prod"}}, true},
+		{"non-string metadata", []middleware.Matcher{{Label: "code", Operator: middleware.MatchEqual, Value: "500"}}, true},
+		{"all matchers", []middleware.Matcher{
+			{Label: "env", Operator: middleware.MatchEqual, Value: "prod"},
+			{Label: middleware.SourceIDLabel, Operator: middleware.MatchNotRegexp, Value: "api-.*"},
+		}, false},
+		{"missing label", []middleware.Matcher{{Label: "region", Operator: middleware.MatchNotEqual, Value: "eu"}}, true},
+		{"no matchers", nil, false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := middleware.SilenceRule{Matchers: tc.matchers}
+			assert.Equal(t, tc.matches, s.Matches(a))
+		})
+	}
+}
+
+func TestSilenceMiddleware(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	rep := newTestSilenceRepository()
+	require.NoError(t, rep.CreateSilence(ctx, &middleware.SilenceRule{
+		Matchers: []middleware.Matcher{{Label: middleware.SourceIDLabel, Operator: middleware.MatchRegexp, Value: "deploy-.*"}},
+		StartsAt: now.Add(-time.Minute),
+		EndsAt:   now.Add(time.Hour),
+	}))
+	require.NoError(t, rep.CreateSilence(ctx, &middleware.SilenceRule{
+		Matchers: []middleware.Matcher{{Label: middleware.SourceIDLabel, Operator: middleware.MatchEqual, Value: "expired"}},
+		StartsAt: now.Add(-time.Hour),
+		EndsAt:   now.Add(-time.Minute),
+	}))
+
+	alertLog := &testAlertLog{}
+	var sent []alert.Alert
+	handler := middleware.Silence(rep, alertLog)(alert.HandlerFunc(func(ctx context.Context, alerts ...alert.Alert) {
+		sent = append(sent, alerts...)
+	}))
+
+	handler.Handle(ctx,
+		alert.Alert{SourceID: "deploy-api"},
+		alert.Alert{SourceID: "expired"},
+		alert.Alert{SourceID: "db"},
+	)
+	require.Len(t, sent, 2)
+	assert.Equal(t, "expired", sent[0].SourceID)
+	assert.Equal(t, "db", sent[1].SourceID)
+	require.Len(t, alertLog.alerts, 1)
+	assert.Equal(t, "deploy-api", alertLog.alerts[0].SourceID)
+	assert.Equal(t, "1", alertLog.alerts[0].MetaData[middleware.SilencedByMetaKey])
+}
+
+type testUser struct{ id string }
+
+func (u *testUser) RawID() interface{}              { return u.id }
+func (u *testUser) StringID() string                { return u.id }
+func (u *testUser) IsZeroID() bool                  { return u.id == "" }
+func (u *testUser) SetIDFromString(id string) error { u.id = id; return nil }
+func (u *testUser) GenerateID()                     {}
+
+func TestSilenceMatcherCompiled(t *testing.T) {
+	silence := middleware.SilenceRule{
+		Matchers: []middleware.Matcher{{Label: middleware.SourceIDLabel, Operator: middleware.MatchRegexp, Value: "api-.*"}},
+		StartsAt: time.Now(),
+		EndsAt:   time.Now().Add(time.Hour),
+	}
+	require.NoError(t, silence.Validate())
+	allocs := testing.AllocsPerRun(100, func() {
+		silence.Matches(alert.Alert{SourceID: "api-1"})
+	})
+	assert.Zero(t, allocs, "the regexp is compiled once")
+	assert.True(t, silence.Matches(alert.Alert{SourceID: "api-1"}))
+	assert.False(t, silence.Matches(alert.Alert{SourceID: "my-api-1"}))
+
+	silence.Matchers[0].Value = "("
+	assert.ErrorIs(t, silence.Validate(), middleware.ErrInvalidSilence)
+}
+
+func TestSilenceController(t *testing.T) {
+	rep := newTestSilenceRepository()
+	r := chi.NewRouter()
+	r.Route("/silences", middleware.NewSilenceController(rep).Mount)
+
+	user := &testUser{id: "admin"}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), auth.UserEntryCtxKey, user)))
+		return w
+	}
+
+	w := do(http.MethodPost, "/silences", `{"matchers":[{"label":"sourceID","operator":"=~","value":"("}],"startsAt":"2024-01-01T00:00:00Z","endsAt":"2024-01-02T00:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(http.MethodPost, "/silences", `{"matchers":[{"label":"sourceID","operator":"=","value":"api"}],"startsAt":"2024-01-01T00:00:00Z","endsAt":"2024-01-02T00:00:00Z","createdBy":"intruder"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"1"`)
+
+	w = do(http.MethodGet, "/silences/1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"createdBy":"admin"`, "the creator is the user of the request")
+
+	w = do(http.MethodGet, "/silences?active=true", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[]", w.Body.String())
+
+	w = do(http.MethodPut, "/silences/1", `{"matchers":[{"label":"sourceID","operator":"=","value":"api"}],"startsAt":"2024-01-01T00:00:00Z","endsAt":"2024-01-01T01:00:00Z"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/silences/1", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/silences/1", "").Code)
+}
+
+type countingSilenceRepository struct {
+	*testSilenceRepository
+	finds int
+}
+
+func (r *countingSilenceRepository) FindSilences(ctx context.Context, filter middleware.SilenceFilter) ([]*middleware.SilenceRule, error) {
+	r.finds++
+	return r.testSilenceRepository.FindSilences(ctx, filter)
+}
+
+func TestSilenceCache(t *testing.T) {
+	ctx := context.Background()
+	rep := &countingSilenceRepository{testSilenceRepository: newTestSilenceRepository()}
+	silences := middleware.NewSilenceCache(rep, time.Hour)
+	var sent []alert.Alert
+	handler := middleware.Silence(silences, nil)(alert.HandlerFunc(func(ctx context.Context, alerts ...alert.Alert) {
+		sent = append(sent, alerts...)
+	}))
+
+	handler.Handle(ctx, alert.Alert{SourceID: "api-1"})
+	handler.Handle(ctx, alert.Alert{SourceID: "api-1"})
+	assert.Equal(t, 1, rep.finds, "the silences are cached")
+	assert.Len(t, sent, 2)
+
+	require.NoError(t, silences.CreateSilence(ctx, &middleware.SilenceRule{
+		Matchers: []middleware.Matcher{{Label: middleware.SourceIDLabel, Operator: middleware.MatchRegexp, Value: "api-.*"}},
+		StartsAt: time.Now().Add(-time.Minute),
+		EndsAt:   time.Now().Add(time.Hour),
+	}))
+	handler.Handle(ctx, alert.Alert{SourceID: "api-1"})
+	assert.Equal(t, 2, rep.finds, "the silences are reloaded after a write")
+	assert.Len(t, sent, 2, "the new silence is applied")
+
+	active, err := silences.FindSilences(ctx, middleware.SilenceFilter{ActiveAt: time.Now()})
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	allocs := testing.AllocsPerRun(100, func() {
+		active[0].Matches(alert.Alert{SourceID: "api-1"})
+	})
+	assert.Zero(t, allocs, "the cached silences are compiled")
+	assert.Equal(t, 2, rep.finds)
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/bldsoft/gost/alert/middleware"
+	"github.com/bldsoft/gost/utils"
+)
+
+type testSilenceRepository struct {
+	silences map[string]*middleware.SilenceRule
+	nextID   int
+}
+
+func newTestSilenceRepository() *testSilenceRepository {
+	return &testSilenceRepository{
+		silences: make(map[string]*middleware.SilenceRule),
+	}
+}
+
+func (r *testSilenceRepository) CreateSilence(ctx context.Context, silence *middleware.SilenceRule) error {
+	r.nextID++
+	silence.ID = strconv.Itoa(r.nextID)
+	s := *silence
+	r.silences[s.ID] = &s
+	return nil
+}
+
+func (r *testSilenceRepository) UpdateSilence(ctx context.Context, silence *middleware.SilenceRule) error {
+	if _, ok := r.silences[silence.ID]; !ok {
+		return utils.ErrObjectNotFound
+	}
+	s := *silence
+	r.silences[s.ID] = &s
+	return nil
+}
+
+func (r *testSilenceRepository) DeleteSilence(ctx context.Context, id string) error {
+	if _, ok := r.silences[id]; !ok {
+		return utils.ErrObjectNotFound
+	}
+	delete(r.silences, id)
+	return nil
+}
+
+func (r *testSilenceRepository) GetSilence(ctx context.Context, id string) (*middleware.SilenceRule, error) {
+	s, ok := r.silences[id]
+	if !ok {
+		return nil, utils.ErrObjectNotFound
+	}
+	return s, nil
+}
+
+func (r *testSilenceRepository) FindSilences(ctx context.Context, filter middleware.SilenceFilter) ([]*middleware.SilenceRule, error) {
+	res := make([]*middleware.SilenceRule, 0, len(r.silences))
+	for _, s := range r.silences {
+		if !filter.ActiveAt.IsZero() && !s.IsActive(filter.ActiveAt) {
+			continue
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
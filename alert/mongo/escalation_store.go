@@ -0,0 +1,141 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/bldsoft/gost/alert"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/mongo"
+	"github.com/bldsoft/gost/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	driver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const DefaultEscalationCollectionName = "alert_escalations"
+
+// EscalationStore is an alert.EscalationStore stored in Mongo.
+type EscalationStore struct {
+	collection *driver.Collection
+}
+
+func NewEscalationStore(db *mongo.Storage, collectionName ...string) *EscalationStore {
+	name := DefaultEscalationCollectionName
+	if len(collectionName) > 0 {
+		name = collectionName[0]
+	}
+	s := &EscalationStore{collection: db.Db.Collection(name)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := s.collection.Indexes().CreateOne(ctx, driver.IndexModel{Keys: bson.D{{Key: "nextAt", Value: 1}}}); err != nil {
+		log.ErrorWithFields(log.Fields{"err": err}, "Failed to create indexes for "+name)
+	}
+	return s
+}
+
+func (s *EscalationStore) Get(ctx context.Context, id string) (*alert.Escalation, error) {
+	var res alert.Escalation
+	if err := mongo.WrapErr(s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&res)); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *EscalationStore) Track(ctx context.Context, e *alert.Escalation) error {
+	onInsert := bson.M{
+		"processorID": e.ProcessorID,
+		"policy":      e.Policy,
+		"step":        e.Step,
+	}
+	if e.NextAt != nil {
+		onInsert["nextAt"] = *e.NextAt
+	}
+	update := bson.M{
+		"$set":         bson.M{"alert": e.Alert},
+		"$setOnInsert": onInsert,
+	}
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": e.ID}, update, options.UpdateOne().SetUpsert(true))
+	return err
+}
+
+func (s *EscalationStore) Ack(ctx context.Context, id, by string, at time.Time) error {
+	update := bson.M{
+		"$set":   bson.M{"ackedAt": at, "ackedBy": by},
+		"$unset": bson.M{"nextAt": ""},
+	}
+	return s.updateOne(ctx, id, update)
+}
+
+func (s *EscalationStore) Unack(ctx context.Context, id string, now time.Time) error {
+	// policy.ackTimeout is stored in nanoseconds, dates are added milliseconds
+	nextAt := bson.M{"$add": bson.A{now, bson.M{"$toLong": bson.M{"$divide": bson.A{"$policy.ackTimeout", int64(time.Millisecond)}}}}}
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			"nextAt":  bson.M{"$cond": bson.A{bson.M{"$lt": bson.A{"$step", "$policy.maxSteps"}}, nextAt, "$$REMOVE"}},
+			"ackedAt": "$$REMOVE",
+			"ackedBy": "$$REMOVE",
+		}},
+	}
+	return s.updateOne(ctx, id, update)
+}
+
+func (s *EscalationStore) updateOne(ctx context.Context, id string, update any) error {
+	res, err := s.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return utils.ErrObjectNotFound
+	}
+	return nil
+}
+
+func (s *EscalationStore) Delete(ctx context.Context, id string) error {
+	res, err := s.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return utils.ErrObjectNotFound
+	}
+	return nil
+}
+
+func (s *EscalationStore) Due(ctx context.Context, now time.Time) ([]*alert.Escalation, error) {
+	return s.find(ctx, bson.M{"nextAt": bson.M{"$lte": now}})
+}
+
+func (s *EscalationStore) Claim(ctx context.Context, e *alert.Escalation, prevStep int, now time.Time) (bool, error) {
+	update := bson.M{"$set": bson.M{"step": e.Step}}
+	if e.NextAt != nil {
+		update["$set"].(bson.M)["nextAt"] = *e.NextAt
+	} else {
+		update["$unset"] = bson.M{"nextAt": ""}
+	}
+	res, err := s.collection.UpdateOne(ctx, bson.M{"_id": e.ID, "step": prevStep, "nextAt": bson.M{"$lte": now}}, update)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+func (s *EscalationStore) List(ctx context.Context) ([]*alert.Escalation, error) {
+	return s.find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+}
+
+func (s *EscalationStore) find(ctx context.Context, filter bson.M, opts ...options.Lister[options.FindOptions]) ([]*alert.Escalation, error) {
+	cur, err := s.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*alert.Escalation, 0)
+	if err := cur.All(ctx, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Compile time checks to ensure your type satisfies an interface
+var _ alert.EscalationStore = (*EscalationStore)(nil)
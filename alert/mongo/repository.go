@@ -0,0 +1,175 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/bldsoft/gost/alert"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/mongo"
+	"github.com/bldsoft/gost/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	driver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DefaultCollectionName = "alerts"
+
+	BsonFieldNameSourceID   = "sourceID"
+	BsonFieldNameSeverity   = "severity"
+	BsonFieldNameFrom       = "from"
+	BsonFieldNameState      = "state"
+	BsonFieldNameResolvedAt = "resolvedAt"
+)
+
+type Record struct {
+	mongo.EntityID    `bson:",inline" json:"-"`
+	alert.AlertRecord `bson:",inline"`
+}
+
+func (r *Record) toAlertRecord() *alert.AlertRecord {
+	res := r.AlertRecord
+	res.ID = r.StringID()
+	return &res
+}
+
+// Repository is an alert.HistoryRepository stored in Mongo.
+type Repository struct {
+	rep        mongo.Repository[Record, *Record]
+	pendingFor time.Duration
+}
+
+func NewRepository(db *mongo.Storage, collectionName ...string) *Repository {
+	name := DefaultCollectionName
+	if len(collectionName) > 0 {
+		name = collectionName[0]
+	}
+	r := &Repository{rep: mongo.NewRepository[Record](db, name)}
+
+	indexes := []driver.IndexModel{
+		{Keys: bson.D{{Key: BsonFieldNameSourceID, Value: 1}, {Key: BsonFieldNameSeverity, Value: 1}, {Key: BsonFieldNameFrom, Value: -1}}},
+		{Keys: bson.D{{Key: BsonFieldNameFrom, Value: -1}}},
+		{Keys: bson.D{{Key: BsonFieldNameState, Value: 1}}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := r.rep.Collection().Indexes().CreateMany(ctx, indexes); err != nil {
+		log.ErrorWithFields(log.Fields{"err": err}, "Failed to create indexes for "+name)
+	}
+	return r
+}
+
+// SetPendingFor sets how long an alert stays pending before it fires. By default, alerts fire immediately.
+func (r *Repository) SetPendingFor(d time.Duration) *Repository {
+	r.pendingFor = d
+	return r
+}
+
+func (r *Repository) CreateAlert(ctx context.Context, a alert.Alert) error {
+	return r.rep.Insert(ctx, &Record{AlertRecord: alert.NewAlertRecord(a, r.pendingFor, time.Now())})
+}
+
+// GetAlert returns the latest alert of the source with the severity.
+func (r *Repository) GetAlert(ctx context.Context, id string, level alert.SeverityLevel) (alert.Alert, error) {
+	record, err := r.latest(ctx, id, level, false)
+	if err != nil {
+		return alert.Alert{}, err
+	}
+	return record.Alert, nil
+}
+
+// UpdateAlert updates the latest not resolved alert of the source with the severity and moves it through the lifecycle.
+func (r *Repository) UpdateAlert(ctx context.Context, id string, level alert.SeverityLevel, newAlert alert.Alert) error {
+	record, err := r.latest(ctx, id, level, true)
+	if err != nil {
+		return err
+	}
+	prevState := record.State
+	if err := record.Update(newAlert, r.pendingFor, time.Now()); err != nil {
+		return err
+	}
+	// the state condition prevents overwriting a concurrent resolution
+	return r.rep.ReplaceOne(ctx, bson.M{"_id": record.EntityID.ID, BsonFieldNameState: prevState}, record)
+}
+
+func (r *Repository) latest(ctx context.Context, sourceID string, level alert.SeverityLevel, notResolved bool) (*Record, error) {
+	filter := bson.M{BsonFieldNameSourceID: sourceID, BsonFieldNameSeverity: level}
+	if notResolved {
+		filter[BsonFieldNameState] = bson.M{"$ne": alert.AlertStateResolved}
+	}
+	records, err := r.rep.Find(ctx, filter, &repository.QueryOptions{
+		Sort:  repository.Sort().Desc(BsonFieldNameFrom),
+		Limit: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return records[0], nil
+}
+
+func (r *Repository) FindAlertByID(ctx context.Context, id string) (*alert.AlertRecord, error) {
+	record, err := r.rep.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return record.toAlertRecord(), nil
+}
+
+// FindAlerts returns the alerts matching the filter, the most recent first.
+func (r *Repository) FindAlerts(ctx context.Context, params *alert.HistoryParams) (*alert.History, error) {
+	filter := historyFilter(params.HistoryFilter)
+	opt := options.Find().
+		SetSort(bson.D{{Key: BsonFieldNameFrom, Value: -1}}).
+		SetSkip(params.Offset).
+		SetLimit(params.Limit)
+
+	cursor, err := r.rep.Collection().Find(ctx, filter, opt)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	res := &alert.History{Alerts: make([]alert.AlertRecord, 0, len(records))}
+	for i := range records {
+		res.Alerts = append(res.Alerts, *records[i].toAlertRecord())
+	}
+	res.TotalCount, err = r.rep.Collection().CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func historyFilter(f alert.HistoryFilter) bson.M {
+	filter := make(bson.M)
+	if len(f.SourceIDs) > 0 {
+		filter[BsonFieldNameSourceID] = bson.M{"$in": f.SourceIDs}
+	}
+	if len(f.Severities) > 0 {
+		filter[BsonFieldNameSeverity] = bson.M{"$in": f.Severities}
+	}
+	if len(f.States) > 0 {
+		filter[BsonFieldNameState] = bson.M{"$in": f.States}
+	}
+	if !f.To.IsZero() {
+		filter[BsonFieldNameFrom] = bson.M{"$lt": f.To}
+	}
+	if !f.From.IsZero() {
+		filter["$or"] = bson.A{
+			bson.M{BsonFieldNameState: bson.M{"$ne": alert.AlertStateResolved}},
+			bson.M{BsonFieldNameResolvedAt: bson.M{"$gte": f.From}},
+		}
+	}
+	return filter
+}
+
+// Compile time checks to ensure your type satisfies an interface
+var _ alert.HistoryRepository = (*Repository)(nil)
@@ -0,0 +1,59 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/alert"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestRecordBson(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := &Record{AlertRecord: alert.NewAlertRecord(alert.Alert{
+		SourceID: "src",
+		Severity: alert.SeverityLevelHigh,
+		From:     from,
+		To:       from.Add(time.Minute),
+	}, 0, from)}
+	record.GenerateID()
+
+	data, err := bson.Marshal(record)
+	require.NoError(t, err)
+	var doc bson.M
+	require.NoError(t, bson.Unmarshal(data, &doc))
+	assert.Equal(t, record.EntityID.ID, doc["_id"])
+	assert.Equal(t, "src", doc[BsonFieldNameSourceID])
+	assert.Equal(t, string(alert.AlertStateResolved), doc[BsonFieldNameState])
+	assert.Contains(t, doc, BsonFieldNameResolvedAt)
+
+	var decoded Record
+	require.NoError(t, bson.Unmarshal(data, &decoded))
+	res := decoded.toAlertRecord()
+	assert.Equal(t, record.StringID(), res.ID)
+	assert.Equal(t, alert.SeverityLevelHigh, res.Severity)
+	assert.True(t, from.Add(time.Minute).Equal(*res.ResolvedAt))
+}
+
+func TestHistoryFilter(t *testing.T) {
+	from := time.Unix(100, 0)
+	to := time.Unix(200, 0)
+	filter := historyFilter(alert.HistoryFilter{
+		SourceIDs:  []string{"src"},
+		Severities: []alert.SeverityLevel{alert.SeverityLevelCritical},
+		From:       from,
+		To:         to,
+	})
+	assert.Equal(t, bson.M{
+		BsonFieldNameSourceID: bson.M{"$in": []string{"src"}},
+		BsonFieldNameSeverity: bson.M{"$in": []alert.SeverityLevel{alert.SeverityLevelCritical}},
+		BsonFieldNameFrom:     bson.M{"$lt": to},
+		"$or": bson.A{
+			bson.M{BsonFieldNameState: bson.M{"$ne": alert.AlertStateResolved}},
+			bson.M{BsonFieldNameResolvedAt: bson.M{"$gte": from}},
+		},
+	}, filter)
+	assert.Empty(t, historyFilter(alert.HistoryFilter{}))
+}
@@ -0,0 +1,118 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/bldsoft/gost/alert/middleware"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/mongo"
+	"github.com/bldsoft/gost/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	driver "go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const DefaultSilenceCollectionName = "alert_silences"
+
+type silenceRecord struct {
+	mongo.EntityID         `bson:",inline" json:"-"`
+	middleware.SilenceRule `bson:",inline"`
+}
+
+func (r *silenceRecord) toSilenceRule() *middleware.SilenceRule {
+	res := r.SilenceRule
+	res.ID = r.StringID()
+	return &res
+}
+
+// SilenceRepository is a middleware.SilenceRepository stored in Mongo.
+type SilenceRepository struct {
+	rep mongo.Repository[silenceRecord, *silenceRecord]
+}
+
+func NewSilenceRepository(db *mongo.Storage, collectionName ...string) *SilenceRepository {
+	name := DefaultSilenceCollectionName
+	if len(collectionName) > 0 {
+		name = collectionName[0]
+	}
+	r := &SilenceRepository{rep: mongo.NewRepository[silenceRecord](db, name)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := r.rep.Collection().Indexes().CreateOne(ctx, driver.IndexModel{Keys: bson.D{{Key: "endsAt", Value: 1}}}); err != nil {
+		log.ErrorWithFields(log.Fields{"err": err}, "Failed to create indexes for "+name)
+	}
+	return r
+}
+
+// CreateSilence inserts the silence and sets its ID.
+func (r *SilenceRepository) CreateSilence(ctx context.Context, silence *middleware.SilenceRule) error {
+	record := &silenceRecord{SilenceRule: *silence}
+	if err := r.rep.Insert(ctx, record); err != nil {
+		return err
+	}
+	silence.ID = record.StringID()
+	return nil
+}
+
+// UpdateSilence updates the matchers, the time window and the comment. The creator and the creation time are kept.
+func (r *SilenceRepository) UpdateSilence(ctx context.Context, silence *middleware.SilenceRule) error {
+	var record silenceRecord
+	if err := record.SetIDFromString(silence.ID); err != nil {
+		return repository.ErrNotFound
+	}
+	return r.rep.UpdateOne(ctx, bson.M{"_id": record.EntityID.ID}, bson.M{"$set": bson.M{
+		"matchers": silence.Matchers,
+		"startsAt": silence.StartsAt,
+		"endsAt":   silence.EndsAt,
+		"comment":  silence.Comment,
+	}})
+}
+
+func (r *SilenceRepository) DeleteSilence(ctx context.Context, id string) error {
+	var record silenceRecord
+	if err := record.SetIDFromString(id); err != nil {
+		return repository.ErrNotFound
+	}
+	res, err := r.rep.Collection().DeleteOne(ctx, bson.M{"_id": record.EntityID.ID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *SilenceRepository) GetSilence(ctx context.Context, id string) (*middleware.SilenceRule, error) {
+	var record silenceRecord
+	if err := record.SetIDFromString(id); err != nil {
+		return nil, repository.ErrNotFound
+	}
+	res, err := r.rep.FindByID(ctx, record.EntityID.ID)
+	if err != nil {
+		return nil, err
+	}
+	return res.toSilenceRule(), nil
+}
+
+// FindSilences returns the silences, the latest ending first.
+func (r *SilenceRepository) FindSilences(ctx context.Context, filter middleware.SilenceFilter) ([]*middleware.SilenceRule, error) {
+	query := bson.M{}
+	if !filter.ActiveAt.IsZero() {
+		query["startsAt"] = bson.M{"$lte": filter.ActiveAt}
+		query["endsAt"] = bson.M{"$gt": filter.ActiveAt}
+	}
+	records, err := r.rep.Find(ctx, query, &repository.QueryOptions{Sort: repository.Sort().Desc("endsAt")})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*middleware.SilenceRule, 0, len(records))
+	for _, record := range records {
+		res = append(res, record.toSilenceRule())
+	}
+	return res, nil
+}
+
+// Compile time checks to ensure your type satisfies an interface
+var _ middleware.SilenceRepository = (*SilenceRepository)(nil)
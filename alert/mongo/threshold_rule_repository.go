@@ -0,0 +1,96 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/bldsoft/gost/alert/threshold"
+	"github.com/bldsoft/gost/mongo"
+	"github.com/bldsoft/gost/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const DefaultThresholdRuleCollectionName = "alert_threshold_rules"
+
+type thresholdRuleRecord struct {
+	mongo.EntityID `bson:",inline" json:"-"`
+	threshold.Rule `bson:",inline"`
+}
+
+func (r *thresholdRuleRecord) toRule() *threshold.Rule {
+	res := r.Rule
+	res.ID = r.StringID()
+	return &res
+}
+
+// ThresholdRuleRepository is a threshold.RuleRepository stored in Mongo.
+type ThresholdRuleRepository struct {
+	rep mongo.Repository[thresholdRuleRecord, *thresholdRuleRecord]
+}
+
+func NewThresholdRuleRepository(db *mongo.Storage, collectionName ...string) *ThresholdRuleRepository {
+	name := DefaultThresholdRuleCollectionName
+	if len(collectionName) > 0 {
+		name = collectionName[0]
+	}
+	return &ThresholdRuleRepository{rep: mongo.NewRepository[thresholdRuleRecord](db, name)}
+}
+
+// CreateRule inserts the rule and sets its ID.
+func (r *ThresholdRuleRepository) CreateRule(ctx context.Context, rule *threshold.Rule) error {
+	record := &thresholdRuleRecord{Rule: *rule}
+	if err := r.rep.Insert(ctx, record); err != nil {
+		return err
+	}
+	rule.ID = record.StringID()
+	return nil
+}
+
+func (r *ThresholdRuleRepository) UpdateRule(ctx context.Context, rule *threshold.Rule) error {
+	record := &thresholdRuleRecord{Rule: *rule}
+	if err := record.SetIDFromString(rule.ID); err != nil {
+		return repository.ErrNotFound
+	}
+	return r.rep.Update(ctx, record)
+}
+
+func (r *ThresholdRuleRepository) DeleteRule(ctx context.Context, id string) error {
+	var record thresholdRuleRecord
+	if err := record.SetIDFromString(id); err != nil {
+		return repository.ErrNotFound
+	}
+	res, err := r.rep.Collection().DeleteOne(ctx, bson.M{"_id": record.EntityID.ID})
+	if err != nil {
+		return mongo.WrapErr(err)
+	}
+	if res.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ThresholdRuleRepository) GetRule(ctx context.Context, id string) (*threshold.Rule, error) {
+	var record thresholdRuleRecord
+	if err := record.SetIDFromString(id); err != nil {
+		return nil, repository.ErrNotFound
+	}
+	res, err := r.rep.FindByID(ctx, record.EntityID.ID)
+	if err != nil {
+		return nil, err
+	}
+	return res.toRule(), nil
+}
+
+func (r *ThresholdRuleRepository) FindRules(ctx context.Context) ([]*threshold.Rule, error) {
+	records, err := r.rep.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*threshold.Rule, 0, len(records))
+	for _, record := range records {
+		res = append(res, record.toRule())
+	}
+	return res, nil
+}
+
+// Compile time checks to ensure your type satisfies an interface
+var _ threshold.RuleRepository = (*ThresholdRuleRepository)(nil)
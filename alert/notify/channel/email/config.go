@@ -4,12 +4,19 @@ import (
 	"cmp"
 	"text/template"
 
+	"github.com/bldsoft/gost/alert/notify/channel"
 	"github.com/bldsoft/gost/config"
 	"github.com/bldsoft/gost/utils"
 
 	_ "embed"
 )
 
+// The names of the email templates that can be overridden with Config.Templates.
+const (
+	SubjectTemplateName = "email_subject"
+	MessageTemplateName = "email_message"
+)
+
 //go:embed default_message.tmpl
 var emailTemplate string
 var DefaultEmailTemplate = template.Must(template.New("email").Parse(emailTemplate))
@@ -23,6 +30,8 @@ type Config struct {
 	SubjectTemplate *template.Template
 	MessageTemplate *template.Template
 	UsePlainText    bool
+	// Templates overrides the templates per receiver, optional.
+	Templates channel.TemplateSource
 }
 
 type SMTPConfig struct {
@@ -25,11 +25,13 @@ func NewEmail(cfg Config) *Email {
 
 func (e *Email) Send(ctx context.Context, receiver Receiver, message channel.Message) error {
 	var subject, body strings.Builder
-	err := e.Cfg.MessageTemplate.Execute(&body, message.Data)
+	messageTemplate := channel.LookupTemplate(e.Cfg.Templates, MessageTemplateName, receiver, e.Cfg.MessageTemplate)
+	err := messageTemplate.Execute(&body, message.Data)
 	if err != nil {
 		return err
 	}
-	err = e.Cfg.SubjectTemplate.Execute(&subject, message.Data)
+	subjectTemplate := channel.LookupTemplate(e.Cfg.Templates, SubjectTemplateName, receiver, e.Cfg.SubjectTemplate)
+	err = subjectTemplate.Execute(&subject, message.Data)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,52 @@
+package mattermost
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/bldsoft/gost/alert/notify/channel"
+	"github.com/bldsoft/gost/alert/notify/channel/webhook"
+)
+
+// Webhook posts the messages to Mattermost incoming webhooks.
+type Webhook struct {
+	Cfg WebhookConfig
+}
+
+func NewWebhook(cfg WebhookConfig) *Webhook {
+	return &Webhook{
+		Cfg: prepareWebhookConfig(cfg),
+	}
+}
+
+func (w *Webhook) client() *http.Client {
+	if w.Cfg.Client != nil {
+		return w.Cfg.Client
+	}
+	return webhook.DefaultClient
+}
+
+func (w *Webhook) Send(ctx context.Context, receiver Receiver, msg channel.Message) error {
+	var text strings.Builder
+	tmpl := channel.LookupTemplate(w.Cfg.Templates, MessageTemplateName, receiver, w.Cfg.MessageTemplate)
+	if err := tmpl.Execute(&text, msg.Data); err != nil {
+		return err
+	}
+	body, err := json.Marshal(struct {
+		Text     string `json:"text"`
+		Channel  string `json:"channel,omitempty"`
+		Username string `json:"username,omitempty"`
+		IconURL  string `json:"icon_url,omitempty"`
+	}{
+		Text:     text.String(),
+		Channel:  receiver.Channel,
+		Username: w.Cfg.Username,
+		IconURL:  w.Cfg.IconURL,
+	})
+	if err != nil {
+		return err
+	}
+	return webhook.Post(ctx, w.client(), receiver.URL, body, "application/json")
+}
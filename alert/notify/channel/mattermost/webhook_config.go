@@ -0,0 +1,37 @@
+package mattermost
+
+import (
+	"cmp"
+	"net/http"
+	"text/template"
+
+	"github.com/bldsoft/gost/alert/notify/channel"
+
+	_ "embed"
+)
+
+// MessageTemplateName is the name of the message template that can be overridden with WebhookConfig.Templates.
+const MessageTemplateName = "mattermost_message"
+
+//go:embed default_message.tmpl
+var messageTemplate string
+var DefaultMessageTemplate = template.Must(template.New("message").Parse(messageTemplate))
+
+type WebhookConfig struct {
+	MessageTemplate *template.Template
+	Username        string // optional, the webhook must be allowed to override it
+	IconURL         string // optional, the webhook must be allowed to override it
+	// Templates overrides the message template per receiver, optional.
+	Templates channel.TemplateSource
+	// Client sends the webhooks, webhook.DefaultClient is used if it isn't set.
+	Client *http.Client
+}
+
+var DefaultWebhookConfig = WebhookConfig{
+	MessageTemplate: DefaultMessageTemplate,
+}
+
+func prepareWebhookConfig(cfg WebhookConfig) WebhookConfig {
+	cfg.MessageTemplate = cmp.Or(cfg.MessageTemplate, DefaultWebhookConfig.MessageTemplate)
+	return cfg
+}
@@ -0,0 +1,13 @@
+package mattermost
+
+import "github.com/bldsoft/gost/alert/notify/channel"
+
+type Receiver struct {
+	URL string
+	// Channel overrides the default channel of the webhook, optional.
+	Channel string
+}
+
+func (r Receiver) IsReceiver() {}
+
+var _ channel.Receiver = Receiver{}
@@ -0,0 +1,43 @@
+package mattermost
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bldsoft/gost/alert/notify/channel"
+	"github.com/bldsoft/gost/alert/notify/channel/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSend(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["channel"] == "unknown" {
+			http.Error(w, "channel not found", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	w := NewWebhook(WebhookConfig{Username: "alerts"})
+	msg := channel.Message{Data: map[string]any{"host": "h1"}}
+	require.NoError(t, w.Send(context.Background(), Receiver{URL: srv.URL, Channel: "ops"}, msg))
+	assert.Equal(t, "ops", body["channel"])
+	assert.Equal(t, "alerts", body["username"])
+	assert.NotContains(t, body, "icon_url")
+	assert.Contains(t, body["text"], "- host: `h1`")
+
+	err := w.Send(context.Background(), Receiver{URL: srv.URL, Channel: "unknown"}, msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
+
+func TestWebhookClient(t *testing.T) {
+	assert.Same(t, webhook.DefaultClient, NewWebhook(WebhookConfig{}).client(), "the default client has a timeout")
+	client := &http.Client{}
+	assert.Same(t, client, NewWebhook(WebhookConfig{Client: client}).client())
+}
@@ -9,15 +9,21 @@ import (
 
 type Webhook struct {
 	webhook.Webhook
+	cfg WebhookConfig
 }
 
 func NewWebhook(cfg WebhookConfig) *Webhook {
 	webhookConfig := prepareWebhookConfig(cfg)
 	return &Webhook{
 		Webhook: webhook.Webhook{Cfg: webhookConfig},
+		cfg:     cfg,
 	}
 }
 
 func (w *Webhook) Send(ctx context.Context, receiver Receiver, msg channel.Message) error {
+	if tmpl := channel.LookupTemplate(w.cfg.Templates, MessageTemplateName, receiver, nil); tmpl != nil {
+		body, mimeType := bodyFormatFunc(tmpl, w.cfg.ColorTemplate)(msg)
+		return webhook.Post(ctx, w.Client(), receiver.URL, body, mimeType)
+	}
 	return w.Webhook.Send(ctx, webhook.Receiver{URL: receiver.URL}, msg)
 }
@@ -3,6 +3,7 @@ package slack
 import (
 	"cmp"
 	"encoding/json"
+	"net/http"
 	"strings"
 	"text/template"
 
@@ -12,6 +13,9 @@ import (
 	_ "embed"
 )
 
+// MessageTemplateName is the name of the message template that can be overridden with WebhookConfig.Templates.
+const MessageTemplateName = "slack_message"
+
 //go:embed default_message.tmpl
 var messageTemplate string
 var DefaultMessageTemplate = template.Must(template.New("message").Parse(messageTemplate))
@@ -19,6 +23,10 @@ var DefaultMessageTemplate = template.Must(template.New("message").Parse(message
 type WebhookConfig struct {
 	MessageTemplate *template.Template
 	ColorTemplate   *template.Template // optional attachment color
+	// Templates overrides the message template per receiver, optional.
+	Templates channel.TemplateSource
+	// Client sends the webhooks, webhook.DefaultClient is used if it isn't set.
+	Client *http.Client
 }
 
 var DefaultWebhookConfig = WebhookConfig{
@@ -29,6 +37,7 @@ func prepareWebhookConfig(cfg WebhookConfig) webhook.Config {
 	cfg.MessageTemplate = cmp.Or(cfg.MessageTemplate, DefaultWebhookConfig.MessageTemplate)
 	return webhook.Config{
 		BodyFormat: bodyFormatFunc(cfg.MessageTemplate, cfg.ColorTemplate),
+		Client:     cfg.Client,
 	}
 }
 
@@ -0,0 +1,51 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"text/template"
+
+	"github.com/bldsoft/gost/alert/notify/channel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type templateSource map[string]*template.Template
+
+func (s templateSource) Template(name string, receiver channel.Receiver) *template.Template {
+	return s[name]
+}
+
+type countingTransport struct {
+	calls atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWebhookTemplateOverrideClient(t *testing.T) {
+	var body struct {
+		Text string `json:"text"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer srv.Close()
+
+	transport := &countingTransport{}
+	w := NewWebhook(WebhookConfig{
+		Templates: templateSource{MessageTemplateName: template.Must(template.New("").Parse(`{{index . "id"}} is down`))},
+		Client:    &http.Client{Transport: transport},
+	})
+	msg := channel.Message{Data: map[string]any{"id": "db"}}
+	require.NoError(t, w.Send(context.Background(), Receiver{URL: srv.URL}, msg))
+
+	assert.Equal(t, "db is down", body.Text)
+	assert.Equal(t, int32(1), transport.calls.Load(), "the override is sent with the configured client")
+}
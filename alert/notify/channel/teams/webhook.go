@@ -0,0 +1,36 @@
+package teams
+
+import (
+	"cmp"
+	"context"
+
+	"github.com/bldsoft/gost/alert/notify/channel"
+	"github.com/bldsoft/gost/alert/notify/channel/webhook"
+)
+
+// Webhook posts the messages as Adaptive Cards to Microsoft Teams.
+type Webhook struct {
+	webhook.Webhook
+	cfg WebhookConfig
+}
+
+func NewWebhook(cfg WebhookConfig) *Webhook {
+	cfg = prepareWebhookConfig(cfg)
+	return &Webhook{
+		Webhook: webhook.Webhook{Cfg: webhook.Config{BodyFormat: bodyFormatFunc(cfg)}},
+		cfg:     cfg,
+	}
+}
+
+func (w *Webhook) Send(ctx context.Context, receiver Receiver, msg channel.Message) error {
+	title := channel.LookupTemplate(w.cfg.Templates, TitleTemplateName, receiver, nil)
+	message := channel.LookupTemplate(w.cfg.Templates, MessageTemplateName, receiver, nil)
+	if title != nil || message != nil {
+		cfg := w.cfg
+		cfg.TitleTemplate = cmp.Or(title, cfg.TitleTemplate)
+		cfg.MessageTemplate = cmp.Or(message, cfg.MessageTemplate)
+		body, mimeType := bodyFormatFunc(cfg)(msg)
+		return webhook.Post(ctx, w.Client(), receiver.URL, body, mimeType)
+	}
+	return w.Webhook.Send(ctx, webhook.Receiver{URL: receiver.URL}, msg)
+}
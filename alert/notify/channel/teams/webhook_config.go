@@ -0,0 +1,115 @@
+package teams
+
+import (
+	"cmp"
+	"encoding/json"
+	"strings"
+	"text/template"
+
+	"github.com/bldsoft/gost/alert/notify/channel"
+
+	_ "embed"
+)
+
+// The names of the templates that can be overridden with WebhookConfig.Templates.
+const (
+	TitleTemplateName   = "teams_title"
+	MessageTemplateName = "teams_message"
+)
+
+//go:embed default_title.tmpl
+var titleTemplate string
+var DefaultTitleTemplate = template.Must(template.New("title").Parse(titleTemplate))
+
+//go:embed default_message.tmpl
+var messageTemplate string
+var DefaultMessageTemplate = template.Must(template.New("message").Parse(messageTemplate))
+
+type WebhookConfig struct {
+	TitleTemplate   *template.Template
+	MessageTemplate *template.Template // Adaptive Card markdown
+	ColorTemplate   *template.Template // optional title color: default, accent, good, warning or attention
+	// Templates overrides the title and message templates per receiver, optional.
+	Templates channel.TemplateSource
+}
+
+var DefaultWebhookConfig = WebhookConfig{
+	TitleTemplate:   DefaultTitleTemplate,
+	MessageTemplate: DefaultMessageTemplate,
+}
+
+func prepareWebhookConfig(cfg WebhookConfig) WebhookConfig {
+	cfg.TitleTemplate = cmp.Or(cfg.TitleTemplate, DefaultWebhookConfig.TitleTemplate)
+	cfg.MessageTemplate = cmp.Or(cfg.MessageTemplate, DefaultWebhookConfig.MessageTemplate)
+	return cfg
+}
+
+type textBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Wrap   bool   `json:"wrap"`
+	Size   string `json:"size,omitempty"`
+	Weight string `json:"weight,omitempty"`
+	Color  string `json:"color,omitempty"`
+}
+
+type adaptiveCard struct {
+	Schema  string      `json:"$schema"`
+	Type    string      `json:"type"`
+	Version string      `json:"version"`
+	Body    []textBlock `json:"body"`
+}
+
+type attachment struct {
+	ContentType string       `json:"contentType"`
+	Content     adaptiveCard `json:"content"`
+}
+
+type message struct {
+	Type        string       `json:"type"`
+	Attachments []attachment `json:"attachments"`
+}
+
+func execute(tmpl *template.Template, data any) string {
+	if tmpl == nil {
+		return ""
+	}
+	var res strings.Builder
+	if err := tmpl.Execute(&res, data); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(res.String())
+}
+
+func bodyFormatFunc(cfg WebhookConfig) func(msg channel.Message) (body []byte, mimeType string) {
+	return func(msg channel.Message) (body []byte, mimeType string) {
+		card := adaptiveCard{
+			Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+			Type:    "AdaptiveCard",
+			Version: "1.4",
+			Body: []textBlock{
+				{
+					Type:   "TextBlock",
+					Text:   execute(cfg.TitleTemplate, msg.Data),
+					Wrap:   true,
+					Size:   "Medium",
+					Weight: "Bolder",
+					Color:  execute(cfg.ColorTemplate, msg.Data),
+				},
+				{
+					Type: "TextBlock",
+					Text: execute(cfg.MessageTemplate, msg.Data),
+					Wrap: true,
+				},
+			},
+		}
+		body, _ = json.Marshal(message{
+			Type: "message",
+			Attachments: []attachment{{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content:     card,
+			}},
+		})
+		return body, "application/json"
+	}
+}
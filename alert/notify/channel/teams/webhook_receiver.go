@@ -0,0 +1,12 @@
+package teams
+
+import "github.com/bldsoft/gost/alert/notify/channel"
+
+// Receiver is a Teams incoming webhook or a Workflows webhook accepting Adaptive Cards.
+type Receiver struct {
+	URL string
+}
+
+func (r Receiver) IsReceiver() {}
+
+var _ channel.Receiver = Receiver{}
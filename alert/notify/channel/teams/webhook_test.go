@@ -0,0 +1,40 @@
+package teams
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+
+	"github.com/bldsoft/gost/alert/notify/channel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSend(t *testing.T) {
+	var body message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer srv.Close()
+
+	w := NewWebhook(WebhookConfig{
+		TitleTemplate: template.Must(template.New("").Parse(`{{index . "id"}} alert`)),
+		ColorTemplate: template.Must(template.New("").Parse("attention")),
+	})
+	msg := channel.Message{Data: map[string]any{"id": "db", "host": "h1"}}
+	require.NoError(t, w.Send(context.Background(), Receiver{URL: srv.URL}, msg))
+
+	assert.Equal(t, "message", body.Type)
+	require.Len(t, body.Attachments, 1)
+	assert.Equal(t, "application/vnd.microsoft.card.adaptive", body.Attachments[0].ContentType)
+	card := body.Attachments[0].Content
+	assert.Equal(t, "AdaptiveCard", card.Type)
+	require.Len(t, card.Body, 2)
+	assert.Equal(t, "db alert", card.Body[0].Text)
+	assert.Equal(t, "attention", card.Body[0].Color)
+	assert.Contains(t, card.Body[1].Text, "- host: `h1`")
+}
@@ -0,0 +1,46 @@
+package telegram
+
+import (
+	"cmp"
+	"net/http"
+	"text/template"
+
+	"github.com/bldsoft/gost/alert/notify/channel"
+	"github.com/bldsoft/gost/utils"
+
+	_ "embed"
+)
+
+// MessageTemplateName is the name of the message template that can be overridden with Config.Templates.
+const MessageTemplateName = "telegram_message"
+
+//go:embed default_message.tmpl
+var messageTemplate string
+var DefaultMessageTemplate = template.Must(template.New("message").Parse(messageTemplate))
+
+type Config struct {
+	BotToken utils.FullyHidden
+	// APIURL is the Bot API server URL, https://api.telegram.org by default.
+	APIURL string
+	// MessageTemplate renders the message text in the ParseMode format.
+	MessageTemplate *template.Template
+	// ParseMode is the Bot API parse mode, HTML by default.
+	ParseMode string
+	// Templates overrides the message template per receiver, optional.
+	Templates channel.TemplateSource
+	// Client sends the Bot API requests, webhook.DefaultClient is used if it isn't set.
+	Client *http.Client
+}
+
+var DefaultConfig = Config{
+	APIURL:          "https://api.telegram.org",
+	MessageTemplate: DefaultMessageTemplate,
+	ParseMode:       "HTML",
+}
+
+func prepareConfig(cfg Config) Config {
+	cfg.APIURL = cmp.Or(cfg.APIURL, DefaultConfig.APIURL)
+	cfg.MessageTemplate = cmp.Or(cfg.MessageTemplate, DefaultConfig.MessageTemplate)
+	cfg.ParseMode = cmp.Or(cfg.ParseMode, DefaultConfig.ParseMode)
+	return cfg
+}
@@ -0,0 +1,12 @@
+package telegram
+
+import "github.com/bldsoft/gost/alert/notify/channel"
+
+type Receiver struct {
+	// ChatID is the chat ID or the @username of the channel.
+	ChatID string
+}
+
+func (r Receiver) IsReceiver() {}
+
+var _ channel.Receiver = Receiver{}
@@ -0,0 +1,60 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bldsoft/gost/alert/notify/channel"
+	"github.com/bldsoft/gost/alert/notify/channel/webhook"
+)
+
+// Telegram sends messages via the Telegram Bot API.
+type Telegram struct {
+	Cfg Config
+}
+
+func NewTelegram(cfg Config) *Telegram {
+	return &Telegram{
+		Cfg: prepareConfig(cfg),
+	}
+}
+
+func (t *Telegram) client() *http.Client {
+	if t.Cfg.Client != nil {
+		return t.Cfg.Client
+	}
+	return webhook.DefaultClient
+}
+
+func (t *Telegram) Send(ctx context.Context, receiver Receiver, msg channel.Message) error {
+	var text strings.Builder
+	tmpl := channel.LookupTemplate(t.Cfg.Templates, MessageTemplateName, receiver, t.Cfg.MessageTemplate)
+	if err := tmpl.Execute(&text, msg.Data); err != nil {
+		return err
+	}
+	body, err := json.Marshal(struct {
+		ChatID                string `json:"chat_id"`
+		Text                  string `json:"text"`
+		ParseMode             string `json:"parse_mode"`
+		DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+	}{
+		ChatID:                receiver.ChatID,
+		Text:                  text.String(),
+		ParseMode:             t.Cfg.ParseMode,
+		DisableWebPagePreview: true,
+	})
+	if err != nil {
+		return err
+	}
+	apiURL := strings.TrimSuffix(t.Cfg.APIURL, "/") + "/bot" + t.Cfg.BotToken.String() + "/sendMessage"
+	err = webhook.Post(ctx, t.client(), apiURL, body, "application/json")
+	if urlErr := (*url.Error)(nil); errors.As(err, &urlErr) {
+		return fmt.Errorf("telegram: %w", urlErr.Err) // the url contains the bot token
+	}
+	return err
+}
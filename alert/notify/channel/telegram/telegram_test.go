@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bldsoft/gost/alert/notify/channel"
+	"github.com/bldsoft/gost/alert/notify/channel/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelegramSend(t *testing.T) {
+	var (
+		path string
+		body map[string]any
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["chat_id"] == "unknown" {
+			http.Error(w, `{"ok":false,"description":"Bad Request: chat not found"}`, http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	tg := NewTelegram(Config{BotToken: "123:secret", APIURL: srv.URL})
+	msg := channel.Message{Data: map[string]any{"host": "<db>"}}
+	require.NoError(t, tg.Send(context.Background(), Receiver{ChatID: "@alerts"}, msg))
+	assert.Equal(t, "/bot123:secret/sendMessage", path)
+	assert.Equal(t, "@alerts", body["chat_id"])
+	assert.Equal(t, "HTML", body["parse_mode"])
+	assert.Contains(t, body["text"], "<code>&lt;db&gt;</code>")
+
+	err := tg.Send(context.Background(), Receiver{ChatID: "unknown"}, msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "chat not found")
+
+	srv.Close()
+	err = tg.Send(context.Background(), Receiver{ChatID: "@alerts"}, msg)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+}
+
+func TestTelegramClient(t *testing.T) {
+	assert.Same(t, webhook.DefaultClient, NewTelegram(Config{}).client(), "the default client has a timeout")
+	client := &http.Client{}
+	assert.Same(t, client, NewTelegram(Config{Client: client}).client())
+}
@@ -0,0 +1,20 @@
+package channel
+
+import "text/template"
+
+// TemplateSource overrides the templates of the channels, e.g. with the templates edited by the users.
+type TemplateSource interface {
+	// Template returns the override of the named template for the receiver or nil if there is none.
+	Template(name string, receiver Receiver) *template.Template
+}
+
+// LookupTemplate returns the override of the named template for the receiver or def if there is none.
+func LookupTemplate(src TemplateSource, name string, receiver Receiver, def *template.Template) *template.Template {
+	if src == nil {
+		return def
+	}
+	if tmpl := src.Template(name, receiver); tmpl != nil {
+		return tmpl
+	}
+	return def
+}
@@ -2,14 +2,27 @@ package webhook
 
 import (
 	"encoding/json"
+	"net/http"
+	"time"
 
 	_ "embed"
 
 	"github.com/bldsoft/gost/alert/notify/channel"
 )
 
+// BodyTemplateName is the name of the webhook body template that can be set with Config.Templates.
+// The rendered template is posted as JSON instead of the BodyFormat result.
+const BodyTemplateName = "webhook_body"
+
+// DefaultClient sends the webhooks if Config.Client isn't set. Unlike http.DefaultClient, it has a timeout.
+var DefaultClient = &http.Client{Timeout: 30 * time.Second}
+
 type Config struct {
 	BodyFormat func(msg channel.Message) (body []byte, mimeType string)
+	// Templates sets the body template per receiver, optional.
+	Templates channel.TemplateSource
+	// Client sends the webhooks, DefaultClient is used if it isn't set.
+	Client *http.Client
 }
 
 var DefaultWebhookConfig = Config{
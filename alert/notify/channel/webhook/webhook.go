@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/bldsoft/gost/alert/notify/channel"
 )
@@ -21,20 +22,35 @@ func NewWebhook(cfg Config) *Webhook {
 	}
 }
 
-func (w *Webhook) client() *http.Client {
-	return http.DefaultClient
+// Client returns the configured client or DefaultClient.
+func (w *Webhook) Client() *http.Client {
+	if w.Cfg.Client != nil {
+		return w.Cfg.Client
+	}
+	return DefaultClient
 }
 
 func (w *Webhook) Send(ctx context.Context, receiver Receiver, msg channel.Message) error {
+	if tmpl := channel.LookupTemplate(w.Cfg.Templates, BodyTemplateName, receiver, nil); tmpl != nil {
+		var body strings.Builder
+		if err := tmpl.Execute(&body, msg.Data); err != nil {
+			return err
+		}
+		return Post(ctx, w.Client(), receiver.URL, []byte(body.String()), "application/json")
+	}
 	body, mimeType := w.Cfg.BodyFormat(msg)
+	return Post(ctx, w.Client(), receiver.URL, body, mimeType)
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, receiver.URL, bytes.NewBuffer(body))
+// Post posts the body to the url. Responses with status >= 300 are returned as errors.
+func Post(ctx context.Context, client *http.Client, url string, body []byte, mimeType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", mimeType)
 
-	resp, err := w.client().Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
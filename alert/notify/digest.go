@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/bldsoft/gost/utils/poly"
+
+	_ "embed"
+)
+
+const (
+	// DigestMsgKey is the digest message data key holding the rendered digest text.
+	DigestMsgKey = "digest"
+	// DigestCountMsgKey is the digest message data key holding the number of the folded notifications.
+	DigestCountMsgKey = "digestCount"
+
+	defaultMaxDigestSize = 100
+)
+
+//go:embed digest.tmpl
+var digestTemplate string
+var DefaultDigestTemplate = template.Must(template.New("digest").Parse(digestTemplate))
+
+// DigestData is the DigestTemplate data.
+type DigestData struct {
+	Count    int
+	Since    time.Time
+	Messages []Message
+	// Omitted is the number of the messages over RateLimitConfig.MaxDigestSize.
+	Omitted int
+}
+
+type digest struct {
+	receiver poly.Poly[Receiver]
+	data     DigestData
+}
+
+// digests collects the rate limited notifications per receiver.
+type digests struct {
+	maxSize int
+	mtx     sync.Mutex
+	pending map[string]*digest
+}
+
+func newDigests(maxSize int) *digests {
+	if maxSize <= 0 {
+		maxSize = defaultMaxDigestSize
+	}
+	return &digests{
+		maxSize: maxSize,
+		pending: make(map[string]*digest),
+	}
+}
+
+func (d *digests) add(key string, n Notification, now time.Time) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	dg, ok := d.pending[key]
+	if !ok {
+		dg = &digest{receiver: n.Receiver, data: DigestData{Since: now}}
+		d.pending[key] = dg
+	}
+	dg.data.Count++
+	if len(dg.data.Messages) < d.maxSize {
+		dg.data.Messages = append(dg.data.Messages, n.Message)
+	} else {
+		dg.data.Omitted++
+	}
+}
+
+// flush returns the collected digests and starts new ones.
+func (d *digests) flush() []*digest {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	res := make([]*digest, 0, len(d.pending))
+	for key, dg := range d.pending {
+		res = append(res, dg)
+		delete(d.pending, key)
+	}
+	return res
+}
+
+// DigestMessage renders the digest data with the template into the DigestMsgKey message data.
+func DigestMessage(tmpl *template.Template, data DigestData) (Message, error) {
+	var text strings.Builder
+	if err := tmpl.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	return Message{Data: map[string]any{
+		DigestMsgKey:      strings.TrimSpace(text.String()),
+		DigestCountMsgKey: data.Count,
+	}}, nil
+}
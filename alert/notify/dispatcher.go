@@ -7,7 +7,10 @@ import (
 
 	"github.com/bldsoft/gost/alert/notify/channel"
 	"github.com/bldsoft/gost/alert/notify/channel/email"
+	"github.com/bldsoft/gost/alert/notify/channel/mattermost"
 	"github.com/bldsoft/gost/alert/notify/channel/slack"
+	"github.com/bldsoft/gost/alert/notify/channel/teams"
+	"github.com/bldsoft/gost/alert/notify/channel/telegram"
 	"github.com/bldsoft/gost/alert/notify/channel/webhook"
 	"github.com/bldsoft/gost/utils/poly"
 )
@@ -22,36 +25,87 @@ func init() {
 	poly.Register[Receiver]().
 		Type("email", email.Receiver{}).
 		Type("slack_webhook", slack.Receiver{}).
-		Type("webhook", webhook.Receiver{})
+		Type("webhook", webhook.Receiver{}).
+		Type("telegram", telegram.Receiver{}).
+		Type("teams_webhook", teams.Receiver{}).
+		Type("mattermost_webhook", mattermost.Receiver{})
 }
 
 type DispatcherConfig struct {
-	Email        *email.Config
-	SlackWebhook *slack.WebhookConfig
-	Webhook      *webhook.Config
+	Email             *email.Config
+	SlackWebhook      *slack.WebhookConfig
+	Webhook           *webhook.Config
+	Telegram          *telegram.Config
+	TeamsWebhook      *teams.WebhookConfig
+	MattermostWebhook *mattermost.WebhookConfig
+	// Templates overrides the channel templates, e.g. with a TemplateStore, see TemplateNames.
+	// It's used by the channels whose config doesn't set its own.
+	Templates channel.TemplateSource
 }
 
 type Dispatcher struct {
-	email   channelWrapper[email.Receiver]
-	slack   channelWrapper[slack.Receiver]
-	webhook channelWrapper[webhook.Receiver]
+	email      channelWrapper[email.Receiver]
+	slack      channelWrapper[slack.Receiver]
+	webhook    channelWrapper[webhook.Receiver]
+	telegram   channelWrapper[telegram.Receiver]
+	teams      channelWrapper[teams.Receiver]
+	mattermost channelWrapper[mattermost.Receiver]
 }
 
 func NewDispatcher(cfg DispatcherConfig) *Dispatcher {
 	d := &Dispatcher{}
 	if cfg.Email != nil {
+		emailCfg := *cfg.Email
+		if emailCfg.Templates == nil {
+			emailCfg.Templates = cfg.Templates
+		}
 		d.email = channelWrapper[email.Receiver]{
-			channel: email.NewEmail(*cfg.Email),
+			channel: email.NewEmail(emailCfg),
 		}
 	}
 	if cfg.SlackWebhook != nil {
+		slackCfg := *cfg.SlackWebhook
+		if slackCfg.Templates == nil {
+			slackCfg.Templates = cfg.Templates
+		}
 		d.slack = channelWrapper[slack.Receiver]{
-			channel: slack.NewWebhook(*cfg.SlackWebhook),
+			channel: slack.NewWebhook(slackCfg),
 		}
 	}
 	if cfg.Webhook != nil {
+		webhookCfg := *cfg.Webhook
+		if webhookCfg.Templates == nil {
+			webhookCfg.Templates = cfg.Templates
+		}
 		d.webhook = channelWrapper[webhook.Receiver]{
-			channel: webhook.NewWebhook(*cfg.Webhook),
+			channel: webhook.NewWebhook(webhookCfg),
+		}
+	}
+	if cfg.Telegram != nil {
+		telegramCfg := *cfg.Telegram
+		if telegramCfg.Templates == nil {
+			telegramCfg.Templates = cfg.Templates
+		}
+		d.telegram = channelWrapper[telegram.Receiver]{
+			channel: telegram.NewTelegram(telegramCfg),
+		}
+	}
+	if cfg.TeamsWebhook != nil {
+		teamsCfg := *cfg.TeamsWebhook
+		if teamsCfg.Templates == nil {
+			teamsCfg.Templates = cfg.Templates
+		}
+		d.teams = channelWrapper[teams.Receiver]{
+			channel: teams.NewWebhook(teamsCfg),
+		}
+	}
+	if cfg.MattermostWebhook != nil {
+		mattermostCfg := *cfg.MattermostWebhook
+		if mattermostCfg.Templates == nil {
+			mattermostCfg.Templates = cfg.Templates
+		}
+		d.mattermost = channelWrapper[mattermost.Receiver]{
+			channel: mattermost.NewWebhook(mattermostCfg),
 		}
 	}
 	return d
@@ -65,6 +119,12 @@ func (d *Dispatcher) Send(ctx context.Context, notification Notification) error
 		return d.slack.Send(ctx, rcv, notification.Message)
 	case webhook.Receiver:
 		return d.webhook.Send(ctx, rcv, notification.Message)
+	case telegram.Receiver:
+		return d.telegram.Send(ctx, rcv, notification.Message)
+	case teams.Receiver:
+		return d.teams.Send(ctx, rcv, notification.Message)
+	case mattermost.Receiver:
+		return d.mattermost.Send(ctx, rcv, notification.Message)
 	default:
 		return fmt.Errorf("%w: %s", ErrUnknownNotificationType, rcv)
 	}
@@ -0,0 +1,136 @@
+package mongo
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bldsoft/gost/controller"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// DeadLetterController lists and redrives the dead letters of the queue.
+type DeadLetterController struct {
+	controller.BaseController
+	queue *Queue
+}
+
+func NewDeadLetterController(queue *Queue) *DeadLetterController {
+	return &DeadLetterController{queue: queue}
+}
+
+type DeadLettersParams struct {
+	Offset int64 `json:"offset,omitempty" schema:"offset,omitempty"`
+	Limit  int64 `json:"limit,omitempty" schema:"limit,omitempty"`
+}
+
+type RedriveParams struct {
+	// RetryCount is the number of retries of the redriven notifications, 1 if not set.
+	RetryCount int `json:"retryCount,omitempty" schema:"retryCount,omitempty"`
+}
+
+type redriveResult struct {
+	Redriven int `json:"redriven"`
+}
+
+func (c *DeadLetterController) responseError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrObjectNotFound):
+		c.ResponseError(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	default:
+		log.FromContext(r.Context()).Error(err.Error())
+		c.ResponseError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// GetDeadLettersHandler lists the dead letters, the most recently failed first.
+// @Summary list notification dead letters
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param offset query int false "Offset"
+// @Param limit query int false "Limit"
+// @Produce json
+// @Success 200 {object} DeadLetters "OK"
+// @Router /notify/dead-letters [get]
+func (c *DeadLetterController) GetDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := utils.FromRequest[DeadLettersParams](r)
+	if err != nil {
+		c.ResponseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := c.queue.DeadLetters(r.Context(), params.Offset, params.Limit)
+	if err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, res)
+}
+
+// RedriveHandler moves a dead letter back to the retry queue.
+// @Summary redrive a notification dead letter
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param id path string true "Dead letter ID"
+// @Param retryCount query int false "Number of retries"
+// @Success 200 {string} string "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /notify/dead-letters/{id}/redrive [post]
+func (c *DeadLetterController) RedriveHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := utils.FromRequest[RedriveParams](r)
+	if err != nil {
+		c.ResponseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.queue.Redrive(r.Context(), chi.URLParam(r, "id"), params.RetryCount); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseOK(w)
+}
+
+// RedriveAllHandler moves all dead letters back to the retry queue.
+// @Summary redrive all notification dead letters
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param retryCount query int false "Number of retries"
+// @Produce json
+// @Success 200 {object} redriveResult "OK"
+// @Router /notify/dead-letters/redrive [post]
+func (c *DeadLetterController) RedriveAllHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := utils.FromRequest[RedriveParams](r)
+	if err != nil {
+		c.ResponseError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n, err := c.queue.RedriveAll(r.Context(), params.RetryCount)
+	if err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, redriveResult{Redriven: n})
+}
+
+// DeleteDeadLetterHandler removes a dead letter permanently.
+// @Summary delete a notification dead letter
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param id path string true "Dead letter ID"
+// @Success 200 {string} string "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /notify/dead-letters/{id} [delete]
+func (c *DeadLetterController) DeleteDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	if err := c.queue.DeleteDeadLetter(r.Context(), chi.URLParam(r, "id")); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseOK(w)
+}
+
+// Mount it with r.Route("/notify/dead-letters", c.Mount).
+func (c *DeadLetterController) Mount(r chi.Router) {
+	r.Get("/", c.GetDeadLettersHandler)
+	r.Post("/redrive", c.RedriveAllHandler)
+	r.Post("/{id}/redrive", c.RedriveHandler)
+	r.Delete("/{id}", c.DeleteDeadLetterHandler)
+}
@@ -0,0 +1,364 @@
+package mongo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bldsoft/gost/alert/notify"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/mongo"
+	"github.com/bldsoft/gost/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	driver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DefaultQueueCollectionName      = "notify_queue"
+	DefaultDeadLetterCollectionName = "notify_dead_letter"
+
+	// illegalOperationCode is returned by the standalone servers for transactions
+	illegalOperationCode = 20
+)
+
+type QueueConfig struct {
+	QueueCollectionName      string
+	DeadLetterCollectionName string
+	// VisibilityTimeout is how long a dequeued notification is hidden from the other consumers.
+	// If it isn't marked done or requeued in time (e.g. the consumer has crashed), it's dequeued again.
+	VisibilityTimeout time.Duration
+	// BackoffBase is the Requeue delay after the first attempt, it's doubled after each next attempt up to BackoffMax.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+var DefaultQueueConfig = QueueConfig{
+	QueueCollectionName:      DefaultQueueCollectionName,
+	DeadLetterCollectionName: DefaultDeadLetterCollectionName,
+	VisibilityTimeout:        5 * time.Minute,
+	BackoffBase:              time.Minute,
+	BackoffMax:               time.Hour,
+}
+
+type queuedNotification struct {
+	ID           bson.ObjectID       `bson:"_id,omitempty"`
+	Notification notify.Notification `bson:"notification"`
+	RetryAt      time.Time           `bson:"retryAt"`
+	RetryCount   int                 `bson:"retryCount"`
+	Attempts     int                 `bson:"attempts"`
+	CreatedAt    time.Time           `bson:"createdAt"`
+}
+
+// DeadLetter is a notification whose retries are exhausted.
+type DeadLetter struct {
+	ID           bson.ObjectID       `json:"id" bson:"_id"`
+	Notification notify.Notification `json:"notification" bson:"notification"`
+	Attempts     int                 `json:"attempts" bson:"attempts"`
+	Error        string              `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt    time.Time           `json:"createdAt" bson:"createdAt"`
+	FailedAt     time.Time           `json:"failedAt" bson:"failedAt"`
+}
+
+type DeadLetters struct {
+	DeadLetters []DeadLetter `json:"deadLetters"`
+	TotalCount  int64        `json:"totalCount"`
+}
+
+// Queue is a notify.DeadLetterQueue persisted in Mongo and shared by the service replicas.
+// The notifications are moved between the queue and the dead letters in transactions,
+// see withTransaction for the standalone servers.
+type Queue struct {
+	cfg        QueueConfig
+	client     *driver.Client
+	queue      *driver.Collection
+	deadLetter *driver.Collection
+}
+
+var _ notify.DeadLetterQueue = (*Queue)(nil)
+
+// NewQueue creates the queue. Zero config fields are set from DefaultQueueConfig.
+func NewQueue(db *mongo.Storage, cfg QueueConfig) *Queue {
+	cfg.QueueCollectionName = cmp.Or(cfg.QueueCollectionName, DefaultQueueConfig.QueueCollectionName)
+	cfg.DeadLetterCollectionName = cmp.Or(cfg.DeadLetterCollectionName, DefaultQueueConfig.DeadLetterCollectionName)
+	cfg.VisibilityTimeout = cmp.Or(cfg.VisibilityTimeout, DefaultQueueConfig.VisibilityTimeout)
+	cfg.BackoffBase = cmp.Or(cfg.BackoffBase, DefaultQueueConfig.BackoffBase)
+	cfg.BackoffMax = cmp.Or(cfg.BackoffMax, DefaultQueueConfig.BackoffMax)
+
+	q := &Queue{
+		cfg:        cfg,
+		client:     db.Client,
+		queue:      db.Db.Collection(cfg.QueueCollectionName),
+		deadLetter: db.Db.Collection(cfg.DeadLetterCollectionName),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := q.queue.Indexes().CreateOne(ctx, driver.IndexModel{Keys: bson.D{{Key: "retryAt", Value: 1}}}); err != nil {
+		log.ErrorWithFields(log.Fields{"err": err}, "Failed to create indexes for "+cfg.QueueCollectionName)
+	}
+	if _, err := q.deadLetter.Indexes().CreateOne(ctx, driver.IndexModel{Keys: bson.D{{Key: "failedAt", Value: -1}}}); err != nil {
+		log.ErrorWithFields(log.Fields{"err": err}, "Failed to create indexes for "+cfg.DeadLetterCollectionName)
+	}
+	return q
+}
+
+func (q *Queue) Enqueue(ctx context.Context, n notify.RetriedNotification) error {
+	_, err := q.queue.InsertOne(ctx, queuedNotification{
+		Notification: n.Notification,
+		RetryAt:      n.RetryAt,
+		RetryCount:   n.RetryCount,
+		CreatedAt:    time.Now(),
+	})
+	return err
+}
+
+// Dequeue atomically takes the notification with the earliest due RetryAt and hides it for VisibilityTimeout.
+// utils.ErrObjectNotFound is returned if there is no due notification.
+func (q *Queue) Dequeue(ctx context.Context) (id string, _ *notify.RetriedNotification, err error) {
+	now := time.Now()
+	var doc queuedNotification
+	err = q.queue.FindOneAndUpdate(ctx,
+		bson.M{"retryAt": bson.M{"$lte": now}},
+		bson.M{
+			"$set": bson.M{"retryAt": now.Add(q.cfg.VisibilityTimeout)},
+			"$inc": bson.M{"attempts": 1},
+		},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "retryAt", Value: 1}}),
+	).Decode(&doc)
+	if errors.Is(err, driver.ErrNoDocuments) {
+		return "", nil, utils.ErrObjectNotFound
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	restoreMessage(&doc.Notification)
+	return doc.ID.Hex(), &notify.RetriedNotification{
+		Notification: doc.Notification,
+		RetryAt:      doc.RetryAt,
+		RetryCount:   doc.RetryCount,
+	}, nil
+}
+
+func (q *Queue) MarkDone(ctx context.Context, id string) error {
+	objID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = q.queue.DeleteOne(ctx, bson.M{"_id": objID})
+	return err
+}
+
+// Requeue makes the notification visible again after the exponential backoff,
+// but not earlier than n.RetryAt. The backoff is computed from the stored attempts by the same update.
+func (q *Queue) Requeue(ctx context.Context, id string, n notify.RetriedNotification) error {
+	objID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	retryAt := bson.M{"$max": bson.A{
+		bson.M{"$add": bson.A{time.Now(), q.backoffExpr("$attempts")}},
+		n.RetryAt,
+	}}
+	res, err := q.queue.UpdateOne(ctx, bson.M{"_id": objID}, driver.Pipeline{{{Key: "$set", Value: bson.M{
+		// literal, so the message fields aren't evaluated as expressions
+		"notification": bson.M{"$literal": n.Notification},
+		"retryCount":   n.RetryCount,
+		"retryAt":      retryAt,
+	}}}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return utils.ErrObjectNotFound
+	}
+	return nil
+}
+
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.cfg.BackoffBase
+	for i := 1; i < attempts && delay < q.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, q.cfg.BackoffMax)
+}
+
+// backoffExpr is the aggregation expression of the backoff in milliseconds after the attempts,
+// the delays are taken from the table of the backoffs up to BackoffMax.
+func (q *Queue) backoffExpr(attempts string) bson.M {
+	var delays bson.A
+	for i := 1; ; i++ {
+		delay := q.backoff(i)
+		delays = append(delays, delay.Milliseconds())
+		if delay >= q.cfg.BackoffMax {
+			break
+		}
+	}
+	index := bson.M{"$min": bson.A{
+		bson.M{"$max": bson.A{bson.M{"$subtract": bson.A{attempts, 1}}, 0}},
+		len(delays) - 1,
+	}}
+	return bson.M{"$arrayElemAt": bson.A{delays, index}}
+}
+
+// withTransaction runs f in a transaction. The standalone servers don't support transactions, f runs without it there:
+// it inserts the notification before deleting it, so the notification isn't lost, but it may be duplicated.
+func (q *Queue) withTransaction(ctx context.Context, f func(ctx context.Context) error) error {
+	session, err := q.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		return nil, f(ctx)
+	})
+	var serverErr driver.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(illegalOperationCode) {
+		return f(ctx)
+	}
+	return err
+}
+
+// DeadLetter moves the notification to the dead letter collection in a transaction.
+func (q *Queue) DeadLetter(ctx context.Context, id string, n notify.RetriedNotification, reason error) error {
+	objID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	dl := DeadLetter{
+		ID:           objID,
+		Notification: n.Notification,
+		CreatedAt:    objID.Timestamp(),
+		FailedAt:     time.Now(),
+	}
+	if reason != nil {
+		dl.Error = reason.Error()
+	}
+	return q.withTransaction(ctx, func(ctx context.Context) error {
+		dl := dl
+		var doc queuedNotification
+		switch err := q.queue.FindOne(ctx, bson.M{"_id": objID}, options.FindOne().SetProjection(bson.M{"attempts": 1, "createdAt": 1})).Decode(&doc); {
+		case err == nil:
+			dl.Attempts, dl.CreatedAt = doc.Attempts, doc.CreatedAt
+		case !errors.Is(err, driver.ErrNoDocuments):
+			return err
+		}
+
+		// insert first, so the notification isn't lost if the deletion fails without a transaction,
+		// the dead letter of a repeated call is kept
+		if _, err := q.deadLetter.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$setOnInsert": dl}, options.UpdateOne().SetUpsert(true)); err != nil {
+			return err
+		}
+		_, err := q.queue.DeleteOne(ctx, bson.M{"_id": objID})
+		return err
+	})
+}
+
+// DeadLetters returns the dead letters, the most recently failed first. Zero limit means no limit.
+func (q *Queue) DeadLetters(ctx context.Context, offset, limit int64) (*DeadLetters, error) {
+	total, err := q.deadLetter.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "failedAt", Value: -1}}).SetSkip(offset)
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	cur, err := q.deadLetter.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	res := &DeadLetters{DeadLetters: []DeadLetter{}, TotalCount: total}
+	if err := cur.All(ctx, &res.DeadLetters); err != nil {
+		return nil, err
+	}
+	for i := range res.DeadLetters {
+		restoreMessage(&res.DeadLetters[i].Notification)
+	}
+	return res, nil
+}
+
+// Redrive moves the dead letter back to the queue with the given number of retries. It's retried immediately.
+func (q *Queue) Redrive(ctx context.Context, id string, retryCount int) error {
+	objID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %w", utils.ErrObjectNotFound, err)
+	}
+	var dl DeadLetter
+	err = q.deadLetter.FindOne(ctx, bson.M{"_id": objID}).Decode(&dl)
+	if errors.Is(err, driver.ErrNoDocuments) {
+		return utils.ErrObjectNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return q.redrive(ctx, dl, retryCount)
+}
+
+// RedriveAll moves all dead letters back to the queue. It returns the number of redriven notifications.
+func (q *Queue) RedriveAll(ctx context.Context, retryCount int) (int, error) {
+	cur, err := q.deadLetter.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var n int
+	for cur.Next(ctx) {
+		var dl DeadLetter
+		if err := cur.Decode(&dl); err != nil {
+			return n, err
+		}
+		if err := q.redrive(ctx, dl, retryCount); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, cur.Err()
+}
+
+func (q *Queue) redrive(ctx context.Context, dl DeadLetter, retryCount int) error {
+	return q.withTransaction(ctx, func(ctx context.Context) error {
+		// insert first, so the notification isn't lost if the deletion fails without a transaction
+		_, err := q.queue.ReplaceOne(ctx, bson.M{"_id": dl.ID}, queuedNotification{
+			ID:           dl.ID,
+			Notification: dl.Notification,
+			RetryAt:      time.Now(),
+			RetryCount:   max(retryCount, 1),
+			CreatedAt:    dl.CreatedAt,
+		}, options.Replace().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+		_, err = q.deadLetter.DeleteOne(ctx, bson.M{"_id": dl.ID})
+		return err
+	})
+}
+
+// DeleteDeadLetter removes the dead letter permanently.
+func (q *Queue) DeleteDeadLetter(ctx context.Context, id string) error {
+	objID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %w", utils.ErrObjectNotFound, err)
+	}
+	res, err := q.deadLetter.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return utils.ErrObjectNotFound
+	}
+	return nil
+}
+
+// restoreMessage converts the BSON dates of the message data back to time.Time, the templates rely on it.
+func restoreMessage(n *notify.Notification) {
+	for k, v := range n.Message.Data {
+		if dt, ok := v.(bson.DateTime); ok {
+			n.Message.Data[k] = dt.Time()
+		}
+	}
+}
@@ -0,0 +1,180 @@
+//go:build integration_test
+
+package mongo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/alert/notify"
+	"github.com/bldsoft/gost/alert/notify/channel/webhook"
+	"github.com/bldsoft/gost/config"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/mongo"
+	"github.com/bldsoft/gost/utils"
+	"github.com/bldsoft/gost/utils/poly"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	testDbOnce sync.Once
+	testDb     *mongo.Storage
+)
+
+func newTestQueue(t *testing.T, cfg QueueConfig) *Queue {
+	t.Helper()
+	testDbOnce.Do(func() {
+		log.SetLogLevel("")
+		var dbCfg mongo.Config
+		config.ReadConfig(&dbCfg, "")
+		testDb = mongo.NewStorage(dbCfg)
+		testDb.Connect()
+	})
+	cfg.QueueCollectionName = "test_" + DefaultQueueCollectionName
+	cfg.DeadLetterCollectionName = "test_" + DefaultDeadLetterCollectionName
+	q := NewQueue(testDb, cfg)
+	t.Cleanup(func() {
+		ctx := context.Background()
+		_, _ = q.queue.DeleteMany(ctx, bson.M{})
+		_, _ = q.deadLetter.DeleteMany(ctx, bson.M{})
+	})
+	return q
+}
+
+func testNotification(text string) notify.RetriedNotification {
+	return notify.RetriedNotification{
+		Notification: notify.Notification{
+			Receiver: poly.Poly[notify.Receiver]{Value: webhook.Receiver{URL: "http://localhost"}},
+			Message:  notify.Message{Data: map[string]any{"text": text}},
+		},
+		RetryAt:    time.Now().Add(-time.Second),
+		RetryCount: 2,
+	}
+}
+
+func storedRetryAt(t *testing.T, q *Queue, id string) time.Time {
+	t.Helper()
+	objID, err := bson.ObjectIDFromHex(id)
+	require.NoError(t, err)
+	var doc queuedNotification
+	require.NoError(t, q.queue.FindOne(context.Background(), bson.M{"_id": objID}).Decode(&doc))
+	return doc.RetryAt
+}
+
+func TestQueueDequeueVisibility(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, QueueConfig{VisibilityTimeout: 200 * time.Millisecond})
+	require.NoError(t, q.Enqueue(ctx, testNotification("a")))
+
+	id, n, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", n.Notification.Message.Data["text"])
+	assert.Equal(t, 2, n.RetryCount)
+
+	_, _, err = q.Dequeue(ctx)
+	assert.ErrorIs(t, err, utils.ErrObjectNotFound, "hidden for the visibility timeout")
+
+	time.Sleep(300 * time.Millisecond)
+	redeliveredID, _, err := q.Dequeue(ctx)
+	require.NoError(t, err, "visible again after the visibility timeout")
+	assert.Equal(t, id, redeliveredID)
+
+	require.NoError(t, q.MarkDone(ctx, id))
+	time.Sleep(300 * time.Millisecond)
+	_, _, err = q.Dequeue(ctx)
+	assert.ErrorIs(t, err, utils.ErrObjectNotFound)
+}
+
+func TestQueueRequeue(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, QueueConfig{BackoffBase: time.Hour, BackoffMax: 4 * time.Hour})
+	require.NoError(t, q.Enqueue(ctx, testNotification("a")))
+
+	id, n, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	n.RetryCount--
+	n.Notification.Message.Data["text"] = "$price"
+	n.RetryAt = time.Time{}
+	require.NoError(t, q.Requeue(ctx, id, *n))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), storedRetryAt(t, q, id), time.Minute, "backoff after the first attempt")
+
+	_, _, err = q.Dequeue(ctx)
+	assert.ErrorIs(t, err, utils.ErrObjectNotFound)
+
+	_, err = q.queue.UpdateOne(ctx, bson.M{}, bson.M{"$set": bson.M{"attempts": 3}})
+	require.NoError(t, err)
+	require.NoError(t, q.Requeue(ctx, id, *n))
+	assert.WithinDuration(t, time.Now().Add(4*time.Hour), storedRetryAt(t, q, id), time.Minute, "backoff is doubled up to the max")
+
+	n.RetryAt = time.Now().Add(10 * time.Hour)
+	require.NoError(t, q.Requeue(ctx, id, *n))
+	assert.WithinDuration(t, n.RetryAt, storedRetryAt(t, q, id), time.Second, "not earlier than RetryAt")
+
+	var doc queuedNotification
+	require.NoError(t, q.queue.FindOne(ctx, bson.M{}).Decode(&doc))
+	assert.Equal(t, 1, doc.RetryCount)
+	assert.Equal(t, "$price", doc.Notification.Message.Data["text"], "the message isn't evaluated")
+
+	err = q.Requeue(ctx, bson.NewObjectID().Hex(), *n)
+	assert.ErrorIs(t, err, utils.ErrObjectNotFound)
+}
+
+func TestQueueDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, QueueConfig{})
+	require.NoError(t, q.Enqueue(ctx, testNotification("a")))
+
+	id, n, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	require.NoError(t, q.DeadLetter(ctx, id, *n, errors.New("connection refused")))
+	require.NoError(t, q.DeadLetter(ctx, id, *n, errors.New("connection refused")), "idempotent")
+
+	count, err := q.queue.CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Zero(t, count, "removed from the queue")
+
+	dls, err := q.DeadLetters(ctx, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, dls.DeadLetters, 1)
+	assert.EqualValues(t, 1, dls.TotalCount)
+	dl := dls.DeadLetters[0]
+	assert.Equal(t, id, dl.ID.Hex())
+	assert.Equal(t, 1, dl.Attempts)
+	assert.Equal(t, "connection refused", dl.Error)
+	assert.Equal(t, "a", dl.Notification.Message.Data["text"])
+}
+
+func TestQueueRedrive(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, QueueConfig{})
+	var ids []string
+	for _, text := range []string{"a", "b", "c"} {
+		require.NoError(t, q.Enqueue(ctx, testNotification(text)))
+		id, n, err := q.Dequeue(ctx)
+		require.NoError(t, err)
+		require.NoError(t, q.DeadLetter(ctx, id, *n, nil))
+		ids = append(ids, id)
+	}
+
+	require.NoError(t, q.Redrive(ctx, ids[0], 3))
+	id, n, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ids[0], id)
+	assert.Equal(t, 3, n.RetryCount)
+	assert.ErrorIs(t, q.Redrive(ctx, ids[0], 3), utils.ErrObjectNotFound, "not a dead letter anymore")
+
+	redriven, err := q.RedriveAll(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, redriven)
+	dls, err := q.DeadLetters(ctx, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, dls.DeadLetters)
+	count, err := q.queue.CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+}
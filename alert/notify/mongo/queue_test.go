@@ -0,0 +1,31 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/alert/notify"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestBackoff(t *testing.T) {
+	q := &Queue{cfg: QueueConfig{BackoffBase: time.Minute, BackoffMax: 10 * time.Minute}}
+	for attempts, want := range []time.Duration{
+		time.Minute, time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute,
+	} {
+		assert.Equal(t, want, q.backoff(attempts), "attempts %d", attempts)
+	}
+	assert.Equal(t, 10*time.Minute, q.backoff(1000))
+}
+
+func TestRestoreMessage(t *testing.T) {
+	from := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	n := notify.Notification{Message: notify.Message{Data: map[string]any{
+		"from":     bson.NewDateTimeFromTime(from),
+		"severity": int32(2),
+	}}}
+	restoreMessage(&n)
+	assert.True(t, from.Equal(n.Message.Data["from"].(time.Time)))
+	assert.Equal(t, int32(2), n.Message.Data["severity"])
+}
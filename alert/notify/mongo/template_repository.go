@@ -0,0 +1,193 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bldsoft/gost/alert/notify"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/mongo"
+	"github.com/bldsoft/gost/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	driver "go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const DefaultTemplateCollectionName = "notify_templates"
+
+type templateRecord struct {
+	mongo.EntityID  `bson:",inline" json:"-"`
+	notify.Template `bson:",inline"`
+	// ReceiverKey makes the name and receiver pair unique.
+	ReceiverKey string `bson:"receiverKey"`
+}
+
+func (r *templateRecord) toTemplate() *notify.Template {
+	res := r.Template
+	res.ID = r.StringID()
+	return &res
+}
+
+type templateVersionRecord struct {
+	TemplateID             bson.ObjectID `bson:"templateID"`
+	notify.TemplateVersion `bson:",inline"`
+}
+
+// TemplateRepository is a notify.TemplateRepository stored in Mongo.
+// The versions are kept in a separate collection named after the templates one with the "_versions" suffix.
+type TemplateRepository struct {
+	rep      mongo.Repository[templateRecord, *templateRecord]
+	versions *driver.Collection
+}
+
+var _ notify.TemplateRepository = (*TemplateRepository)(nil)
+
+func NewTemplateRepository(db *mongo.Storage, collectionName ...string) *TemplateRepository {
+	name := DefaultTemplateCollectionName
+	if len(collectionName) > 0 {
+		name = collectionName[0]
+	}
+	r := &TemplateRepository{
+		rep:      mongo.NewRepository[templateRecord](db, name),
+		versions: db.Db.Collection(name + "_versions"),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := r.rep.Collection().Indexes().CreateOne(ctx, driver.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}, {Key: "receiverKey", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		log.ErrorWithFields(log.Fields{"err": err}, "Failed to create indexes for "+name)
+	}
+	if _, err := r.versions.Indexes().CreateOne(ctx, driver.IndexModel{
+		Keys:    bson.D{{Key: "templateID", Value: 1}, {Key: "version", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		log.ErrorWithFields(log.Fields{"err": err}, "Failed to create indexes for "+name+"_versions")
+	}
+	return r
+}
+
+func (r *TemplateRepository) saveVersion(ctx context.Context, id bson.ObjectID, t *notify.Template) error {
+	_, err := r.versions.InsertOne(ctx, templateVersionRecord{
+		TemplateID: id,
+		TemplateVersion: notify.TemplateVersion{
+			Version:   t.Version,
+			Text:      t.Text,
+			UpdatedAt: t.UpdatedAt,
+		},
+	})
+	return mongo.WrapErr(err)
+}
+
+func (r *TemplateRepository) CreateTemplate(ctx context.Context, t *notify.Template) error {
+	t.Version = 1
+	t.UpdatedAt = time.Now()
+	record := &templateRecord{Template: *t, ReceiverKey: t.ReceiverKey()}
+	if err := r.rep.Insert(ctx, record); err != nil {
+		return err
+	}
+	t.ID = record.StringID()
+	return r.saveVersion(ctx, record.EntityID.ID, t)
+}
+
+func (r *TemplateRepository) UpdateTemplate(ctx context.Context, t *notify.Template) error {
+	var record templateRecord
+	if err := record.SetIDFromString(t.ID); err != nil {
+		return repository.ErrNotFound
+	}
+	updatedAt := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"name":        t.Name,
+			"receiverKey": t.ReceiverKey(),
+			"text":        t.Text,
+			"updatedAt":   updatedAt,
+		},
+		"$inc": bson.M{"version": 1},
+	}
+	if t.Receiver != nil {
+		update["$set"].(bson.M)["receiver"] = t.Receiver
+	} else {
+		update["$unset"] = bson.M{"receiver": ""}
+	}
+	err := r.rep.UpdateOne(ctx, bson.M{"_id": record.EntityID.ID, "version": t.Version}, update)
+	if errors.Is(err, repository.ErrNotFound) {
+		if _, err := r.rep.FindByID(ctx, record.EntityID.ID); err != nil {
+			return err
+		}
+		return notify.ErrTemplateVersionConflict
+	}
+	if err != nil {
+		return err
+	}
+	t.Version++
+	t.UpdatedAt = updatedAt
+	return r.saveVersion(ctx, record.EntityID.ID, t)
+}
+
+// DeleteTemplate deletes the template with its versions.
+func (r *TemplateRepository) DeleteTemplate(ctx context.Context, id string) error {
+	var record templateRecord
+	if err := record.SetIDFromString(id); err != nil {
+		return repository.ErrNotFound
+	}
+	res, err := r.rep.Collection().DeleteOne(ctx, bson.M{"_id": record.EntityID.ID})
+	if err != nil {
+		return mongo.WrapErr(err)
+	}
+	if res.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	_, err = r.versions.DeleteMany(ctx, bson.M{"templateID": record.EntityID.ID})
+	return mongo.WrapErr(err)
+}
+
+func (r *TemplateRepository) GetTemplate(ctx context.Context, id string) (*notify.Template, error) {
+	var record templateRecord
+	if err := record.SetIDFromString(id); err != nil {
+		return nil, repository.ErrNotFound
+	}
+	res, err := r.rep.FindByID(ctx, record.EntityID.ID)
+	if err != nil {
+		return nil, err
+	}
+	return res.toTemplate(), nil
+}
+
+func (r *TemplateRepository) FindTemplates(ctx context.Context) ([]*notify.Template, error) {
+	records, err := r.rep.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*notify.Template, 0, len(records))
+	for _, record := range records {
+		res = append(res, record.toTemplate())
+	}
+	return res, nil
+}
+
+func (r *TemplateRepository) TemplateVersions(ctx context.Context, id string) ([]notify.TemplateVersion, error) {
+	var record templateRecord
+	if err := record.SetIDFromString(id); err != nil {
+		return nil, repository.ErrNotFound
+	}
+	cursor, err := r.versions.Find(ctx, bson.M{"templateID": record.EntityID.ID}, options.Find().SetSort(bson.D{{Key: "version", Value: -1}}))
+	if err != nil {
+		return nil, mongo.WrapErr(err)
+	}
+	var records []templateVersionRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, mongo.WrapErr(err)
+	}
+	if len(records) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	res := make([]notify.TemplateVersion, 0, len(records))
+	for _, v := range records {
+		res = append(res, v.TemplateVersion)
+	}
+	return res, nil
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"text/template"
+	"time"
+
+	cache "github.com/bldsoft/gost/cache/v2"
+	"github.com/bldsoft/gost/ratelimit"
+)
+
+const (
+	rateLimitKeyPrefix    = "notify:rate:"
+	defaultDigestInterval = time.Minute
+)
+
+// RateLimitConfig limits the notifications sent to a receiver with a token bucket:
+// a receiver gets up to Burst notifications at once and one more every Interval.
+// The notifications over the limit are folded into a digest sent every DigestInterval.
+type RateLimitConfig struct {
+	Burst    int
+	Interval time.Duration
+	// DigestInterval is Interval by default, or a minute if Interval isn't set either.
+	DigestInterval time.Duration
+	// DigestTemplate renders the digest text, DefaultDigestTemplate is used if it isn't set.
+	DigestTemplate *template.Template
+	// DigestMessage builds the digest message, e.g. to render it with the channel templates.
+	// By default, the message data holds the rendered DigestTemplate under DigestMsgKey.
+	DigestMessage func(data DigestData) (Message, error)
+	// MaxDigestSize is the max number of the messages kept in a digest, the rest are only counted. 100 by default.
+	MaxDigestSize int
+}
+
+func prepareRateLimitConfig(cfg RateLimitConfig) *RateLimitConfig {
+	if cfg.DigestInterval <= 0 {
+		cfg.DigestInterval = defaultDigestInterval
+		if cfg.Interval > 0 {
+			cfg.DigestInterval = cfg.Interval
+		}
+	}
+	return &cfg
+}
+
+// RateLimiter is a token bucket limiter per key.
+// The buckets are kept in memory or, to share the limits across replicas, in a distributed cache.
+type RateLimiter struct {
+	limiter *ratelimit.Limiter
+}
+
+func NewRateLimiter(burst int, interval time.Duration) *RateLimiter {
+	bucket := ratelimit.TokenBucket{Burst: burst, Interval: interval}
+	return &RateLimiter{limiter: ratelimit.NewLimiter(bucket).SetKeyPrefix(rateLimitKeyPrefix)}
+}
+
+// SetCache makes the limiter keep the buckets in the distributed cache.
+func (l *RateLimiter) SetCache(rep cache.IDistrCacheRepository) *RateLimiter {
+	l.limiter.SetCache(rep)
+	return l
+}
+
+// Allow reports whether the key has a token left and takes it.
+func (l *RateLimiter) Allow(key string) (bool, error) {
+	return l.allow(key, time.Now())
+}
+
+func (l *RateLimiter) allow(key string, now time.Time) (bool, error) {
+	res, err := l.limiter.AllowAt(context.Background(), key, now)
+	return res.Allowed, err
+}
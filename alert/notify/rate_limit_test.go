@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bldsoft/gost/alert/notify/channel/webhook"
+	"github.com/bldsoft/gost/cache/v2/redis"
+	"github.com/bldsoft/gost/utils/poly"
+	goredis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	rep := redis.NewRepository(redis.NewStorageFromClient(client, ""), 0)
+
+	limiters := map[string]func() *RateLimiter{
+		"local": func() *RateLimiter { return NewRateLimiter(2, time.Minute) },
+		"cache": func() *RateLimiter { return NewRateLimiter(2, time.Minute).SetCache(rep) },
+	}
+	for name, newLimiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			l, other := newLimiter(), newLimiter()
+			now := time.Now()
+			allow := func(l *RateLimiter, key string, at time.Time) bool {
+				allowed, err := l.allow(name+key, at)
+				require.NoError(t, err)
+				return allowed
+			}
+			assert.True(t, allow(l, "a", now))
+			assert.True(t, allow(l, "a", now))
+			assert.False(t, allow(l, "a", now))
+			assert.True(t, allow(l, "b", now), "buckets are per key")
+			assert.False(t, allow(l, "a", now.Add(30*time.Second)))
+			assert.True(t, allow(l, "a", now.Add(time.Minute)), "refilled")
+			assert.False(t, allow(l, "a", now.Add(time.Minute)))
+
+			if name == "cache" {
+				assert.False(t, allow(other, "a", now.Add(time.Minute)), "the bucket is shared")
+			} else {
+				assert.True(t, allow(other, "a", now.Add(time.Minute)))
+			}
+		})
+	}
+}
+
+func TestServiceRateLimit(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		bodies []map[string]any
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mtx.Lock()
+		bodies = append(bodies, body)
+		mtx.Unlock()
+	}))
+	defer srv.Close()
+
+	service := NewService(ServiceConfig{
+		RateLimit:  &RateLimitConfig{Burst: 1, Interval: time.Hour, MaxDigestSize: 2},
+		Dispatcher: DispatcherConfig{Webhook: &webhook.Config{}},
+	})
+	ctx := context.Background()
+	receiver := poly.Poly[Receiver]{Value: webhook.Receiver{URL: srv.URL}}
+	for i := range 4 {
+		require.NoError(t, service.Send(ctx, Notification{Receiver: receiver, Message: Message{Data: map[string]any{"n": i}}}))
+	}
+	require.Len(t, bodies, 1)
+
+	service.sendDigests(ctx)
+	require.Len(t, bodies, 2)
+	assert.Equal(t, float64(3), bodies[1][DigestCountMsgKey])
+	assert.Contains(t, bodies[1][DigestMsgKey], "3 notifications were rate limited")
+	assert.Contains(t, bodies[1][DigestMsgKey], "n: 1;")
+	assert.Contains(t, bodies[1][DigestMsgKey], "n: 2;")
+	assert.Contains(t, bodies[1][DigestMsgKey], "…and 1 more")
+
+	service.sendDigests(ctx)
+	assert.Len(t, bodies, 2, "digest is sent once")
+}
+
+func TestServiceDefaultDigestInterval(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	// no retries, Run only sends the digests
+	service := NewService(ServiceConfig{
+		RateLimit:  &RateLimitConfig{Burst: 1, Interval: 50 * time.Millisecond},
+		Dispatcher: DispatcherConfig{Webhook: &webhook.Config{}},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go service.Run(ctx)
+
+	receiver := poly.Poly[Receiver]{Value: webhook.Receiver{URL: srv.URL}}
+	for i := range 2 {
+		require.NoError(t, service.Send(ctx, Notification{Receiver: receiver, Message: Message{Data: map[string]any{"n": i}}}))
+	}
+	assert.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, 10*time.Millisecond, "the digest is flushed")
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/bldsoft/gost/alert/notify/channel"
+	cache "github.com/bldsoft/gost/cache/v2"
 	"github.com/bldsoft/gost/log"
 	"github.com/bldsoft/gost/utils"
 	"github.com/bldsoft/gost/utils/poly"
@@ -32,11 +33,20 @@ type Queue interface {
 	Requeue(ctx context.Context, id string, n RetriedNotification) error
 }
 
+// DeadLetterQueue is a Queue keeping the notifications whose retries are exhausted.
+// If the service queue implements it, DeadLetter is called instead of MarkDone after the last failed retry.
+type DeadLetterQueue interface {
+	Queue
+	DeadLetter(ctx context.Context, id string, n RetriedNotification, reason error) error
+}
+
 type ServiceConfig struct {
 	RetryCount             int
 	RetryQueuePollInterval time.Duration
 	WorkerN                int
 	SendTimeout            time.Duration
+	// RateLimit limits the notifications per receiver, optional.
+	RateLimit *RateLimitConfig
 
 	Dispatcher DispatcherConfig
 }
@@ -53,6 +63,9 @@ type Service struct {
 	dispatcher *Dispatcher
 	queue      Queue
 	wg         *wp.WorkerPool
+
+	limiter *RateLimiter
+	digests *digests
 }
 
 func NewService(cfg ServiceConfig) *Service {
@@ -65,6 +78,11 @@ func NewService(cfg ServiceConfig) *Service {
 	if cfg.RetryCount > 0 {
 		res.queue = NewMemoryQueue(1024)
 	}
+	if cfg.RateLimit != nil {
+		res.cfg.RateLimit = prepareRateLimitConfig(*cfg.RateLimit)
+		res.limiter = NewRateLimiter(cfg.RateLimit.Burst, cfg.RateLimit.Interval)
+		res.digests = newDigests(cfg.RateLimit.MaxDigestSize)
+	}
 	return res
 }
 
@@ -73,12 +91,48 @@ func (ns *Service) SetQueue(queue Queue) *Service {
 	return ns
 }
 
+// SetRateLimitCache shares the rate limits across the replicas, see ServiceConfig.RateLimit.
+func (ns *Service) SetRateLimitCache(rep cache.IDistrCacheRepository) *Service {
+	if ns.limiter != nil {
+		ns.limiter.SetCache(rep)
+	}
+	return ns
+}
+
 func (ns *Service) withSendTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
 	timeout := cmp.Or(ns.cfg.SendTimeout, DefaultNotificationServiceConfig.SendTimeout)
 	return context.WithTimeout(ctx, timeout)
 }
 
+// Send sends the notification. If the receiver is over the rate limit, the notification is added to its digest.
 func (ns *Service) Send(ctx context.Context, notification Notification) error {
+	if ns.rateLimited(ctx, notification) {
+		return nil
+	}
+	return ns.send(ctx, notification)
+}
+
+func (ns *Service) rateLimited(ctx context.Context, notification Notification) bool {
+	if ns.limiter == nil {
+		return false
+	}
+	key := receiverKey(notification.Receiver.Value)
+	allowed, err := ns.limiter.Allow(key)
+	if err != nil {
+		log.FromContext(ctx).ErrorWithFields(log.Fields{"receiver": key, "error": err}, "Failed to check notification rate limit")
+		return false
+	}
+	if !allowed {
+		ns.digests.add(key, notification, time.Now())
+	}
+	return !allowed
+}
+
+func receiverKey(receiver Receiver) string {
+	return fmt.Sprintf("%T:%v", receiver, receiver)
+}
+
+func (ns *Service) send(ctx context.Context, notification Notification) error {
 	ctx, cancel := ns.withSendTimeout(ctx)
 	defer cancel()
 
@@ -102,14 +156,28 @@ func (ns *Service) Send(ctx context.Context, notification Notification) error {
 }
 
 func (ns *Service) Run(ctx context.Context) error {
-	ticker := time.NewTicker(ns.cfg.RetryQueuePollInterval)
-	defer ticker.Stop()
+	// nil channels are never selected, so Run only sends the digests if there is no retry queue
+	var retryC <-chan time.Time
+	if ns.queue != nil {
+		ticker := time.NewTicker(ns.cfg.RetryQueuePollInterval)
+		defer ticker.Stop()
+		retryC = ticker.C
+	}
+
+	var digestC <-chan time.Time
+	if ns.digests != nil {
+		digestTicker := time.NewTicker(ns.cfg.RateLimit.DigestInterval)
+		defer digestTicker.Stop()
+		digestC = digestTicker.C
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case <-ticker.C:
+		case <-digestC:
+			ns.sendDigests(ctx)
+		case <-retryC:
 			for {
 				id, n, err := ns.queue.Dequeue(ctx)
 				if err != nil {
@@ -139,6 +207,27 @@ func (ns *Service) Run(ctx context.Context) error {
 	}
 }
 
+// sendDigests sends the digests of the rate limited notifications. They aren't rate limited themselves.
+func (ns *Service) sendDigests(ctx context.Context) {
+	digestMessage := ns.cfg.RateLimit.DigestMessage
+	if digestMessage == nil {
+		tmpl := cmp.Or(ns.cfg.RateLimit.DigestTemplate, DefaultDigestTemplate)
+		digestMessage = func(data DigestData) (Message, error) {
+			return DigestMessage(tmpl, data)
+		}
+	}
+	for _, dg := range ns.digests.flush() {
+		msg, err := digestMessage(dg.data)
+		if err != nil {
+			log.FromContext(ctx).ErrorWithFields(log.Fields{"error": err}, "Failed to render notification digest")
+			continue
+		}
+		if err := ns.send(ctx, Notification{Receiver: dg.receiver, Message: msg}); err != nil {
+			log.FromContext(ctx).ErrorWithFields(log.Fields{"error": err}, "Failed to send notification digest")
+		}
+	}
+}
+
 func (ns *Service) retrySend(ctx context.Context, id string, n RetriedNotification) error {
 	ctx, cancel := ns.withSendTimeout(ctx)
 	defer cancel()
@@ -153,6 +242,12 @@ func (ns *Service) retrySend(ctx context.Context, id string, n RetriedNotificati
 
 	n.RetryCount--
 	if n.RetryCount <= 0 {
+		if dlq, ok := ns.queue.(DeadLetterQueue); ok {
+			if err := dlq.DeadLetter(ctx, id, n, sendErr); err != nil {
+				return errors.Join(sendErr, fmt.Errorf("dead letter: %w", err))
+			}
+			return sendErr
+		}
 		if err := ns.queue.MarkDone(ctx, id); err != nil {
 			return errors.Join(sendErr, fmt.Errorf("mark done after failure: %w", err))
 		}
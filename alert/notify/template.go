@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/bldsoft/gost/alert/notify/channel"
+	"github.com/bldsoft/gost/alert/notify/channel/email"
+	"github.com/bldsoft/gost/alert/notify/channel/mattermost"
+	"github.com/bldsoft/gost/alert/notify/channel/slack"
+	"github.com/bldsoft/gost/alert/notify/channel/teams"
+	"github.com/bldsoft/gost/alert/notify/channel/telegram"
+	"github.com/bldsoft/gost/alert/notify/channel/webhook"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/utils/poly"
+)
+
+const defaultTemplatePollInterval = time.Minute
+
+var (
+	ErrInvalidTemplate         = errors.New("invalid notification template")
+	ErrTemplateVersionConflict = errors.New("notification template version conflict")
+)
+
+// templateReceivers maps the names of the templates that can be overridden to the receivers of their channels.
+var templateReceivers = map[string]Receiver{
+	email.SubjectTemplateName:      email.Receiver{},
+	email.MessageTemplateName:      email.Receiver{},
+	slack.MessageTemplateName:      slack.Receiver{},
+	webhook.BodyTemplateName:       webhook.Receiver{},
+	telegram.MessageTemplateName:   telegram.Receiver{},
+	teams.TitleTemplateName:        teams.Receiver{},
+	teams.MessageTemplateName:      teams.Receiver{},
+	mattermost.MessageTemplateName: mattermost.Receiver{},
+}
+
+// TemplateNames returns the names of the channel templates that can be overridden with a Template.
+func TemplateNames() []string {
+	res := make([]string, 0, len(templateReceivers))
+	for name := range templateReceivers {
+		res = append(res, name)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// Template overrides the named channel template for the Receiver or, if it isn't set, for all receivers of the channel.
+type Template struct {
+	ID       string               `json:"id" bson:"-"`
+	Name     string               `json:"name" bson:"name"`
+	Receiver *poly.Poly[Receiver] `json:"receiver,omitempty" bson:"receiver,omitempty"`
+	Text     string               `json:"text" bson:"text"`
+	// Version is incremented on every update, an update must be based on the current version.
+	Version   int       `json:"version" bson:"version"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// TemplateVersion is a saved version of a Template.
+type TemplateVersion struct {
+	Version   int       `json:"version" bson:"version"`
+	Text      string    `json:"text" bson:"text"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+func (t *Template) Validate() error {
+	example, ok := templateReceivers[t.Name]
+	if !ok {
+		return fmt.Errorf("%w: unknown template %q, expected one of %v", ErrInvalidTemplate, t.Name, TemplateNames())
+	}
+	if t.Receiver != nil && reflect.TypeOf(t.Receiver.Value) != reflect.TypeOf(example) {
+		return fmt.Errorf("%w: %s template doesn't apply to %s receiver", ErrInvalidTemplate, t.Name, t.Receiver.Type())
+	}
+	_, err := t.Parse()
+	return err
+}
+
+func (t *Template) Parse() (*template.Template, error) {
+	tmpl, err := template.New(t.Name).Parse(t.Text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// ReceiverKey identifies the receiver of the template, it's empty for the templates of all receivers.
+func (t *Template) ReceiverKey() string {
+	if t.Receiver == nil || t.Receiver.Value == nil {
+		return ""
+	}
+	return receiverKey(t.Receiver.Value)
+}
+
+// TemplateRepository keeps the templates with their versions.
+// There is at most one template per name and receiver, a duplicate is repository.ErrAlreadyExists.
+type TemplateRepository interface {
+	// CreateTemplate inserts the template as version 1 and sets its ID.
+	CreateTemplate(ctx context.Context, t *Template) error
+	// UpdateTemplate saves the template as the next version if t.Version is the current one,
+	// otherwise it fails with ErrTemplateVersionConflict.
+	UpdateTemplate(ctx context.Context, t *Template) error
+	DeleteTemplate(ctx context.Context, id string) error
+	GetTemplate(ctx context.Context, id string) (*Template, error)
+	FindTemplates(ctx context.Context) ([]*Template, error)
+	// TemplateVersions returns the saved versions of the template, the latest first.
+	TemplateVersions(ctx context.Context, id string) ([]TemplateVersion, error)
+}
+
+type templateKey struct {
+	name     string
+	receiver string
+}
+
+// TemplateStore is a channel.TemplateSource keeping the templates of the repository in memory.
+// A receiver template takes precedence over the template of all receivers,
+// the channels fall back to their own templates if there is neither.
+type TemplateStore struct {
+	rep          TemplateRepository
+	pollInterval time.Duration
+
+	mtx       sync.RWMutex
+	templates map[templateKey]*template.Template
+}
+
+var _ channel.TemplateSource = (*TemplateStore)(nil)
+
+func NewTemplateStore(rep TemplateRepository) *TemplateStore {
+	return &TemplateStore{
+		rep:          rep,
+		pollInterval: defaultTemplatePollInterval,
+		templates:    make(map[templateKey]*template.Template),
+	}
+}
+
+// SetPollInterval sets how often the templates are reloaded.
+func (s *TemplateStore) SetPollInterval(d time.Duration) *TemplateStore {
+	s.pollInterval = d
+	return s
+}
+
+func (s *TemplateStore) Template(name string, receiver Receiver) *template.Template {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	if tmpl, ok := s.templates[templateKey{name: name, receiver: receiverKey(receiver)}]; ok {
+		return tmpl
+	}
+	return s.templates[templateKey{name: name}]
+}
+
+// Load reloads the templates. The invalid ones are skipped, so the channels use their own templates instead.
+func (s *TemplateStore) Load(ctx context.Context) error {
+	templates, err := s.rep.FindTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	res := make(map[templateKey]*template.Template, len(templates))
+	for _, t := range templates {
+		if err := t.Validate(); err != nil {
+			log.FromContext(ctx).ErrorWithFields(log.Fields{"id": t.ID, "error": err}, "Skipped notification template")
+			continue
+		}
+		tmpl, _ := t.Parse()
+		res[templateKey{name: t.Name, receiver: t.ReceiverKey()}] = tmpl
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.templates = res
+	return nil
+}
+
+// Run reloads the templates every poll interval until ctx is done.
+func (s *TemplateStore) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.Load(ctx); err != nil {
+			log.FromContext(ctx).ErrorWithFields(log.Fields{"error": err}, "Failed to load notification templates")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
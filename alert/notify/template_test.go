@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bldsoft/gost/alert/notify/channel/email"
+	"github.com/bldsoft/gost/alert/notify/channel/mattermost"
+	"github.com/bldsoft/gost/alert/notify/channel/slack"
+	"github.com/bldsoft/gost/alert/notify/channel/teams"
+	"github.com/bldsoft/gost/alert/notify/channel/telegram"
+	"github.com/bldsoft/gost/alert/notify/channel/webhook"
+	"github.com/bldsoft/gost/utils/poly"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTemplateRepository struct {
+	TemplateRepository
+	templates []*Template
+}
+
+func (r *testTemplateRepository) FindTemplates(ctx context.Context) ([]*Template, error) {
+	return r.templates, nil
+}
+
+func receiverPoly(r Receiver) *poly.Poly[Receiver] {
+	return &poly.Poly[Receiver]{Value: r}
+}
+
+func TestTemplateValidate(t *testing.T) {
+	valid := Template{Name: slack.MessageTemplateName, Receiver: receiverPoly(slack.Receiver{URL: "u"}), Text: "{{ .id }}"}
+	assert.NoError(t, valid.Validate())
+
+	for name, tmpl := range map[string]Template{
+		"unknown name":     {Name: "sms", Text: "x"},
+		"receiver type":    {Name: slack.MessageTemplateName, Receiver: receiverPoly(email.Receiver{}), Text: "x"},
+		"invalid template": {Name: email.SubjectTemplateName, Text: "{{ .id "},
+	} {
+		assert.ErrorIs(t, tmpl.Validate(), ErrInvalidTemplate, name)
+	}
+}
+
+func TestTemplateStore(t *testing.T) {
+	ctx := context.Background()
+	rcv := slack.Receiver{URL: "special"}
+	store := NewTemplateStore(&testTemplateRepository{templates: []*Template{
+		{ID: "1", Name: slack.MessageTemplateName, Text: "all"},
+		{ID: "2", Name: slack.MessageTemplateName, Receiver: receiverPoly(rcv), Text: "special"},
+		{ID: "3", Name: email.SubjectTemplateName, Text: "{{ invalid"},
+	}})
+	require.NoError(t, store.Load(ctx))
+
+	render := func(name string, r Receiver) string {
+		tmpl := store.Template(name, r)
+		if tmpl == nil {
+			return ""
+		}
+		return tmpl.Root.String()
+	}
+	assert.Equal(t, "special", render(slack.MessageTemplateName, rcv))
+	assert.Equal(t, "all", render(slack.MessageTemplateName, slack.Receiver{URL: "other"}))
+	assert.Empty(t, render(email.SubjectTemplateName, email.Receiver{}), "invalid template is skipped")
+}
+
+func TestDispatcherTemplates(t *testing.T) {
+	bodies := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+	}))
+	defer srv.Close()
+
+	custom := webhook.Receiver{URL: srv.URL + "/custom"}
+	store := NewTemplateStore(&testTemplateRepository{templates: []*Template{
+		{Name: webhook.BodyTemplateName, Receiver: receiverPoly(custom), Text: `{"alert":"{{ .id }}"}`},
+	}})
+	require.NoError(t, store.Load(context.Background()))
+	d := NewDispatcher(DispatcherConfig{Webhook: &webhook.Config{}, Templates: store})
+
+	msg := Message{Data: map[string]any{"id": "db"}}
+	require.NoError(t, d.Send(context.Background(), Notification{Receiver: poly.Poly[Receiver]{Value: custom}, Message: msg}))
+	assert.Equal(t, `{"alert":"db"}`, <-bodies)
+	require.NoError(t, d.Send(context.Background(), Notification{Receiver: poly.Poly[Receiver]{Value: webhook.Receiver{URL: srv.URL}}, Message: msg}))
+	assert.JSONEq(t, `{"id":"db"}`, <-bodies, "default body")
+}
+
+func TestDispatcherChatTemplates(t *testing.T) {
+	bodies := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+	}))
+	defer srv.Close()
+
+	store := NewTemplateStore(&testTemplateRepository{templates: []*Template{
+		{Name: telegram.MessageTemplateName, Text: "telegram {{ .id }}"},
+		{Name: teams.TitleTemplateName, Text: "teams {{ .id }}"},
+		{Name: mattermost.MessageTemplateName, Text: "mattermost {{ .id }}"},
+	}})
+	require.NoError(t, store.Load(context.Background()))
+	d := NewDispatcher(DispatcherConfig{
+		Telegram:          &telegram.Config{APIURL: srv.URL},
+		TeamsWebhook:      &teams.WebhookConfig{},
+		MattermostWebhook: &mattermost.WebhookConfig{},
+		Templates:         store,
+	})
+
+	msg := Message{Data: map[string]any{"id": "db"}}
+	for _, tt := range []struct {
+		receiver Receiver
+		expected string
+	}{
+		{telegram.Receiver{ChatID: "1"}, "telegram db"},
+		{teams.Receiver{URL: srv.URL}, "teams db"},
+		{mattermost.Receiver{URL: srv.URL}, "mattermost db"},
+	} {
+		require.NoError(t, d.Send(context.Background(), Notification{Receiver: poly.Poly[Receiver]{Value: tt.receiver}, Message: msg}))
+		assert.Contains(t, <-bodies, tt.expected)
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"cmp"
 	"context"
 	"errors"
+	"fmt"
+	"iter"
 	"slices"
 	"text/template"
 
 	"github.com/bldsoft/gost/alert/notify"
 	"github.com/bldsoft/gost/alert/notify/channel"
+	cache "github.com/bldsoft/gost/cache/v2"
 	"github.com/bldsoft/gost/utils/poly"
 	"github.com/bldsoft/gost/utils/seq"
 
@@ -24,14 +27,40 @@ var emailMessageTemplate string
 //go:embed notify_templates/slack.tmpl
 var slackMessageTemplate string
 
+//go:embed notify_templates/telegram.tmpl
+var telegramMessageTemplate string
+
+//go:embed notify_templates/teams_title.tmpl
+var teamsTitleTemplate string
+
+//go:embed notify_templates/teams.tmpl
+var teamsMessageTemplate string
+
+//go:embed notify_templates/teams_color.tmpl
+var teamsColorTemplate string
+
+//go:embed notify_templates/mattermost.tmpl
+var mattermostMessageTemplate string
+
+//go:embed notify_templates/digest.tmpl
+var digestTemplate string
+
 var defaultEmailSubjectTemplate = template.Must(template.New("email_subject").Parse(emailSubjectTemplate))
 var defaultEmailMessageTemplate = template.Must(template.New("email_message").Parse(emailMessageTemplate))
 var defaultSlackMessageTemplate = template.Must(template.New("slack_message").Parse(slackMessageTemplate))
+var defaultTelegramMessageTemplate = template.Must(template.New("telegram_message").Parse(telegramMessageTemplate))
+var defaultTeamsTitleTemplate = template.Must(template.New("teams_title").Parse(teamsTitleTemplate))
+var defaultTeamsMessageTemplate = template.Must(template.New("teams_message").Parse(teamsMessageTemplate))
+var defaultTeamsColorTemplate = template.Must(template.New("teams_color").Parse(teamsColorTemplate))
+var defaultMattermostMessageTemplate = template.Must(template.New("mattermost_message").Parse(mattermostMessageTemplate))
+var defaultDigestTemplate = template.Must(template.New("digest").Parse(digestTemplate))
 
 const (
-	FromMsgKey     = "from"
-	ToMsgKey       = "to"
-	SeverityMsgKey = "severity"
+	FromMsgKey        = "from"
+	ToMsgKey          = "to"
+	SeverityMsgKey    = "severity"
+	IDMsgKey          = "id"
+	DescriptionMsgKey = "description"
 )
 
 type NotifyConfig = notify.ServiceConfig
@@ -43,9 +72,27 @@ type NotifyServiceAdapter struct {
 }
 
 func NewNotifyService(cfg NotifyConfig, receivers ...poly.Poly[notify.Receiver]) *NotifyServiceAdapter {
-	cfg.Dispatcher.Email.MessageTemplate = cmp.Or(cfg.Dispatcher.Email.MessageTemplate, defaultEmailMessageTemplate)
-	cfg.Dispatcher.Email.SubjectTemplate = cmp.Or(cfg.Dispatcher.Email.SubjectTemplate, defaultEmailSubjectTemplate)
-	cfg.Dispatcher.SlackWebhook.MessageTemplate = cmp.Or(cfg.Dispatcher.SlackWebhook.MessageTemplate, defaultSlackMessageTemplate)
+	if email := cfg.Dispatcher.Email; email != nil {
+		email.MessageTemplate = cmp.Or(email.MessageTemplate, defaultEmailMessageTemplate)
+		email.SubjectTemplate = cmp.Or(email.SubjectTemplate, defaultEmailSubjectTemplate)
+	}
+	if slack := cfg.Dispatcher.SlackWebhook; slack != nil {
+		slack.MessageTemplate = cmp.Or(slack.MessageTemplate, defaultSlackMessageTemplate)
+	}
+	if telegram := cfg.Dispatcher.Telegram; telegram != nil {
+		telegram.MessageTemplate = cmp.Or(telegram.MessageTemplate, defaultTelegramMessageTemplate)
+	}
+	if teams := cfg.Dispatcher.TeamsWebhook; teams != nil {
+		teams.TitleTemplate = cmp.Or(teams.TitleTemplate, defaultTeamsTitleTemplate)
+		teams.MessageTemplate = cmp.Or(teams.MessageTemplate, defaultTeamsMessageTemplate)
+		teams.ColorTemplate = cmp.Or(teams.ColorTemplate, defaultTeamsColorTemplate)
+	}
+	if mattermost := cfg.Dispatcher.MattermostWebhook; mattermost != nil {
+		mattermost.MessageTemplate = cmp.Or(mattermost.MessageTemplate, defaultMattermostMessageTemplate)
+	}
+	if rateLimit := cfg.RateLimit; rateLimit != nil && rateLimit.DigestMessage == nil {
+		rateLimit.DigestMessage = digestMessageFunc(cmp.Or(rateLimit.DigestTemplate, defaultDigestTemplate))
+	}
 	return &NotifyServiceAdapter{
 		cfg:           cfg,
 		notifyService: notify.NewService(cfg),
@@ -53,6 +100,12 @@ func NewNotifyService(cfg NotifyConfig, receivers ...poly.Poly[notify.Receiver])
 	}
 }
 
+// SetRateLimitCache shares the notification rate limits across the replicas, see NotifyConfig.RateLimit.
+func (s *NotifyServiceAdapter) SetRateLimitCache(rep cache.IDistrCacheRepository) *NotifyServiceAdapter {
+	_ = s.notifyService.SetRateLimitCache(rep)
+	return s
+}
+
 func (s *NotifyServiceAdapter) Run(ctx context.Context) error {
 	return s.notifyService.Run(ctx)
 }
@@ -63,11 +116,20 @@ func (s *NotifyServiceAdapter) SetQueue(queue notify.Queue) *NotifyServiceAdapte
 }
 
 func (s *NotifyServiceAdapter) Send(ctx context.Context, alert Alert) error {
-	var errs error
-	for _, receiver := range seq.Concat2(
+	return s.send(ctx, alert, seq.Concat2(
 		slices.All(s.receivers),
 		slices.All(alert.Receivers),
-	) {
+	))
+}
+
+// SendTo sends the alert to the given receivers only, e.g. to escalate it.
+func (s *NotifyServiceAdapter) SendTo(ctx context.Context, alert Alert, receivers ...poly.Poly[notify.Receiver]) error {
+	return s.send(ctx, alert, slices.All(receivers))
+}
+
+func (s *NotifyServiceAdapter) send(ctx context.Context, alert Alert, receivers iter.Seq2[int, poly.Poly[notify.Receiver]]) error {
+	var errs error
+	for _, receiver := range receivers {
 		notification := notify.Notification{
 			Receiver: receiver,
 			Message:  s.prepareMessage(alert),
@@ -79,6 +141,11 @@ func (s *NotifyServiceAdapter) Send(ctx context.Context, alert Alert) error {
 }
 
 func (h *NotifyServiceAdapter) prepareMessage(alert Alert) channel.Message {
+	return alertMessage(alert)
+}
+
+// alertMessage is the notification message data of the alert, the templates are rendered with it.
+func alertMessage(alert Alert) channel.Message {
 	msg := channel.Message{
 		Data: make(map[string]any),
 	}
@@ -92,3 +159,26 @@ func (h *NotifyServiceAdapter) prepareMessage(alert Alert) channel.Message {
 	msg.Data[SeverityMsgKey] = alert.Severity
 	return msg
 }
+
+// digestMessageFunc builds the digest of the rate limited alerts as an alert message,
+// so it's rendered with the alert templates. The digest has the highest severity of the folded alerts.
+func digestMessageFunc(tmpl *template.Template) func(data notify.DigestData) (channel.Message, error) {
+	return func(data notify.DigestData) (channel.Message, error) {
+		msg, err := notify.DigestMessage(tmpl, data)
+		if err != nil {
+			return msg, err
+		}
+		var severity SeverityLevel
+		for _, m := range data.Messages {
+			if s, ok := m.Data[SeverityMsgKey].(SeverityLevel); ok {
+				severity = max(severity, s)
+			}
+		}
+		msg.Data[IDMsgKey] = fmt.Sprintf("%d rate limited alerts", data.Count)
+		msg.Data[SeverityMsgKey] = severity
+		msg.Data[FromMsgKey] = data.Since
+		msg.Data[DescriptionMsgKey] = msg.Data[notify.DigestMsgKey]
+		delete(msg.Data, notify.DigestMsgKey)
+		return msg, nil
+	}
+}
@@ -0,0 +1,83 @@
+package alert
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/bldsoft/gost/alert/notify"
+	"github.com/bldsoft/gost/alert/notify/channel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTemplates(t *testing.T) {
+	s := &NotifyServiceAdapter{}
+	from := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	firing := Alert{
+		SourceID: "db",
+		Severity: SeverityLevelCritical,
+		From:     from,
+		MetaData: map[string]any{"id": "db", "description": "db is down", "host": "<h1>"},
+	}
+	resolved := firing
+	resolved.To = from.Add(time.Hour)
+
+	templates := []*template.Template{
+		defaultEmailSubjectTemplate,
+		defaultEmailMessageTemplate,
+		defaultSlackMessageTemplate,
+		defaultTelegramMessageTemplate,
+		defaultTeamsTitleTemplate,
+		defaultTeamsMessageTemplate,
+		defaultTeamsColorTemplate,
+		defaultMattermostMessageTemplate,
+	}
+	for _, tmpl := range templates {
+		for _, a := range []Alert{firing, resolved} {
+			var res strings.Builder
+			require.NoError(t, tmpl.Execute(&res, s.prepareMessage(a).Data), tmpl.Name())
+			assert.NotEmpty(t, res.String(), tmpl.Name())
+		}
+	}
+
+	render := func(tmpl *template.Template, a Alert) string {
+		var res strings.Builder
+		require.NoError(t, tmpl.Execute(&res, s.prepareMessage(a).Data))
+		return res.String()
+	}
+	assert.Contains(t, render(defaultTelegramMessageTemplate, firing), "<code>&lt;h1&gt;</code>")
+	assert.Contains(t, render(defaultTelegramMessageTemplate, firing), "<b>Severity:</b> critical")
+	assert.Equal(t, "🚨 Critical alert: db", render(defaultTeamsTitleTemplate, firing))
+	assert.Equal(t, "🚨 Critical alert: db (resolved)", render(defaultTeamsTitleTemplate, resolved))
+	assert.Equal(t, "attention", render(defaultTeamsColorTemplate, firing))
+	assert.Equal(t, "good", render(defaultTeamsColorTemplate, resolved))
+	assert.Contains(t, render(defaultMattermostMessageTemplate, resolved), "2024-01-02 03:04:05 — 2024-01-02 04:04:05")
+}
+
+func TestDigestMessage(t *testing.T) {
+	s := &NotifyServiceAdapter{}
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := notify.DigestData{
+		Count: 3,
+		Since: since,
+		Messages: []channel.Message{
+			s.prepareMessage(Alert{Severity: SeverityLevelHigh, From: since, MetaData: map[string]any{"id": "db"}}),
+			s.prepareMessage(Alert{Severity: SeverityLevelLow, From: since, To: since.Add(time.Minute)}),
+		},
+		Omitted: 1,
+	}
+	msg, err := digestMessageFunc(defaultDigestTemplate)(data)
+	require.NoError(t, err)
+	assert.Equal(t, SeverityLevelHigh, msg.Data[SeverityMsgKey])
+	assert.Equal(t, "3 rate limited alerts", msg.Data[IDMsgKey])
+	assert.Equal(t, "[high] db from 2024-01-02 03:04:05"+
+		"\n[low] alert from 2024-01-02 03:04:05 to 2024-01-02 03:05:05"+
+		"\n…and 1 more", msg.Data[DescriptionMsgKey])
+
+	for _, tmpl := range []*template.Template{defaultEmailMessageTemplate, defaultSlackMessageTemplate, defaultTelegramMessageTemplate, defaultMattermostMessageTemplate} {
+		var res strings.Builder
+		require.NoError(t, tmpl.Execute(&res, msg.Data), tmpl.Name())
+	}
+}
@@ -0,0 +1,153 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bldsoft/gost/discovery"
+	"github.com/bldsoft/gost/log"
+)
+
+const (
+	defaultShardRefreshInterval = 30 * time.Second
+	defaultShardRecheckInterval = 15 * time.Second
+)
+
+// Sharder assigns the processors to the healthy instances of the service with rendezvous (highest random weight)
+// hashing on the processor ID, so when an instance joins or leaves only its share of the processors moves.
+// The instances are reloaded on the NotifyingDiscovery events and every refresh interval.
+// The own instance is always a member, so without discovery data every instance evaluates every processor.
+type Sharder struct {
+	discovery       discovery.Discovery
+	serviceName     string
+	instanceID      string
+	refreshInterval time.Duration
+	recheckInterval time.Duration
+	changed         chan struct{}
+
+	mtx       sync.RWMutex
+	instances []string
+}
+
+func NewSharder(d discovery.Discovery, serviceName, instanceID string) *Sharder {
+	s := &Sharder{
+		discovery:       d,
+		serviceName:     serviceName,
+		instanceID:      instanceID,
+		refreshInterval: defaultShardRefreshInterval,
+		recheckInterval: defaultShardRecheckInterval,
+		changed:         make(chan struct{}, 1),
+		instances:       []string{instanceID},
+	}
+	if nd, ok := d.(discovery.NotifyingDiscovery); ok {
+		// the handlers can be called under the discovery lock, so the instances are reloaded by Run
+		nd.Subscribe(discovery.NewEventHandler(func(ctx context.Context, instance discovery.ServiceInstanceInfo) {
+			select {
+			case s.changed <- struct{}{}:
+			default:
+			}
+		}).ServiceName(serviceName).EventType(discovery.EventTypeUp, discovery.EventTypeDown, discovery.EventTypeRemoved))
+	}
+	return s
+}
+
+// SetRefreshInterval sets how often the instances are reloaded without discovery events.
+func (s *Sharder) SetRefreshInterval(d time.Duration) *Sharder {
+	s.refreshInterval = d
+	return s
+}
+
+// SetRecheckInterval sets how often the manager checks whether it owns the processor it doesn't own,
+// i.e. how soon a processor is taken over after rebalancing.
+func (s *Sharder) SetRecheckInterval(d time.Duration) *Sharder {
+	s.recheckInterval = d
+	return s
+}
+
+// Instances returns the IDs of the instances sharing the processors.
+func (s *Sharder) Instances() []string {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return slices.Clone(s.instances)
+}
+
+// Owner returns the ID of the instance evaluating the processor.
+func (s *Sharder) Owner(processorID string) string {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	var (
+		owner     string
+		maxWeight uint64
+	)
+	for _, instance := range s.instances {
+		if w := shardWeight(instance, processorID); owner == "" || w > maxWeight {
+			owner, maxWeight = instance, w
+		}
+	}
+	return owner
+}
+
+func (s *Sharder) Owns(processorID string) bool {
+	return s.Owner(processorID) == s.instanceID
+}
+
+func shardWeight(instanceID, processorID string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(instanceID))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(processorID))
+	// fnv doesn't mix the last bytes well enough for comparing the weights, so finalize it with splitmix64
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Refresh reloads the healthy instances of the service.
+func (s *Sharder) Refresh(ctx context.Context) error {
+	service, err := s.discovery.ServiceByName(ctx, s.serviceName)
+	if err != nil && !errors.Is(err, discovery.NotFound) {
+		return err
+	}
+	instances := []string{s.instanceID}
+	if service != nil {
+		for _, instance := range service.Instances {
+			if instance.Healthy && instance.ID != s.instanceID {
+				instances = append(instances, instance.ID)
+			}
+		}
+	}
+	slices.Sort(instances)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if !slices.Equal(s.instances, instances) {
+		log.FromContext(ctx).InfoWithFields(log.Fields{"instances": instances}, "Alert processors are rebalanced")
+	}
+	s.instances = instances
+	return nil
+}
+
+// Run keeps the instances up to date until ctx is done.
+func (s *Sharder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.refreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			log.FromContext(ctx).ErrorWithFields(log.Fields{"error": err}, "Failed to refresh alert shard instances")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-s.changed:
+		}
+	}
+}
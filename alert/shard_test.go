@@ -0,0 +1,201 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/discovery"
+	"github.com/bldsoft/gost/discovery/fake"
+	"github.com/bldsoft/gost/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testShardService = "alerts"
+
+func testShardDiscovery(instances ...discovery.ServiceInstanceInfo) *fake.Discovery {
+	d := fake.NewDiscovery(server.Config{ServiceName: "other"})
+	d.AddService(&discovery.ServiceInfo{Name: testShardService, Instances: instances})
+	return d
+}
+
+func healthyInstances(ids ...string) []discovery.ServiceInstanceInfo {
+	res := make([]discovery.ServiceInstanceInfo, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, discovery.ServiceInstanceInfo{ServiceName: testShardService, ID: id, Healthy: true})
+	}
+	return res
+}
+
+func TestSharderOwnership(t *testing.T) {
+	ctx := context.Background()
+	ids := []string{"i1", "i2", "i3"}
+	instances := healthyInstances(ids...)
+	instances = append(instances, discovery.ServiceInstanceInfo{ServiceName: testShardService, ID: "unhealthy"})
+	d := testShardDiscovery(instances...)
+
+	sharders := make([]*Sharder, 0, len(ids))
+	for _, id := range ids {
+		s := NewSharder(d, testShardService, id)
+		require.NoError(t, s.Refresh(ctx))
+		assert.Equal(t, ids, s.Instances())
+		sharders = append(sharders, s)
+	}
+
+	owners := make(map[string]string)
+	perInstance := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		id := fmt.Sprintf("processor-%d", i)
+		var n int
+		for _, s := range sharders {
+			if s.Owns(id) {
+				n++
+				owners[id] = s.instanceID
+			}
+		}
+		require.Equal(t, 1, n, "%s is owned by exactly one instance", id)
+		perInstance[owners[id]]++
+	}
+	for _, id := range ids {
+		assert.Greater(t, perInstance[id], 50, "processors are spread over the instances")
+	}
+
+	// i3 leaves, only its processors move
+	d.AddService(&discovery.ServiceInfo{Name: testShardService, Instances: healthyInstances("i1", "i2")})
+	s := sharders[0]
+	require.NoError(t, s.Refresh(ctx))
+	assert.Equal(t, []string{"i1", "i2"}, s.Instances())
+	for id, owner := range owners {
+		if owner != "i3" {
+			assert.Equal(t, owner, s.Owner(id))
+		} else {
+			assert.NotEqual(t, "i3", s.Owner(id))
+		}
+	}
+}
+
+func TestSharderWithoutDiscoveryData(t *testing.T) {
+	s := NewSharder(fake.NewDiscovery(server.Config{ServiceName: "other"}), testShardService, "i1")
+	require.NoError(t, s.Refresh(context.Background()))
+	assert.Equal(t, []string{"i1"}, s.Instances())
+	assert.True(t, s.Owns("processor"))
+}
+
+func TestSharderRebalancesOnEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := testShardDiscovery(healthyInstances("i1")...)
+	s := NewSharder(d, testShardService, "i1").SetRefreshInterval(time.Hour)
+	go func() { _ = s.Run(ctx) }()
+	require.Eventually(t, func() bool { return len(s.Instances()) == 1 }, time.Second, 10*time.Millisecond)
+
+	d.AddService(&discovery.ServiceInfo{Name: testShardService, Instances: healthyInstances("i1", "i2")})
+	assert.Eventually(t, func() bool { return len(s.Instances()) == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestManagerSkipsNotOwnedProcessors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := testShardDiscovery(healthyInstances("i1", "i2")...)
+	s := NewSharder(d, testShardService, "i1").SetRecheckInterval(time.Hour)
+	require.NoError(t, s.Refresh(ctx))
+
+	evaluated := make(chan string, 10)
+	m := NewManager(Config{WorkerN: 1}).SetSharder(s)
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprintf("processor-%d", i)
+		m.AddProcessor(Processor{ID: id, Source: SourceFunc(func(ctx context.Context) ([]Alert, time.Time, error) {
+			evaluated <- id
+			return nil, time.Now().Add(time.Hour), nil
+		}), Handler: HandlerFunc(func(ctx context.Context, alerts ...Alert) {})})
+	}
+	go m.Run(ctx)
+
+	var n int
+	deadline := time.After(200 * time.Millisecond)
+	for {
+		select {
+		case id := <-evaluated:
+			n++
+			assert.True(t, s.Owns(id), "%s isn't owned by the instance", id)
+		case <-deadline:
+			assert.Positive(t, n)
+			return
+		}
+	}
+}
+
+func TestManagerEvaluatesLocalSources(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := testShardDiscovery(healthyInstances("i1", "i2")...)
+	s := NewSharder(d, testShardService, "i1").SetRecheckInterval(time.Hour)
+	require.NoError(t, s.Refresh(ctx))
+	id := "breakers"
+	for i := 0; s.Owns(id); i++ {
+		id = fmt.Sprintf("breakers-%d", i)
+	}
+
+	evaluated := make(chan struct{}, 1)
+	m := NewManager(Config{WorkerN: 1}).SetSharder(s)
+	m.AddProcessor(Processor{ID: id, Source: localTestSource(func(ctx context.Context) ([]Alert, time.Time, error) {
+		evaluated <- struct{}{}
+		return nil, time.Now().Add(time.Hour), nil
+	}), Handler: HandlerFunc(func(ctx context.Context, alerts ...Alert) {})})
+	go m.Run(ctx)
+
+	select {
+	case <-evaluated:
+	case <-time.After(time.Second):
+		t.Fatal("the local source isn't evaluated by a not owner instance")
+	}
+}
+
+type localTestSource SourceFunc
+
+func (f localTestSource) EvaluateAlerts(ctx context.Context) ([]Alert, time.Time, error) {
+	return f(ctx)
+}
+
+func (f localTestSource) Local() bool { return true }
+
+func TestManagerResolvesOnOwnershipLoss(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewSharder(testShardDiscovery(), testShardService, "i1").SetRecheckInterval(time.Hour)
+	require.NoError(t, s.Refresh(ctx))
+	id := "processor"
+	for i := 0; shardWeight("i1", id) > shardWeight("i2", id); i++ {
+		id = fmt.Sprintf("processor-%d", i)
+	}
+
+	handled := make(chan Alert, 10)
+	m := NewManager(Config{WorkerN: 1}).SetSharder(s)
+	m.AddProcessor(Processor{ID: id, Source: SourceFunc(func(ctx context.Context) ([]Alert, time.Time, error) {
+		return []Alert{{SourceID: "src", From: time.Now()}}, time.Now().Add(10 * time.Millisecond), nil
+	}), Handler: HandlerFunc(func(ctx context.Context, alerts ...Alert) {
+		for _, a := range alerts {
+			handled <- a
+		}
+	})})
+	go m.Run(ctx)
+	require.True(t, (<-handled).To.IsZero(), "fired by the owner")
+
+	s.discovery = testShardDiscovery(healthyInstances("i1", "i2")...)
+	require.NoError(t, s.Refresh(ctx))
+	require.False(t, s.Owns(id))
+	for {
+		select {
+		case a := <-handled:
+			if a.To.IsZero() {
+				continue // evaluated before the rebalancing
+			}
+			assert.Equal(t, "src", a.SourceID, "the firing alert is resolved by the previous owner")
+			return
+		case <-time.After(time.Second):
+			t.Fatal("the firing alert isn't resolved")
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bldsoft/gost/alert/notify"
+	"github.com/bldsoft/gost/alert/notify/channel/email"
+	"github.com/bldsoft/gost/alert/notify/channel/mattermost"
+	"github.com/bldsoft/gost/alert/notify/channel/slack"
+	"github.com/bldsoft/gost/alert/notify/channel/teams"
+	"github.com/bldsoft/gost/alert/notify/channel/telegram"
+	"github.com/bldsoft/gost/alert/notify/channel/webhook"
+	"github.com/bldsoft/gost/controller"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/repository"
+	"github.com/bldsoft/gost/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultTemplates are the embedded templates the channels fall back to, the webhook posts the message data as JSON.
+var defaultTemplates = map[string]*template.Template{
+	email.SubjectTemplateName:      defaultEmailSubjectTemplate,
+	email.MessageTemplateName:      defaultEmailMessageTemplate,
+	slack.MessageTemplateName:      defaultSlackMessageTemplate,
+	telegram.MessageTemplateName:   defaultTelegramMessageTemplate,
+	teams.TitleTemplateName:        defaultTeamsTitleTemplate,
+	teams.MessageTemplateName:      defaultTeamsMessageTemplate,
+	mattermost.MessageTemplateName: defaultMattermostMessageTemplate,
+}
+
+// SampleAlert is the alert the templates are validated and previewed with.
+func SampleAlert() Alert {
+	from := time.Now().Add(-5 * time.Minute).Truncate(time.Second)
+	return Alert{
+		SourceID: "sample",
+		Severity: SeverityLevelHigh,
+		From:     from,
+		MetaData: map[string]any{
+			IDMsgKey:          "sample",
+			DescriptionMsgKey: "Sample alert description",
+			"host":            "example.com",
+		},
+	}
+}
+
+// RenderTemplate renders the template with the notification message of the alert.
+func RenderTemplate(tmpl *template.Template, alert Alert) (string, error) {
+	var res strings.Builder
+	if err := tmpl.Execute(&res, alertMessage(alert).Data); err != nil {
+		return "", err
+	}
+	return res.String(), nil
+}
+
+// TemplateController manages the notification templates overriding the embedded ones.
+type TemplateController struct {
+	controller.BaseController
+	rep   notify.TemplateRepository
+	store *notify.TemplateStore
+}
+
+func NewTemplateController(rep notify.TemplateRepository) *TemplateController {
+	return &TemplateController{rep: rep}
+}
+
+// SetStore makes the controller reload the store on changes, so they apply without waiting for its poll.
+func (c *TemplateController) SetStore(store *notify.TemplateStore) *TemplateController {
+	c.store = store
+	return c
+}
+
+type PreviewTemplateRequest struct {
+	Name string `json:"name"`
+	// Text is the template to render, the embedded default of the name is rendered if it's empty.
+	Text string `json:"text,omitempty"`
+	// Alert is the alert to render, SampleAlert is used if it isn't set.
+	Alert *Alert `json:"alert,omitempty"`
+}
+
+type PreviewTemplateResult struct {
+	Text string `json:"text"`
+}
+
+func (c *TemplateController) responseError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, notify.ErrInvalidTemplate):
+		c.ResponseError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, notify.ErrTemplateVersionConflict), errors.Is(err, repository.ErrAlreadyExists):
+		c.ResponseError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, utils.ErrObjectNotFound):
+		c.ResponseError(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	default:
+		log.FromContext(r.Context()).Error(err.Error())
+		c.ResponseError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// validate checks the template can be parsed and rendered with the sample alert.
+func (c *TemplateController) validate(t *notify.Template) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	tmpl, _ := t.Parse()
+	if _, err := RenderTemplate(tmpl, SampleAlert()); err != nil {
+		return fmt.Errorf("%w: %w", notify.ErrInvalidTemplate, err)
+	}
+	return nil
+}
+
+func (c *TemplateController) reloadStore(r *http.Request) {
+	if c.store == nil {
+		return
+	}
+	if err := c.store.Load(r.Context()); err != nil {
+		log.FromContext(r.Context()).ErrorWithFields(log.Fields{"error": err}, "Failed to load notification templates")
+	}
+}
+
+// GetTemplatesHandler lists the templates.
+// @Summary list notification templates
+// @Tags admin
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} notify.Template "OK"
+// @Router /alerts/templates [get]
+func (c *TemplateController) GetTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	templates, err := c.rep.FindTemplates(r.Context())
+	if err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, templates)
+}
+
+// GetTemplateHandler gets a single template.
+// @Summary get notification template
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param id path string true "Template ID"
+// @Produce json
+// @Success 200 {object} notify.Template "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /alerts/templates/{id} [get]
+func (c *TemplateController) GetTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	t, err := c.rep.GetTemplate(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, t)
+}
+
+// GetTemplateVersionsHandler lists the saved versions of a template, the latest first.
+// @Summary list notification template versions
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param id path string true "Template ID"
+// @Produce json
+// @Success 200 {array} notify.TemplateVersion "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /alerts/templates/{id}/versions [get]
+func (c *TemplateController) GetTemplateVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	versions, err := c.rep.TemplateVersions(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, versions)
+}
+
+// CreateTemplateHandler creates a template.
+// @Summary create notification template
+// @Tags admin
+// @Security ApiKeyAuth
+// @Accept json
+// @Param template body notify.Template true "Template"
+// @Produce json
+// @Success 200 {object} notify.Template "OK"
+// @Failure 400 {string} string "Bad request"
+// @Failure 409 {string} string "Template of the name and receiver already exists"
+// @Router /alerts/templates [post]
+func (c *TemplateController) CreateTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var t notify.Template
+	if !c.GetObjectFromBody(w, r, &t) {
+		return
+	}
+	t.ID = ""
+	if err := c.validate(&t); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	if err := c.rep.CreateTemplate(r.Context(), &t); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.reloadStore(r)
+	c.ResponseJson(w, r, t)
+}
+
+// UpdateTemplateHandler saves the next version of a template. The version in the body must be the current one.
+// @Summary update notification template
+// @Tags admin
+// @Security ApiKeyAuth
+// @Accept json
+// @Param id path string true "Template ID"
+// @Param template body notify.Template true "Template"
+// @Produce json
+// @Success 200 {object} notify.Template "OK"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Not found"
+// @Failure 409 {string} string "Version conflict"
+// @Router /alerts/templates/{id} [put]
+func (c *TemplateController) UpdateTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var t notify.Template
+	if !c.GetObjectFromBody(w, r, &t) {
+		return
+	}
+	t.ID = chi.URLParam(r, "id")
+	if err := c.validate(&t); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	if err := c.rep.UpdateTemplate(r.Context(), &t); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.reloadStore(r)
+	c.ResponseJson(w, r, t)
+}
+
+// DeleteTemplateHandler deletes a template, the channels fall back to the embedded one.
+// @Summary delete notification template
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param id path string true "Template ID"
+// @Success 200 {string} string "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /alerts/templates/{id} [delete]
+func (c *TemplateController) DeleteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	if err := c.rep.DeleteTemplate(r.Context(), chi.URLParam(r, "id")); err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.reloadStore(r)
+	c.ResponseOK(w)
+}
+
+// PreviewTemplateHandler renders a template with an alert.
+// @Summary preview notification template
+// @Tags admin
+// @Security ApiKeyAuth
+// @Accept json
+// @Param request body PreviewTemplateRequest true "Template and alert"
+// @Produce json
+// @Success 200 {object} PreviewTemplateResult "OK"
+// @Failure 400 {string} string "Bad request"
+// @Router /alerts/templates/preview [post]
+func (c *TemplateController) PreviewTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var req PreviewTemplateRequest
+	if !c.GetObjectFromBody(w, r, &req) {
+		return
+	}
+	text, err := previewTemplate(req)
+	if err != nil {
+		c.responseError(w, r, err)
+		return
+	}
+	c.ResponseJson(w, r, PreviewTemplateResult{Text: text})
+}
+
+func previewTemplate(req PreviewTemplateRequest) (string, error) {
+	alert := SampleAlert()
+	if req.Alert != nil {
+		alert = *req.Alert
+	}
+	if req.Text == "" && req.Name == webhook.BodyTemplateName {
+		body, _ := webhook.DefaultJSONBodyFormat(alertMessage(alert))
+		return string(body), nil
+	}
+
+	var tmpl *template.Template
+	if req.Text == "" {
+		var ok bool
+		if tmpl, ok = defaultTemplates[req.Name]; !ok {
+			return "", fmt.Errorf("%w: unknown template %q, expected one of %v", notify.ErrInvalidTemplate, req.Name, notify.TemplateNames())
+		}
+	} else {
+		t := notify.Template{Name: req.Name, Text: req.Text}
+		if err := t.Validate(); err != nil {
+			return "", err
+		}
+		tmpl, _ = t.Parse()
+	}
+	res, err := RenderTemplate(tmpl, alert)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", notify.ErrInvalidTemplate, err)
+	}
+	return res, nil
+}
+
+// Mount it with r.Route("/alerts/templates", c.Mount).
+func (c *TemplateController) Mount(r chi.Router) {
+	r.Get("/", c.GetTemplatesHandler)
+	r.Post("/", c.CreateTemplateHandler)
+	r.Post("/preview", c.PreviewTemplateHandler)
+	r.Get("/{id}", c.GetTemplateHandler)
+	r.Put("/{id}", c.UpdateTemplateHandler)
+	r.Delete("/{id}", c.DeleteTemplateHandler)
+	r.Get("/{id}/versions", c.GetTemplateVersionsHandler)
+}
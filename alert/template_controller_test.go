@@ -0,0 +1,56 @@
+package alert
+
+import (
+	"testing"
+
+	"github.com/bldsoft/gost/alert/notify"
+	"github.com/bldsoft/gost/alert/notify/channel/email"
+	"github.com/bldsoft/gost/alert/notify/channel/mattermost"
+	"github.com/bldsoft/gost/alert/notify/channel/slack"
+	"github.com/bldsoft/gost/alert/notify/channel/teams"
+	"github.com/bldsoft/gost/alert/notify/channel/telegram"
+	"github.com/bldsoft/gost/alert/notify/channel/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewTemplate(t *testing.T) {
+	res, err := previewTemplate(PreviewTemplateRequest{Name: email.SubjectTemplateName})
+	require.NoError(t, err)
+	assert.Equal(t, "⚠️ High alert: sample", res, "embedded default")
+
+	res, err = previewTemplate(PreviewTemplateRequest{Name: slack.MessageTemplateName})
+	require.NoError(t, err)
+	assert.Contains(t, res, "• host: `example.com`")
+
+	res, err = previewTemplate(PreviewTemplateRequest{Name: webhook.BodyTemplateName})
+	require.NoError(t, err)
+	assert.Contains(t, res, `"host":"example.com"`)
+
+	for _, name := range []string{telegram.MessageTemplateName, teams.TitleTemplateName, teams.MessageTemplateName, mattermost.MessageTemplateName} {
+		res, err = previewTemplate(PreviewTemplateRequest{Name: name})
+		require.NoError(t, err, name)
+		assert.NotEmpty(t, res, name)
+	}
+
+	custom := Alert{Severity: SeverityLevelCritical, MetaData: map[string]any{"id": "db"}}
+	res, err = previewTemplate(PreviewTemplateRequest{
+		Name:  slack.MessageTemplateName,
+		Text:  "{{ .id }} is {{ .severity }}",
+		Alert: &custom,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "db is critical", res)
+
+	_, err = previewTemplate(PreviewTemplateRequest{Name: slack.MessageTemplateName, Text: "{{ .id "})
+	assert.ErrorIs(t, err, notify.ErrInvalidTemplate)
+	_, err = previewTemplate(PreviewTemplateRequest{Name: "sms"})
+	assert.ErrorIs(t, err, notify.ErrInvalidTemplate)
+}
+
+func TestTemplateControllerValidate(t *testing.T) {
+	c := NewTemplateController(nil)
+	assert.NoError(t, c.validate(&notify.Template{Name: slack.MessageTemplateName, Text: "{{ .id }}"}))
+	err := c.validate(&notify.Template{Name: slack.MessageTemplateName, Text: "{{ .from.Missing }}"})
+	assert.ErrorIs(t, err, notify.ErrInvalidTemplate, "fails to render the sample alert")
+}
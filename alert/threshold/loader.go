@@ -0,0 +1,131 @@
+package threshold
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/bldsoft/gost/alert"
+	"github.com/bldsoft/gost/log"
+)
+
+const (
+	// ProcessorIDPrefix prefixes the rule ID in the alert.Manager processor ID.
+	ProcessorIDPrefix = "threshold:"
+
+	defaultLoaderPollInterval = time.Minute
+)
+
+func ProcessorID(ruleID string) string {
+	return ProcessorIDPrefix + ruleID
+}
+
+// Loader keeps the alert.Manager processors in sync with the rules in the repository.
+// New rules are added, changed rules are replaced in their sources and
+// deleted, disabled or invalid rules are removed with their firing alerts resolved.
+type Loader struct {
+	rep          RuleRepository
+	querier      Querier
+	manager      *alert.Manager
+	handler      alert.Handler
+	escalation   *alert.EscalationPolicy
+	pollInterval time.Duration
+
+	mtx     sync.Mutex
+	sources map[string]*Source
+}
+
+func NewLoader(rep RuleRepository, querier Querier, manager *alert.Manager, handler alert.Handler) *Loader {
+	return &Loader{
+		rep:          rep,
+		querier:      querier,
+		manager:      manager,
+		handler:      handler,
+		pollInterval: defaultLoaderPollInterval,
+		sources:      make(map[string]*Source),
+	}
+}
+
+// SetPollInterval sets how often the rules are reloaded.
+func (l *Loader) SetPollInterval(d time.Duration) *Loader {
+	l.pollInterval = d
+	return l
+}
+
+// SetEscalation sets the escalation policy of the rule processors, see alert.Escalator.Middleware.
+func (l *Loader) SetEscalation(policy *alert.EscalationPolicy) *Loader {
+	l.escalation = policy
+	return l
+}
+
+// Load syncs the processors with the rules once.
+func (l *Loader) Load(ctx context.Context) error {
+	rules, err := l.rep.FindRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	loaded := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if rule.Disabled {
+			continue
+		}
+		if err := rule.Validate(); err != nil {
+			log.FromContext(ctx).WarnWithFields(log.Fields{"rule": rule.ID, "err": err}, "Skipping threshold rule")
+			continue
+		}
+		loaded[rule.ID] = struct{}{}
+
+		if source, ok := l.sources[rule.ID]; ok {
+			if !reflect.DeepEqual(source.Rule(), rule) {
+				source.SetRule(rule)
+			}
+			continue
+		}
+		source := NewSource(l.querier, rule)
+		l.sources[rule.ID] = source
+		l.manager.AddProcessor(l.processor(rule.ID, source))
+	}
+
+	for id, source := range l.sources {
+		if _, ok := loaded[id]; ok {
+			continue
+		}
+		l.manager.RemoveProcessor(ProcessorID(id))
+		delete(l.sources, id)
+		if resolved := source.Resolve(); len(resolved) > 0 {
+			p := l.processor(id, source)
+			l.handler.Handle(alert.WithProcessor(ctx, &p), resolved...)
+		}
+	}
+	return nil
+}
+
+// Run reloads the rules until ctx is done.
+func (l *Loader) Run(ctx context.Context) error {
+	ticker := time.NewTicker(l.pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := l.Load(ctx); err != nil {
+			log.FromContext(ctx).ErrorWithFields(log.Fields{"error": err}, "Failed to load threshold rules")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *Loader) processor(ruleID string, source *Source) alert.Processor {
+	return alert.Processor{
+		ID:         ProcessorID(ruleID),
+		Source:     source,
+		Handler:    l.handler,
+		Escalation: l.escalation,
+	}
+}
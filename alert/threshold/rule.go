@@ -0,0 +1,118 @@
+package threshold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bldsoft/gost/alert"
+	"github.com/bldsoft/gost/alert/notify"
+	"github.com/bldsoft/gost/utils/poly"
+)
+
+// DefaultValueColumn is the result column compared with the thresholds if Rule.ValueColumn is empty.
+const DefaultValueColumn = "value"
+
+var ErrInvalidRule = errors.New("invalid threshold rule")
+
+type Operator string
+
+const (
+	OperatorGreater        Operator = ">"
+	OperatorGreaterOrEqual Operator = ">="
+	OperatorLess           Operator = "<"
+	OperatorLessOrEqual    Operator = "<="
+	OperatorEqual          Operator = "=="
+	OperatorNotEqual       Operator = "!="
+)
+
+func (o Operator) compare(value, threshold float64) bool {
+	switch o {
+	case OperatorGreater:
+		return value > threshold
+	case OperatorGreaterOrEqual:
+		return value >= threshold
+	case OperatorLess:
+		return value < threshold
+	case OperatorLessOrEqual:
+		return value <= threshold
+	case OperatorEqual:
+		return value == threshold
+	case OperatorNotEqual:
+		return value != threshold
+	default:
+		return false
+	}
+}
+
+type Threshold struct {
+	Severity alert.SeverityLevel `json:"severity" bson:"severity"`
+	Value    float64             `json:"value" bson:"value"`
+}
+
+// Rule raises an alert for every result row whose ValueColumn breaches a threshold for at least For.
+// The rows are told apart by the LabelColumns, their values are added to the alert metadata.
+// If several thresholds are breached, the one with the highest severity is used.
+type Rule struct {
+	ID           string                       `json:"id" bson:"-"`
+	Name         string                       `json:"name" bson:"name"`
+	Query        string                       `json:"query" bson:"query"`
+	ValueColumn  string                       `json:"valueColumn,omitempty" bson:"valueColumn,omitempty"`
+	LabelColumns []string                     `json:"labelColumns,omitempty" bson:"labelColumns,omitempty"`
+	Operator     Operator                     `json:"operator" bson:"operator"`
+	Thresholds   []Threshold                  `json:"thresholds" bson:"thresholds"`
+	Interval     time.Duration                `json:"interval" bson:"interval"`
+	For          time.Duration                `json:"for,omitempty" bson:"for,omitempty"`
+	Receivers    []poly.Poly[notify.Receiver] `json:"receivers,omitempty" bson:"receivers,omitempty"`
+	Disabled     bool                         `json:"disabled,omitempty" bson:"disabled,omitempty"`
+}
+
+func (r *Rule) Validate() error {
+	switch {
+	case r.Query == "":
+		return fmt.Errorf("%w: empty query", ErrInvalidRule)
+	case len(r.Thresholds) == 0:
+		return fmt.Errorf("%w: no thresholds", ErrInvalidRule)
+	case r.Interval <= 0:
+		return fmt.Errorf("%w: interval must be positive", ErrInvalidRule)
+	case r.For < 0:
+		return fmt.Errorf("%w: negative for duration", ErrInvalidRule)
+	}
+	switch r.Operator {
+	case OperatorGreater, OperatorGreaterOrEqual, OperatorLess, OperatorLessOrEqual, OperatorEqual, OperatorNotEqual:
+	default:
+		return fmt.Errorf("%w: unknown operator %q", ErrInvalidRule, r.Operator)
+	}
+	return nil
+}
+
+func (r *Rule) valueColumn() string {
+	if r.ValueColumn == "" {
+		return DefaultValueColumn
+	}
+	return r.ValueColumn
+}
+
+// severity returns the highest severity whose threshold is breached by the value.
+func (r *Rule) severity(value float64) (alert.SeverityLevel, bool) {
+	var (
+		res      alert.SeverityLevel
+		breached bool
+	)
+	for _, t := range r.Thresholds {
+		if r.Operator.compare(value, t.Value) && (!breached || t.Severity > res) {
+			res, breached = t.Severity, true
+		}
+	}
+	return res, breached
+}
+
+// RuleRepository stores the threshold rules. Get, Update and Delete return utils.ErrObjectNotFound for unknown IDs.
+type RuleRepository interface {
+	CreateRule(ctx context.Context, rule *Rule) error
+	UpdateRule(ctx context.Context, rule *Rule) error
+	DeleteRule(ctx context.Context, id string) error
+	GetRule(ctx context.Context, id string) (*Rule, error)
+	FindRules(ctx context.Context) ([]*Rule, error)
+}
@@ -0,0 +1,199 @@
+package threshold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bldsoft/gost/alert"
+)
+
+const (
+	// RuleMetaKey is the alert metadata key holding the rule name.
+	RuleMetaKey = "rule"
+	// ValueMetaKey is the alert metadata key holding the last value of the breaching row.
+	ValueMetaKey = "value"
+)
+
+// Row is a query result row by column name.
+type Row map[string]any
+
+// Querier runs the rule query, see alert/clickhouse.Querier.
+type Querier interface {
+	Query(ctx context.Context, query string) ([]Row, error)
+}
+
+type series struct {
+	labels       map[string]any
+	value        float64
+	severity     alert.SeverityLevel
+	pendingSince time.Time
+	firingFrom   time.Time // zero while pending
+}
+
+// Source is an alert.Source evaluating a threshold rule. The rule can be replaced with SetRule
+// without losing the pending and firing alerts.
+type Source struct {
+	querier Querier
+
+	mtx    sync.Mutex
+	rule   *Rule
+	series map[string]*series
+}
+
+func NewSource(querier Querier, rule *Rule) *Source {
+	return &Source{
+		querier: querier,
+		rule:    rule,
+		series:  make(map[string]*series),
+	}
+}
+
+func (s *Source) Rule() *Rule {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.rule
+}
+
+func (s *Source) SetRule(rule *Rule) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.rule = rule
+}
+
+func (s *Source) EvaluateAlerts(ctx context.Context) ([]alert.Alert, time.Time, error) {
+	rule := s.Rule()
+	rows, err := s.querier.Query(ctx, rule.Query)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("threshold rule %s: %w", rule.Name, err)
+	}
+	now := time.Now()
+	alerts, err := s.evaluate(rows, now)
+	return alerts, now.Add(rule.Interval), err
+}
+
+// evaluate returns the firing alerts and the resolved ones.
+func (s *Source) evaluate(rows []Row, now time.Time) ([]alert.Alert, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var (
+		res  []alert.Alert
+		errs error
+		seen = make(map[string]struct{}, len(rows))
+	)
+	for _, row := range rows {
+		value, ok := toFloat(row[s.rule.valueColumn()])
+		if !ok {
+			errs = errors.Join(errs, fmt.Errorf("threshold rule %s: column %q isn't a number: %v", s.rule.Name, s.rule.valueColumn(), row[s.rule.valueColumn()]))
+			continue
+		}
+		severity, breached := s.rule.severity(value)
+		if !breached {
+			continue
+		}
+
+		key, labels := s.labels(row)
+		seen[key] = struct{}{}
+		ser, ok := s.series[key]
+		if !ok {
+			ser = &series{pendingSince: now, severity: severity}
+			s.series[key] = ser
+		}
+		ser.labels, ser.value = labels, value
+		if ser.severity != severity {
+			if !ser.firingFrom.IsZero() {
+				res = append(res, s.alert(key, ser, now))
+				ser.firingFrom = now
+			}
+			ser.severity = severity
+		}
+		if ser.firingFrom.IsZero() && now.Sub(ser.pendingSince) >= s.rule.For {
+			ser.firingFrom = now
+		}
+		if !ser.firingFrom.IsZero() {
+			res = append(res, s.alert(key, ser, time.Time{}))
+		}
+	}
+
+	for key, ser := range s.series {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		if !ser.firingFrom.IsZero() {
+			res = append(res, s.alert(key, ser, now))
+		}
+		delete(s.series, key)
+	}
+	return res, errs
+}
+
+// Resolve resolves the firing alerts, e.g. when the rule is removed.
+func (s *Source) Resolve() []alert.Alert {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	now := time.Now()
+	var res []alert.Alert
+	for key, ser := range s.series {
+		if !ser.firingFrom.IsZero() {
+			res = append(res, s.alert(key, ser, now))
+		}
+		delete(s.series, key)
+	}
+	return res
+}
+
+func (s *Source) labels(row Row) (key string, labels map[string]any) {
+	values := make([]string, 0, len(s.rule.LabelColumns))
+	labels = make(map[string]any, len(s.rule.LabelColumns))
+	for _, column := range s.rule.LabelColumns {
+		values = append(values, fmt.Sprint(row[column]))
+		labels[column] = row[column]
+	}
+	return strings.Join(values, ","), labels
+}
+
+func (s *Source) alert(key string, ser *series, to time.Time) alert.Alert {
+	sourceID := s.rule.ID
+	if key != "" {
+		sourceID += "{" + key + "}"
+	}
+	a := alert.Alert{
+		SourceID:  sourceID,
+		Severity:  ser.severity,
+		From:      ser.firingFrom,
+		To:        to,
+		Receivers: s.rule.Receivers,
+		MetaData:  make(map[string]any, len(ser.labels)+2),
+	}
+	for k, v := range ser.labels {
+		a.MetaData[k] = v
+	}
+	a.MetaData[RuleMetaKey] = s.rule.Name
+	a.MetaData[ValueMetaKey] = ser.value
+	return a
+}
+
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return 0, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,161 @@
+package threshold
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/alert"
+	"github.com/bldsoft/gost/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRule() *Rule {
+	return &Rule{
+		ID:           "r1",
+		Name:         "errors",
+		Query:        "SELECT host, count() AS value FROM errors GROUP BY host",
+		LabelColumns: []string{"host"},
+		Operator:     OperatorGreater,
+		Thresholds: []Threshold{
+			{Severity: alert.SeverityLevelMedium, Value: 10},
+			{Severity: alert.SeverityLevelCritical, Value: 100},
+		},
+		Interval: time.Minute,
+		For:      2 * time.Minute,
+	}
+}
+
+func TestRuleSeverity(t *testing.T) {
+	rule := testRule()
+	_, breached := rule.severity(5)
+	assert.False(t, breached)
+	severity, breached := rule.severity(50)
+	assert.True(t, breached)
+	assert.Equal(t, alert.SeverityLevelMedium, severity)
+	severity, _ = rule.severity(500)
+	assert.Equal(t, alert.SeverityLevelCritical, severity)
+
+	assert.NoError(t, rule.Validate())
+	rule.Operator = "~"
+	assert.ErrorIs(t, rule.Validate(), ErrInvalidRule)
+}
+
+func TestSourceEvaluate(t *testing.T) {
+	s := NewSource(nil, testRule())
+	start := time.Now()
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	rows := func(a, b any) []Row {
+		return []Row{{"host": "a", "value": a}, {"host": "b", "value": b}}
+	}
+
+	alerts, err := s.evaluate(rows(uint64(50), uint64(1)), at(0))
+	require.NoError(t, err)
+	assert.Empty(t, alerts, "pending")
+
+	alerts, err = s.evaluate(rows(uint64(50), uint64(1)), at(2))
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "r1{a}", alerts[0].SourceID)
+	assert.Equal(t, alert.SeverityLevelMedium, alerts[0].Severity)
+	assert.Equal(t, at(2), alerts[0].From)
+	assert.True(t, alerts[0].To.IsZero())
+	assert.Equal(t, "a", alerts[0].MetaData["host"])
+	assert.Equal(t, "errors", alerts[0].MetaData[RuleMetaKey])
+	assert.Equal(t, float64(50), alerts[0].MetaData[ValueMetaKey])
+
+	alerts, err = s.evaluate(rows(200.0, int32(1)), at(3))
+	require.NoError(t, err)
+	require.Len(t, alerts, 2, "medium is resolved, critical fires")
+	assert.Equal(t, alert.SeverityLevelMedium, alerts[0].Severity)
+	assert.Equal(t, at(3), alerts[0].To)
+	assert.Equal(t, alert.SeverityLevelCritical, alerts[1].Severity)
+	assert.Equal(t, at(3), alerts[1].From)
+
+	alerts, err = s.evaluate(rows("x", 1), at(4))
+	assert.Error(t, err)
+	require.Len(t, alerts, 1, "missing row is resolved")
+	assert.Equal(t, alert.SeverityLevelCritical, alerts[0].Severity)
+	assert.Equal(t, at(4), alerts[0].To)
+	assert.Empty(t, s.series)
+}
+
+type testQuerier []Row
+
+func (q testQuerier) Query(ctx context.Context, query string) ([]Row, error) {
+	return q, nil
+}
+
+type testRuleRepository map[string]*Rule
+
+func (r testRuleRepository) CreateRule(ctx context.Context, rule *Rule) error {
+	r[rule.ID] = rule
+	return nil
+}
+
+func (r testRuleRepository) UpdateRule(ctx context.Context, rule *Rule) error {
+	if _, ok := r[rule.ID]; !ok {
+		return utils.ErrObjectNotFound
+	}
+	r[rule.ID] = rule
+	return nil
+}
+
+func (r testRuleRepository) DeleteRule(ctx context.Context, id string) error {
+	if _, ok := r[id]; !ok {
+		return utils.ErrObjectNotFound
+	}
+	delete(r, id)
+	return nil
+}
+
+func (r testRuleRepository) GetRule(ctx context.Context, id string) (*Rule, error) {
+	if rule, ok := r[id]; ok {
+		return rule, nil
+	}
+	return nil, utils.ErrObjectNotFound
+}
+
+func (r testRuleRepository) FindRules(ctx context.Context) ([]*Rule, error) {
+	res := make([]*Rule, 0, len(r))
+	for _, rule := range r {
+		res = append(res, rule)
+	}
+	return res, nil
+}
+
+func TestLoader(t *testing.T) {
+	ctx := context.Background()
+	rep := testRuleRepository{}
+	var handled []alert.Alert
+	loader := NewLoader(rep, testQuerier{{"host": "a", "value": 500}}, alert.NewManager(alert.Config{WorkerN: 1}),
+		alert.HandlerFunc(func(ctx context.Context, alerts ...alert.Alert) {
+			handled = append(handled, alerts...)
+		}))
+
+	rule := testRule()
+	rule.For = 0
+	require.NoError(t, rep.CreateRule(ctx, rule))
+	require.NoError(t, rep.CreateRule(ctx, &Rule{ID: "invalid"}))
+	require.NoError(t, loader.Load(ctx))
+	require.Len(t, loader.sources, 1)
+	source := loader.sources[rule.ID]
+
+	alerts, _, err := source.EvaluateAlerts(ctx)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+
+	updated := *rule
+	updated.Name = "renamed"
+	require.NoError(t, rep.UpdateRule(ctx, &updated))
+	require.NoError(t, loader.Load(ctx))
+	assert.Same(t, source, loader.sources[rule.ID], "the source keeps its state")
+	assert.Equal(t, "renamed", source.Rule().Name)
+
+	updated.Disabled = true
+	require.NoError(t, loader.Load(ctx))
+	assert.Empty(t, loader.sources)
+	require.Len(t, handled, 1, "firing alert is resolved")
+	assert.False(t, handled[0].To.IsZero())
+}
@@ -44,12 +44,34 @@ func (s State) String() string {
 // CircuitBreaker clears the internal Counts either
 // on the change of the state or at the closed-state intervals.
 // Counts ignores the results of the requests sent before clearing.
+//
+// The window fields are set if Settings.SlidingWindow is. They hold the finished closed-state calls
+// in the sliding window, which is cleared on the change of the state only.
 type Counts struct {
-	Requests             uint32
-	TotalSuccesses       uint32
-	TotalFailures        uint32
-	ConsecutiveSuccesses uint32
-	ConsecutiveFailures  uint32
+	Requests             uint32 `json:"requests"`
+	TotalSuccesses       uint32 `json:"totalSuccesses"`
+	TotalFailures        uint32 `json:"totalFailures"`
+	ConsecutiveSuccesses uint32 `json:"consecutiveSuccesses"`
+	ConsecutiveFailures  uint32 `json:"consecutiveFailures"`
+	WindowRequests       uint32 `json:"windowRequests,omitempty"`
+	WindowFailures       uint32 `json:"windowFailures,omitempty"`
+	WindowSlowCalls      uint32 `json:"windowSlowCalls,omitempty"`
+}
+
+// FailureRate returns the percentage of the failed calls in the sliding window.
+func (c Counts) FailureRate() float64 {
+	if c.WindowRequests == 0 {
+		return 0
+	}
+	return float64(c.WindowFailures) / float64(c.WindowRequests) * 100
+}
+
+// SlowCallRate returns the percentage of the slow calls in the sliding window.
+func (c Counts) SlowCallRate() float64 {
+	if c.WindowRequests == 0 {
+		return 0
+	}
+	return float64(c.WindowSlowCalls) / float64(c.WindowRequests) * 100
 }
 
 func (c *Counts) onRequest() {
@@ -85,12 +107,17 @@ type CircuitBreaker struct {
 	readyToTrip   func(counts Counts) bool
 	isSuccessful  func(result any, err error) error
 	onStateChange func(name string, from State, to State)
+	slidingWindow SlidingWindow
 
 	mutex      sync.Mutex
 	state      State
 	generation uint64
 	counts     Counts
 	expiry     time.Time
+	window     *window
+	// forced state doesn't change on its own, see ForceOpen and ForceClose
+	forced         bool
+	transitionedAt time.Time
 }
 
 // NewCircuitBreaker returns a new CircuitBreaker configured with the given Settings.
@@ -118,15 +145,23 @@ func NewCircuitBreaker(st Settings) *CircuitBreaker {
 		cb.timeout = st.Timeout
 	}
 
-	if st.ReadyToTrip == nil {
-		cb.readyToTrip = defaultReadyToTrip
-	} else {
+	if st.SlidingWindow != nil {
+		cb.slidingWindow = *st.SlidingWindow
+		cb.window = newWindow(cb.slidingWindow)
+	}
+
+	if st.ReadyToTrip != nil {
 		cb.readyToTrip = st.ReadyToTrip
+	} else if cb.window != nil {
+		cb.readyToTrip = cb.slidingWindow.ReadyToTrip
+	} else {
+		cb.readyToTrip = defaultReadyToTrip
 	}
 
 	cb.isSuccessful = st.IsSuccessful
 
-	cb.ToNewGeneration(time.Now())
+	cb.transitionedAt = time.Now()
+	cb.ToNewGeneration(cb.transitionedAt)
 
 	return cb
 }
@@ -151,7 +186,67 @@ func (cb *CircuitBreaker) Counts() Counts {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
-	return cb.counts
+	return cb.countsAt(time.Now())
+}
+
+// LastTransition returns the time of the last state change or, if there was none, of the creation.
+func (cb *CircuitBreaker) LastTransition() time.Time {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.transitionedAt
+}
+
+// Forced reports whether the state is forced with ForceOpen or ForceClose.
+func (cb *CircuitBreaker) Forced() bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.forced
+}
+
+// ForceOpen opens the CircuitBreaker until Reset or ForceClose, it doesn't become half-open after the timeout.
+func (cb *CircuitBreaker) ForceOpen() {
+	cb.force(StateOpen)
+}
+
+// ForceClose closes the CircuitBreaker until Reset or ForceOpen, the failures don't trip it.
+func (cb *CircuitBreaker) ForceClose() {
+	cb.force(StateClosed)
+}
+
+func (cb *CircuitBreaker) force(state State) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.forced = false
+	cb.setState(state, time.Now())
+	cb.forced = true
+}
+
+// Reset clears the forced state and the counts and closes the CircuitBreaker.
+func (cb *CircuitBreaker) Reset() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := time.Now()
+	cb.forced = false
+	if cb.state != StateClosed {
+		cb.setState(StateClosed, now)
+		return
+	}
+	cb.ToNewGeneration(now)
+	if cb.window != nil {
+		cb.window.clear()
+	}
+}
+
+func (cb *CircuitBreaker) countsAt(now time.Time) Counts {
+	counts := cb.counts
+	if cb.window != nil {
+		counts.WindowRequests, counts.WindowFailures, counts.WindowSlowCalls = cb.window.counts(now)
+	}
+	return counts
 }
 
 // Execute runs the given request if the CircuitBreaker accepts it.
@@ -165,10 +260,11 @@ func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{},
 		return nil, err
 	}
 
+	start := time.Now()
 	defer func() {
 		e := recover()
 		if e != nil {
-			cb.afterRequest(generation, false)
+			cb.afterRequest(generation, false, time.Since(start))
 			panic(e)
 		}
 	}()
@@ -179,7 +275,7 @@ func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{},
 		err = cb.isSuccessful(result, err)
 	}
 
-	cb.afterRequest(generation, err == nil)
+	cb.afterRequest(generation, err == nil, time.Since(start))
 	return result, err
 }
 
@@ -200,7 +296,7 @@ func (cb *CircuitBreaker) beforeRequest() (uint64, error) {
 	return generation, nil
 }
 
-func (cb *CircuitBreaker) afterRequest(before uint64, success bool) {
+func (cb *CircuitBreaker) afterRequest(before uint64, success bool, duration time.Duration) {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
@@ -210,17 +306,24 @@ func (cb *CircuitBreaker) afterRequest(before uint64, success bool) {
 		return
 	}
 
+	slow := cb.slidingWindow.isSlow(duration)
 	if success {
-		cb.onSuccess(state, now)
+		cb.onSuccess(state, now, slow)
 	} else {
-		cb.onFailure(state, now)
+		cb.onFailure(state, now, slow)
 	}
 }
 
-func (cb *CircuitBreaker) onSuccess(state State, now time.Time) {
+func (cb *CircuitBreaker) onSuccess(state State, now time.Time, slow bool) {
 	switch state {
 	case StateClosed:
 		cb.counts.onSuccess()
+		if cb.window != nil {
+			cb.window.record(now, false, slow)
+			if slow && !cb.forced && cb.readyToTrip(cb.countsAt(now)) {
+				cb.setState(StateOpen, now)
+			}
+		}
 	case StateHalfOpen:
 		cb.counts.onSuccess()
 		if cb.counts.ConsecutiveSuccesses >= cb.maxRequests {
@@ -229,11 +332,14 @@ func (cb *CircuitBreaker) onSuccess(state State, now time.Time) {
 	}
 }
 
-func (cb *CircuitBreaker) onFailure(state State, now time.Time) {
+func (cb *CircuitBreaker) onFailure(state State, now time.Time, slow bool) {
 	switch state {
 	case StateClosed:
 		cb.counts.onFailure()
-		if cb.readyToTrip(cb.counts) {
+		if cb.window != nil {
+			cb.window.record(now, true, slow)
+		}
+		if !cb.forced && cb.readyToTrip(cb.countsAt(now)) {
 			cb.setState(StateOpen, now)
 		}
 	case StateHalfOpen:
@@ -248,7 +354,7 @@ func (cb *CircuitBreaker) currentState(now time.Time) (State, uint64) {
 			cb.ToNewGeneration(now)
 		}
 	case StateOpen:
-		if cb.expiry.Before(now) {
+		if !cb.forced && cb.expiry.Before(now) {
 			cb.setState(StateHalfOpen, now)
 		}
 	}
@@ -262,6 +368,10 @@ func (cb *CircuitBreaker) setState(state State, now time.Time) {
 
 	prev := cb.state
 	cb.state = state
+	cb.transitionedAt = now
+	if cb.window != nil {
+		cb.window.clear()
+	}
 
 	cb.ToNewGeneration(now)
 
@@ -0,0 +1,109 @@
+package breaker
+
+import (
+	"net/http"
+
+	"github.com/bldsoft/gost/controller"
+	"github.com/go-chi/chi/v5"
+)
+
+// RegistryController lists the registered circuit breakers and forces their states.
+type RegistryController struct {
+	controller.BaseController
+	registry *Registry
+}
+
+func NewRegistryController(registry *Registry) *RegistryController {
+	return &RegistryController{registry: registry}
+}
+
+func (c *RegistryController) breaker(w http.ResponseWriter, r *http.Request) (*CircuitBreaker, bool) {
+	cb, ok := c.registry.Get(chi.URLParam(r, "name"))
+	if !ok {
+		c.ResponseError(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	}
+	return cb, ok
+}
+
+// GetBreakersHandler lists the circuit breakers.
+// @Summary list circuit breakers
+// @Tags admin
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} Info "OK"
+// @Router /breakers [get]
+func (c *RegistryController) GetBreakersHandler(w http.ResponseWriter, r *http.Request) {
+	c.ResponseJson(w, r, c.registry.List())
+}
+
+// GetBreakerHandler gets a single circuit breaker.
+// @Summary get circuit breaker
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param name path string true "Breaker name"
+// @Produce json
+// @Success 200 {object} Info "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /breakers/{name} [get]
+func (c *RegistryController) GetBreakerHandler(w http.ResponseWriter, r *http.Request) {
+	if cb, ok := c.breaker(w, r); ok {
+		c.ResponseJson(w, r, cb.info())
+	}
+}
+
+// ForceOpenHandler opens a circuit breaker until it's reset or forced closed.
+// @Summary force circuit breaker open
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param name path string true "Breaker name"
+// @Produce json
+// @Success 200 {object} Info "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /breakers/{name}/open [post]
+func (c *RegistryController) ForceOpenHandler(w http.ResponseWriter, r *http.Request) {
+	if cb, ok := c.breaker(w, r); ok {
+		cb.ForceOpen()
+		c.ResponseJson(w, r, cb.info())
+	}
+}
+
+// ForceCloseHandler closes a circuit breaker until it's reset or forced open.
+// @Summary force circuit breaker closed
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param name path string true "Breaker name"
+// @Produce json
+// @Success 200 {object} Info "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /breakers/{name}/close [post]
+func (c *RegistryController) ForceCloseHandler(w http.ResponseWriter, r *http.Request) {
+	if cb, ok := c.breaker(w, r); ok {
+		cb.ForceClose()
+		c.ResponseJson(w, r, cb.info())
+	}
+}
+
+// ResetHandler clears the forced state and the counts of a circuit breaker and closes it.
+// @Summary reset circuit breaker
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param name path string true "Breaker name"
+// @Produce json
+// @Success 200 {object} Info "OK"
+// @Failure 404 {string} string "Not found"
+// @Router /breakers/{name}/reset [post]
+func (c *RegistryController) ResetHandler(w http.ResponseWriter, r *http.Request) {
+	if cb, ok := c.breaker(w, r); ok {
+		cb.Reset()
+		c.ResponseJson(w, r, cb.info())
+	}
+}
+
+// Mount it with r.Route("/breakers", c.Mount).
+func (c *RegistryController) Mount(r chi.Router) {
+	r.Get("/", c.GetBreakersHandler)
+	r.Get("/{name}", c.GetBreakerHandler)
+	r.Post("/{name}/open", c.ForceOpenHandler)
+	r.Post("/{name}/close", c.ForceCloseHandler)
+	r.Post("/{name}/reset", c.ResetHandler)
+}
@@ -1,15 +1,23 @@
 package breaker
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+
+	"github.com/bldsoft/gost/bulkhead"
+	"github.com/bldsoft/gost/retry"
 )
 
 type Client struct {
 	*http.Client
 	circuitBreaker *CircuitBreaker
+	retry          *retry.Policy
+	bulkhead       *bulkhead.Partitions
+	bulkheadKey    bulkhead.KeyFunc
 }
 
 func NewClient(c *http.Client, settings Settings) *Client {
@@ -28,52 +36,97 @@ func NewClient(c *http.Client, settings Settings) *Client {
 	return &Client{Client: c, circuitBreaker: NewCircuitBreaker(settings)}
 }
 
+// SetRetry makes the client retry the requests according to the policy.
+// Every attempt passes the circuit breaker, the requests rejected by it aren't retried.
+func (c *Client) SetRetry(policy retry.Policy) *Client {
+	retryable := policy.RetryableResponse
+	if retryable == nil {
+		retryable = retry.DefaultRetryableResponse
+	}
+	policy.RetryableResponse = func(req *http.Request, resp *http.Response, err error) bool {
+		if errors.Is(err, ErrOpenState) || errors.Is(err, ErrTooManyRequests) {
+			return false
+		}
+		if resp != nil {
+			// the failed response, e.g. 503, is classified by its status, its Retry-After is honored
+			return retryable(req, resp, nil)
+		}
+		return retryable(req, resp, err)
+	}
+	c.retry = &policy
+	return c
+}
+
+// SetBulkhead limits the concurrent requests of every partition, by default of every upstream host.
+// Every retry attempt takes a slot, the requests rejected by the circuit breaker are handled as dropped.
+func (c *Client) SetBulkhead(partitions *bulkhead.Partitions, key bulkhead.KeyFunc) *Client {
+	c.bulkhead = partitions
+	c.bulkheadKey = key
+	return c
+}
+
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	resp, err := c.circuitBreaker.Execute(func() (interface{}, error) {
-		return c.Client.Do(req)
-	})
+	var (
+		resp *http.Response
+		err  error
+	)
+	if c.retry != nil {
+		resp, err = retry.RoundTrip(req, *c.retry, c.do)
+	} else {
+		resp, err = c.do(req)
+	}
 	if err != nil {
+		// the 5xx responses are returned as errors, close them so the connections are reused
+		if resp != nil {
+			resp.Body.Close()
+		}
 		return nil, err
 	}
-	return resp.(*http.Response), err
+	return resp, nil
 }
 
-func (c *Client) Get(url string) (*http.Response, error) {
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.bulkhead != nil {
+		return bulkhead.RoundTrip(c.bulkhead, c.bulkheadKey, req, c.execute)
+	}
+	return c.execute(req)
+}
+
+func (c *Client) execute(req *http.Request) (*http.Response, error) {
 	resp, err := c.circuitBreaker.Execute(func() (interface{}, error) {
-		return c.Client.Get(url)
+		return c.Client.Do(req)
 	})
+	// the response failed by the circuit breaker, e.g. 5xx, is returned with the error,
+	// so the retry policy sees its status and Retry-After, Do closes it
+	r, _ := resp.(*http.Response)
+	return r, err
+}
+
+func (c *Client) Get(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
-	return resp.(*http.Response), err
+	return c.Do(req)
 }
 
 func (c *Client) Head(url string) (*http.Response, error) {
-	resp, err := c.circuitBreaker.Execute(func() (interface{}, error) {
-		return c.Client.Head(url)
-	})
+	req, err := http.NewRequest(http.MethodHead, url, nil)
 	if err != nil {
 		return nil, err
 	}
-	return resp.(*http.Response), err
+	return c.Do(req)
 }
 
 func (c *Client) Post(url string, contentType string, body io.Reader) (*http.Response, error) {
-	resp, err := c.circuitBreaker.Execute(func() (interface{}, error) {
-		return c.Client.Post(url, contentType, body)
-	})
+	req, err := http.NewRequest(http.MethodPost, url, body)
 	if err != nil {
 		return nil, err
 	}
-	return resp.(*http.Response), err
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
 }
 
 func (c *Client) PostForm(url string, data url.Values) (*http.Response, error) {
-	resp, err := c.circuitBreaker.Execute(func() (interface{}, error) {
-		return c.Client.PostForm(url, data)
-	})
-	if err != nil {
-		return nil, err
-	}
-	return resp.(*http.Response), err
+	return c.Post(url, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
 }
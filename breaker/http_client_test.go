@@ -0,0 +1,100 @@
+package breaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/bulkhead"
+	"github.com/bldsoft/gost/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	policy := retry.Policy{MaxAttempts: 5, InitialBackoff: time.Millisecond}
+	client := NewClient(http.DefaultClient, Settings{}).SetRetry(policy)
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestClientRetryStopsOnOpenBreaker(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	policy := retry.Policy{MaxAttempts: 5, InitialBackoff: time.Millisecond}
+	client := NewClient(http.DefaultClient, Settings{
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+	}).SetRetry(policy)
+	_, err := client.Get(srv.URL)
+	assert.ErrorIs(t, err, ErrOpenState)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestClientRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", r.URL.Query().Get("retryAfter"))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	policy := retry.Policy{MaxAttempts: 5, InitialBackoff: time.Minute, MaxRetryAfter: time.Minute}
+	client := NewClient(http.DefaultClient, Settings{}).SetRetry(policy)
+	resp, err := client.Get(srv.URL + "?retryAfter=0")
+	require.NoError(t, err, "the backoff is replaced by Retry-After")
+	resp.Body.Close()
+	assert.Equal(t, int32(2), calls.Load())
+
+	calls.Store(0)
+	_, err = client.Get(srv.URL + "?retryAfter=120")
+	assert.Error(t, err, "Retry-After exceeds MaxRetryAfter")
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestClientBulkhead(t *testing.T) {
+	block := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-block
+	}))
+	defer srv.Close()
+
+	partitions := bulkhead.NewPartitions(bulkhead.Config{NewLimit: func() bulkhead.Limit { return bulkhead.FixedLimit(1) }})
+	client := NewClient(http.DefaultClient, Settings{}).SetBulkhead(partitions, nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err := client.Get(srv.URL)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	_, err := client.Get(srv.URL)
+	assert.ErrorIs(t, err, bulkhead.ErrLimitExceeded)
+	close(block)
+	<-done
+}
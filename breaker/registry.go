@@ -0,0 +1,148 @@
+package breaker
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bldsoft/gost/log"
+)
+
+// Info is the runtime state of a registered CircuitBreaker.
+type Info struct {
+	Name           string    `json:"name"`
+	State          string    `json:"state"`
+	Forced         bool      `json:"forced,omitempty"`
+	Counts         Counts    `json:"counts"`
+	LastTransition time.Time `json:"lastTransition"`
+}
+
+// Registry tracks the circuit breakers by name. The breakers created by the registry log their state changes
+// and notify the registry listeners, e.g. an alert source.
+type Registry struct {
+	mtx       sync.RWMutex
+	breakers  map[string]*CircuitBreaker
+	listeners []func(name string, from State, to State)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{breakers: make(map[string]*CircuitBreaker)}
+}
+
+// OnStateChange adds a listener called whenever the state of a registered breaker changes.
+// It's called under the breaker lock, so it must not call the breaker.
+func (r *Registry) OnStateChange(listener func(name string, from State, to State)) *Registry {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.listeners = append(r.listeners, listener)
+	return r
+}
+
+// instrument makes the OnStateChange of the settings also log the change and notify the listeners.
+func (r *Registry) instrument(st Settings) Settings {
+	next := st.OnStateChange
+	st.OnStateChange = func(name string, from, to State) {
+		fields := log.Fields{"breaker": name, "from": from.String(), "to": to.String()}
+		if to == StateOpen {
+			log.WarnWithFields(fields, "Circuit breaker is open")
+		} else {
+			log.InfoWithFields(fields, "Circuit breaker state is changed")
+		}
+
+		r.mtx.RLock()
+		listeners := r.listeners
+		r.mtx.RUnlock()
+		for _, listener := range listeners {
+			listener(name, from, to)
+		}
+		if next != nil {
+			next(name, from, to)
+		}
+	}
+	return st
+}
+
+// Register adds the breaker created without the registry, its state changes aren't logged or listened to.
+// A breaker with the same name is replaced.
+func (r *Registry) Register(cb *CircuitBreaker) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.breakers[cb.Name()] = cb
+}
+
+// NewCircuitBreaker creates and registers a CircuitBreaker. A breaker with the same name is replaced.
+func (r *Registry) NewCircuitBreaker(st Settings) *CircuitBreaker {
+	cb := NewCircuitBreaker(r.instrument(st))
+	r.Register(cb)
+	return cb
+}
+
+// NewTwoStepCircuitBreaker creates and registers a TwoStepCircuitBreaker. A breaker with the same name is replaced.
+func (r *Registry) NewTwoStepCircuitBreaker(st Settings) *TwoStepCircuitBreaker {
+	tscb := NewTwoStepCircuitBreaker(r.instrument(st))
+	r.Register(tscb.cb)
+	return tscb
+}
+
+// NewClient creates a Client and registers its breaker. A breaker with the same name is replaced.
+func (r *Registry) NewClient(c *http.Client, st Settings) *Client {
+	client := NewClient(c, r.instrument(st))
+	r.Register(client.circuitBreaker)
+	return client
+}
+
+func (r *Registry) Remove(name string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	delete(r.breakers, name)
+}
+
+func (r *Registry) Get(name string) (*CircuitBreaker, bool) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	cb, ok := r.breakers[name]
+	return cb, ok
+}
+
+// Info returns the state of the named breaker.
+func (r *Registry) Info(name string) (Info, bool) {
+	cb, ok := r.Get(name)
+	if !ok {
+		return Info{}, false
+	}
+	return cb.info(), true
+}
+
+// List returns the states of the breakers sorted by name.
+func (r *Registry) List() []Info {
+	r.mtx.RLock()
+	breakers := make([]*CircuitBreaker, 0, len(r.breakers))
+	for _, cb := range r.breakers {
+		breakers = append(breakers, cb)
+	}
+	r.mtx.RUnlock()
+
+	res := make([]Info, 0, len(breakers))
+	for _, cb := range breakers {
+		res = append(res, cb.info())
+	}
+	slices.SortFunc(res, func(a, b Info) int { return strings.Compare(a.Name, b.Name) })
+	return res
+}
+
+func (cb *CircuitBreaker) info() Info {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := time.Now()
+	state, _ := cb.currentState(now)
+	return Info{
+		Name:           cb.name,
+		State:          state.String(),
+		Forced:         cb.forced,
+		Counts:         cb.countsAt(now),
+		LastTransition: cb.transitionedAt,
+	}
+}
@@ -0,0 +1,94 @@
+package breaker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	var changes []StateChange
+	registry := NewRegistry().OnStateChange(func(name string, from, to State) {
+		changes = append(changes, StateChange{name, from, to})
+	})
+	cb := registry.NewCircuitBreaker(Settings{Name: "b", Timeout: time.Second})
+	registry.NewTwoStepCircuitBreaker(Settings{Name: "a"})
+	registry.NewClient(http.DefaultClient, Settings{Name: "c"})
+
+	list := registry.List()
+	require.Len(t, list, 3)
+	assert.Equal(t, []string{"a", "b", "c"}, []string{list[0].Name, list[1].Name, list[2].Name})
+	assert.Equal(t, "closed", list[1].State)
+
+	created := cb.LastTransition()
+	cb.ForceOpen()
+	assert.Equal(t, []StateChange{{"b", StateClosed, StateOpen}}, changes)
+	assert.False(t, cb.LastTransition().Before(created))
+	pseudoSleep(cb, 2*time.Second)
+	assert.ErrorIs(t, succeed(cb), ErrOpenState, "forced open doesn't become half-open")
+	info, ok := registry.Info("b")
+	require.True(t, ok)
+	assert.Equal(t, "open", info.State)
+	assert.True(t, info.Forced)
+
+	cb.ForceClose()
+	for range 10 {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.State(), "forced closed doesn't trip")
+
+	cb.Reset()
+	assert.False(t, cb.Forced())
+	assert.Equal(t, Counts{0, 0, 0, 0, 0, 0, 0, 0}, cb.Counts())
+	for range 6 {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateOpen, cb.State(), "trips after reset")
+
+	registry.Remove("b")
+	_, ok = registry.Get("b")
+	assert.False(t, ok)
+}
+
+func TestRegistryController(t *testing.T) {
+	registry := NewRegistry()
+	cb := registry.NewCircuitBreaker(Settings{Name: "svc"})
+	r := chi.NewRouter()
+	r.Route("/breakers", NewRegistryController(registry).Mount)
+
+	call := func(method, path string) (*httptest.ResponseRecorder, Info) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		var info Info
+		_ = json.Unmarshal(w.Body.Bytes(), &info)
+		return w, info
+	}
+
+	w, info := call(http.MethodPost, "/breakers/svc/open")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "open", info.State)
+	assert.Equal(t, StateOpen, cb.State())
+
+	_, info = call(http.MethodPost, "/breakers/svc/close")
+	assert.Equal(t, "closed", info.State)
+	assert.True(t, info.Forced)
+
+	_, info = call(http.MethodPost, "/breakers/svc/reset")
+	assert.False(t, info.Forced)
+
+	w, _ = call(http.MethodGet, "/breakers/unknown")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/breakers", nil))
+	var list []Info
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, "svc", list[0].Name)
+}
@@ -31,6 +31,11 @@ import (
 // If IsSuccessful returns nil, it is counted as a success.
 // Otherwise the error is counted as a failure.
 // If IsSuccessful is nil, default IsSuccessful is used, which returns error from a request
+//
+// SlidingWindow, if set, records the outcomes and the durations of the closed-state calls
+// in a rolling window exposed through the window Counts fields.
+// If ReadyToTrip is nil, SlidingWindow.ReadyToTrip is used instead of the default one.
+// With the window, ReadyToTrip is also called whenever a slow request succeeds in the closed state.
 type Settings struct {
 	Name          string
 	MaxRequests   uint32
@@ -39,6 +44,7 @@ type Settings struct {
 	ReadyToTrip   func(counts Counts) bool
 	OnStateChange func(name string, from State, to State)
 	IsSuccessful  func(result any, err error) error
+	SlidingWindow *SlidingWindow
 }
 
 const defaultInterval = time.Duration(0) * time.Second
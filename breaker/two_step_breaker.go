@@ -1,5 +1,7 @@
 package breaker
 
+import "time"
+
 // TwoStepCircuitBreaker is like CircuitBreaker but instead of surrounding a function
 // with the breaker functionality, it only checks whether a request can proceed and
 // expects the caller to report the outcome in a separate step using a callback.
@@ -31,14 +33,15 @@ func (tscb *TwoStepCircuitBreaker) Counts() Counts {
 
 // Allow checks if a new request can proceed. It returns a callback that should be used to
 // register the success or failure in a separate step. If the circuit breaker doesn't allow
-// requests, it returns an error.
+// requests, it returns an error. The call lasts until the callback is called, which matters for the slow calls.
 func (tscb *TwoStepCircuitBreaker) Allow() (done func(success bool), err error) {
 	generation, err := tscb.cb.beforeRequest()
 	if err != nil {
 		return nil, err
 	}
 
+	start := time.Now()
 	return func(success bool) {
-		tscb.cb.afterRequest(generation, success)
+		tscb.cb.afterRequest(generation, success, time.Since(start))
 	}, nil
 }
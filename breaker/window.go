@@ -0,0 +1,146 @@
+package breaker
+
+import (
+	"cmp"
+	"time"
+)
+
+const (
+	defaultWindowSize    = 100
+	defaultWindowBuckets = 10
+	defaultMinimumCalls  = 10
+)
+
+// SlidingWindow configures the rolling window of the recent calls:
+//
+// Size is the number of the last calls held by a count-based window. If Size is 0, it's 100.
+//
+// Duration, if set, makes the window time-based: it holds the calls of the last Duration
+// split into Buckets buckets, so the oldest bucket expires at once. If Buckets is 0, it's 10.
+//
+// MinimumCalls is the number of the calls the window must hold before the trip conditions are checked.
+// If MinimumCalls is 0, it's 10, or Size if the count-based window is smaller.
+//
+// FailureRateThreshold is the failure rate, in percent, the CircuitBreaker trips at.
+//
+// SlowCallDuration is the duration above which a call is slow,
+// SlowCallRateThreshold is the rate of the slow calls, in percent, the CircuitBreaker trips at.
+//
+// A zero threshold disables its condition.
+type SlidingWindow struct {
+	Size                  uint32
+	Duration              time.Duration
+	Buckets               uint32
+	MinimumCalls          uint32
+	FailureRateThreshold  float64
+	SlowCallDuration      time.Duration
+	SlowCallRateThreshold float64
+}
+
+// ReadyToTrip reports whether the window counts meet a trip condition.
+// It's the default ReadyToTrip of the CircuitBreaker with the window.
+func (w SlidingWindow) ReadyToTrip(counts Counts) bool {
+	if counts.WindowRequests == 0 || counts.WindowRequests < w.minimumCalls() {
+		return false
+	}
+	return (w.FailureRateThreshold > 0 && counts.FailureRate() >= w.FailureRateThreshold) ||
+		(w.SlowCallRateThreshold > 0 && counts.SlowCallRate() >= w.SlowCallRateThreshold)
+}
+
+func (w SlidingWindow) minimumCalls() uint32 {
+	if w.MinimumCalls > 0 {
+		return w.MinimumCalls
+	}
+	if w.Duration > 0 {
+		return defaultMinimumCalls
+	}
+	return min(defaultMinimumCalls, cmp.Or(w.Size, defaultWindowSize))
+}
+
+func (w SlidingWindow) isSlow(d time.Duration) bool {
+	return w.SlowCallDuration > 0 && d > w.SlowCallDuration
+}
+
+type windowBucket struct {
+	epoch    int64
+	calls    uint32
+	failures uint32
+	slow     uint32
+}
+
+// window is a ring of buckets. A bucket of the count-based window holds a single call,
+// a bucket of the time-based one holds the calls of its span, the epoch is the number of the span since the Unix epoch.
+type window struct {
+	span    time.Duration
+	buckets []windowBucket
+	pos     int
+
+	// the totals of the count-based window
+	calls    uint32
+	failures uint32
+	slow     uint32
+}
+
+func newWindow(st SlidingWindow) *window {
+	if st.Duration > 0 {
+		n := cmp.Or(st.Buckets, defaultWindowBuckets)
+		return &window{
+			span:    max(st.Duration/time.Duration(n), 1),
+			buckets: make([]windowBucket, n),
+		}
+	}
+	return &window{buckets: make([]windowBucket, cmp.Or(st.Size, defaultWindowSize))}
+}
+
+func (w *window) record(now time.Time, failure, slow bool) {
+	var failures, slowCalls uint32
+	if failure {
+		failures = 1
+	}
+	if slow {
+		slowCalls = 1
+	}
+
+	if w.span == 0 {
+		b := &w.buckets[w.pos]
+		w.calls -= b.calls
+		w.failures -= b.failures
+		w.slow -= b.slow
+		*b = windowBucket{calls: 1, failures: failures, slow: slowCalls}
+		w.calls++
+		w.failures += failures
+		w.slow += slowCalls
+		w.pos = (w.pos + 1) % len(w.buckets)
+		return
+	}
+
+	epoch := now.UnixNano() / int64(w.span)
+	b := &w.buckets[epoch%int64(len(w.buckets))]
+	if b.epoch != epoch {
+		*b = windowBucket{epoch: epoch}
+	}
+	b.calls++
+	b.failures += failures
+	b.slow += slowCalls
+}
+
+func (w *window) counts(now time.Time) (calls, failures, slow uint32) {
+	if w.span == 0 {
+		return w.calls, w.failures, w.slow
+	}
+	epoch := now.UnixNano() / int64(w.span)
+	for _, b := range w.buckets {
+		if b.epoch > epoch-int64(len(w.buckets)) && b.epoch <= epoch {
+			calls += b.calls
+			failures += b.failures
+			slow += b.slow
+		}
+	}
+	return calls, failures, slow
+}
+
+func (w *window) clear() {
+	clear(w.buckets)
+	w.pos = 0
+	w.calls, w.failures, w.slow = 0, 0, 0
+}
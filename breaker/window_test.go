@@ -0,0 +1,126 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func slowSucceed2Step(cb *TwoStepCircuitBreaker, d time.Duration) error {
+	done, err := cb.Allow()
+	if err != nil {
+		return err
+	}
+	time.Sleep(d)
+	done(true)
+	return nil
+}
+
+func TestCountBasedWindow(t *testing.T) {
+	w := newWindow(SlidingWindow{Size: 3})
+	now := time.Now()
+	w.record(now, true, false)
+	w.record(now, false, true)
+	calls, failures, slow := w.counts(now)
+	assert.Equal(t, []uint32{2, 1, 1}, []uint32{calls, failures, slow})
+
+	w.record(now, false, false)
+	w.record(now, false, false) // the failure is pushed out
+	calls, failures, slow = w.counts(now)
+	assert.Equal(t, []uint32{3, 0, 1}, []uint32{calls, failures, slow})
+
+	w.clear()
+	calls, _, _ = w.counts(now)
+	assert.Zero(t, calls)
+}
+
+func TestTimeBasedWindow(t *testing.T) {
+	w := newWindow(SlidingWindow{Duration: 10 * time.Second, Buckets: 10})
+	start := time.Unix(1000, 0)
+	w.record(start, true, false)
+	w.record(start.Add(5*time.Second), false, true)
+	calls, failures, slow := w.counts(start.Add(9 * time.Second))
+	assert.Equal(t, []uint32{2, 1, 1}, []uint32{calls, failures, slow})
+
+	// the first bucket expires
+	calls, failures, slow = w.counts(start.Add(10 * time.Second))
+	assert.Equal(t, []uint32{1, 0, 1}, []uint32{calls, failures, slow})
+
+	// the bucket is reused for a later span
+	w.record(start.Add(20*time.Second), false, false)
+	calls, failures, slow = w.counts(start.Add(20 * time.Second))
+	assert.Equal(t, []uint32{1, 0, 0}, []uint32{calls, failures, slow})
+}
+
+func TestFailureRateTrip(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{SlidingWindow: &SlidingWindow{
+		Size:                 10,
+		MinimumCalls:         4,
+		FailureRateThreshold: 50,
+	}})
+
+	assert.Nil(t, fail(cb))
+	assert.Nil(t, fail(cb))
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateClosed, cb.State(), "less than the minimum calls")
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, succeed(cb))
+	assert.Nil(t, succeed(cb))
+	counts := cb.Counts()
+	assert.Equal(t, uint32(7), counts.WindowRequests)
+	assert.Equal(t, uint32(3), counts.WindowFailures)
+	assert.InDelta(t, 42.86, counts.FailureRate(), 0.01)
+
+	// not consecutive, but the failure rate reaches 50%
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+	assert.Zero(t, cb.Counts().WindowRequests, "window is cleared on the state change")
+}
+
+func TestDefaultMinimumCalls(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{SlidingWindow: &SlidingWindow{FailureRateThreshold: 50}})
+	for range defaultMinimumCalls - 1 {
+		assert.Nil(t, fail(cb))
+	}
+	assert.Equal(t, StateClosed, cb.State(), "less than the default minimum calls")
+	assert.Nil(t, fail(cb))
+	assert.Equal(t, StateOpen, cb.State())
+
+	assert.Equal(t, uint32(5), SlidingWindow{Size: 5}.minimumCalls(), "limited by the window size")
+	assert.Equal(t, uint32(defaultMinimumCalls), SlidingWindow{Duration: time.Minute}.minimumCalls())
+	assert.Equal(t, uint32(1), SlidingWindow{MinimumCalls: 1}.minimumCalls())
+}
+
+func TestWindowSurvivesInterval(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{
+		Interval:      time.Second,
+		SlidingWindow: &SlidingWindow{Size: 10, FailureRateThreshold: 100},
+	})
+	assert.Nil(t, succeed(cb))
+	pseudoSleep(cb, 2*time.Second)
+	assert.Nil(t, succeed(cb))
+	counts := cb.Counts()
+	assert.Equal(t, uint32(1), counts.Requests)
+	assert.Equal(t, uint32(2), counts.WindowRequests)
+}
+
+func TestSlowCallRateTrip(t *testing.T) {
+	cb := NewTwoStepCircuitBreaker(Settings{SlidingWindow: &SlidingWindow{
+		Size:                  4,
+		MinimumCalls:          2,
+		SlowCallDuration:      time.Millisecond,
+		SlowCallRateThreshold: 50,
+	}})
+
+	assert.Nil(t, succeed2Step(cb))
+	assert.Nil(t, succeed2Step(cb))
+	assert.Nil(t, succeed2Step(cb))
+	assert.Nil(t, slowSucceed2Step(cb, 5*time.Millisecond))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, float64(25), cb.Counts().SlowCallRate())
+
+	assert.Nil(t, slowSucceed2Step(cb, 5*time.Millisecond))
+	assert.Equal(t, StateOpen, cb.State(), "successful but slow calls trip the breaker")
+}
@@ -0,0 +1,283 @@
+package bulkhead
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixed(n int) func() Limit {
+	return func() Limit { return FixedLimit(n) }
+}
+
+func TestLimiterFixed(t *testing.T) {
+	ctx := context.Background()
+	l := NewLimiter(Config{NewLimit: fixed(2)})
+
+	release1, err := l.Acquire(ctx)
+	require.NoError(t, err)
+	release2, err := l.Acquire(ctx)
+	require.NoError(t, err)
+	_, err = l.Acquire(ctx)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Equal(t, 2, l.Inflight())
+
+	release1(false)
+	release1(false) // released once
+	assert.Equal(t, 1, l.Inflight())
+	release3, err := l.Acquire(ctx)
+	require.NoError(t, err)
+	release2(false)
+	release3(false)
+	assert.Equal(t, 0, l.Inflight())
+}
+
+func TestLimiterQueue(t *testing.T) {
+	ctx := context.Background()
+	l := NewLimiter(Config{NewLimit: fixed(1), MaxQueue: 1, QueueTimeout: time.Second})
+
+	release, err := l.Acquire(ctx)
+	require.NoError(t, err)
+
+	acquired := make(chan func(bool))
+	go func() {
+		release, err := l.Acquire(ctx)
+		assert.NoError(t, err)
+		acquired <- release
+	}()
+	require.Eventually(t, func() bool {
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+		return len(l.waiters) == 1
+	}, time.Second, time.Millisecond)
+
+	// the queue is full
+	_, err = l.Acquire(ctx)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+
+	release(false)
+	select {
+	case release := <-acquired:
+		assert.Equal(t, 1, l.Inflight())
+		release(false)
+	case <-time.After(time.Second):
+		t.Fatal("the queued call didn't get the slot")
+	}
+	assert.Equal(t, 0, l.Inflight())
+}
+
+func TestLimiterQueueTimeout(t *testing.T) {
+	l := NewLimiter(Config{NewLimit: fixed(1), MaxQueue: 1, QueueTimeout: 10 * time.Millisecond})
+	release, err := l.Acquire(context.Background())
+	require.NoError(t, err)
+	defer release(false)
+
+	start := time.Now()
+	_, err = l.Acquire(context.Background())
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = l.Acquire(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	assert.Empty(t, l.waiters)
+	assert.Equal(t, 1, l.inflight)
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	l := NewLimiter(Config{NewLimit: fixed(3), MaxQueue: 100})
+	var (
+		mtx      sync.Mutex
+		inflight int
+		peak     int
+		wg       sync.WaitGroup
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.Acquire(context.Background())
+			if !assert.NoError(t, err) {
+				return
+			}
+			mtx.Lock()
+			inflight++
+			peak = max(peak, inflight)
+			mtx.Unlock()
+			time.Sleep(time.Millisecond)
+			mtx.Lock()
+			inflight--
+			mtx.Unlock()
+			release(false)
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, peak, 3)
+	assert.Equal(t, 0, l.Inflight())
+}
+
+func TestAIMDLimit(t *testing.T) {
+	l := NewAIMDLimit(10, 5, 12).SetLatencyThreshold(100 * time.Millisecond)
+
+	// not used enough to grow
+	l.OnSample(time.Millisecond, 1, false)
+	assert.Equal(t, 10, l.Limit())
+
+	for range 5 {
+		l.OnSample(time.Millisecond, 10, false)
+	}
+	assert.Equal(t, 12, l.Limit())
+
+	l.OnSample(time.Millisecond, 10, true)
+	assert.Equal(t, 10, l.Limit()) // 12 * 0.9
+	l.OnSample(time.Second, 10, false)
+	assert.Equal(t, 9, l.Limit())
+
+	for range 20 {
+		l.OnSample(time.Millisecond, 10, true)
+	}
+	assert.Equal(t, 5, l.Limit())
+}
+
+func TestGradientLimit(t *testing.T) {
+	l := NewGradientLimit(20, 1, 100)
+	for range 50 {
+		l.OnSample(10*time.Millisecond, l.Limit(), false)
+	}
+	grown := l.Limit()
+	assert.Greater(t, grown, 20)
+
+	// the latency rises well above the average
+	for range 20 {
+		l.OnSample(100*time.Millisecond, l.Limit(), false)
+	}
+	assert.Less(t, l.Limit(), grown)
+
+	shrunk := l.Limit()
+	l.OnSample(10*time.Millisecond, l.Limit(), true)
+	assert.Equal(t, max(shrunk/2, 1), l.Limit())
+}
+
+func TestMiddleware(t *testing.T) {
+	p := NewPartitions(Config{NewLimit: fixed(1), RetryAfter: 1500 * time.Millisecond})
+	block := make(chan struct{})
+	entered := make(chan struct{}, 1)
+
+	r := chi.NewRouter()
+	r.With(Middleware(p, RouteKey)).Get("/slow/{id}", func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-block
+	})
+	r.With(Middleware(p, RouteKey)).Get("/fast", func(w http.ResponseWriter, r *http.Request) {})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err := http.Get(srv.URL + "/slow/1")
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+	}()
+	<-entered
+
+	// same route, other path parameter
+	resp, err := http.Get(srv.URL + "/slow/2")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+
+	// other partition
+	resp, err = http.Get(srv.URL + "/fast")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	close(block)
+	<-done
+	assert.Equal(t, 0, p.Get("GET /slow/{id}").Inflight())
+}
+
+func TestTransport(t *testing.T) {
+	block := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-block
+	}))
+	defer srv.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer other.Close()
+
+	p := NewPartitions(Config{NewLimit: func() Limit { return NewAIMDLimit(1, 1, 10) }})
+	client := &http.Client{Transport: NewTransport(nil, p, nil)}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err := client.Get(srv.URL)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	_, err := client.Get(srv.URL)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+
+	resp, err := client.Get(other.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	close(block)
+	<-done
+	srvHost := srv.Listener.Addr().String()
+	assert.Equal(t, 2, p.Get(srvHost).Limit())                        // grown after the success
+	assert.Equal(t, 1, p.Get(other.Listener.Addr().String()).Limit()) // not grown after the overload
+}
+
+func TestRouteKeyFallback(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/unrouted/1", nil)
+	assert.Equal(t, unroutedKey, RouteKey(r))
+	r = httptest.NewRequest("PURGE", "/unrouted/2", nil)
+	assert.Equal(t, unroutedKey, RouteKey(r), "the requests without a route share one partition")
+}
+
+func TestRoundTripReleaseOnBodyClose(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("body"))
+	}))
+	defer srv.Close()
+
+	p := NewPartitions(Config{NewLimit: fixed(1)})
+	client := &http.Client{Transport: NewTransport(nil, p, nil)}
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	limiter := p.Get(srv.Listener.Addr().String())
+	assert.Equal(t, 1, limiter.Inflight(), "the body is being read")
+
+	_, err = client.Get(srv.URL)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+
+	resp.Body.Close()
+	assert.Equal(t, 0, limiter.Inflight())
+	resp.Body.Close()
+	assert.Equal(t, 0, limiter.Inflight(), "released once")
+}
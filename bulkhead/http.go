@@ -0,0 +1,137 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bldsoft/gost/log"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// KeyFunc returns the partition of a request.
+type KeyFunc func(r *http.Request) string
+
+// unroutedKey is the partition shared by the requests without a route pattern.
+const unroutedKey = ""
+
+// RouteKey partitions the requests by the chi route pattern. The pattern is complete only after routing,
+// so the middleware should be added to the routes with chi.Router.With or Group. The requests without a pattern,
+// e.g. before routing, share one partition, so the clients can't create partitions by the path or method.
+func RouteKey(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return r.Method + " " + pattern
+		}
+	}
+	return unroutedKey
+}
+
+// UpstreamKey partitions the outgoing requests by the host they are sent to.
+func UpstreamKey(r *http.Request) string {
+	return r.URL.Host
+}
+
+// Middleware limits the concurrent requests of every partition. If key is nil, all the requests share one partition.
+// The shed requests are responded with 503 Service Unavailable and Retry-After.
+// The 503 and 504 responses of the handler are handled as dropped by the adaptive limits.
+func Middleware(p *Partitions, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			release, err := p.Acquire(r.Context(), partitionKey(key, r))
+			if err != nil {
+				if errors.Is(err, ErrLimitExceeded) {
+					shed(w, r, p.cfg.RetryAfter)
+				}
+				// the context is done, the client has gone
+				return
+			}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				release(isOverloaded(ww.Status()))
+			}()
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+func shed(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	log.FromContext(r.Context()).WarnWithFields(log.Fields{"path": r.URL.Path}, "Request is shed by the concurrency limiter")
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
+
+// RoundTrip sends the request with send, limiting the concurrent requests of every partition.
+// If key is nil, the requests are partitioned by UpstreamKey. The shed requests return ErrLimitExceeded.
+// The errors and the 429, 503 and 504 responses are handled as dropped by the adaptive limits.
+// The slot is held until the response body is closed, so the body must be closed.
+func RoundTrip(p *Partitions, key KeyFunc, req *http.Request, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if key == nil {
+		key = UpstreamKey
+	}
+	release, err := p.Acquire(req.Context(), key(req))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(req)
+	dropped := isDropped(req.Context(), resp, err)
+	if err != nil || resp == nil || resp.Body == nil {
+		release(dropped)
+		return resp, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: func() { release(dropped) }}
+	return resp, nil
+}
+
+// releaseBody releases the slot of the request when the response body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// NewTransport returns a RoundTripper limiting the concurrent requests sent with base, see RoundTrip.
+func NewTransport(base http.RoundTripper, p *Partitions, key KeyFunc) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, partitions: p, key: key}
+}
+
+type transport struct {
+	base       http.RoundTripper
+	partitions *Partitions
+	key        KeyFunc
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return RoundTrip(t.partitions, t.key, req, t.base.RoundTrip)
+}
+
+func partitionKey(key KeyFunc, r *http.Request) string {
+	if key == nil {
+		return ""
+	}
+	return key(r)
+}
+
+func isOverloaded(status int) bool {
+	return status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+func isDropped(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		// the caller canceling the request says nothing about the upstream
+		return !errors.Is(err, context.Canceled) || ctx.Err() == nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || isOverloaded(resp.StatusCode)
+}
@@ -0,0 +1,134 @@
+package bulkhead
+
+import (
+	"math"
+	"time"
+)
+
+// Limit is the algorithm deciding the number of the concurrent calls.
+// The Limiter calls it under its lock, so the implementations don't need to be goroutine-safe.
+type Limit interface {
+	// Limit returns the current limit.
+	Limit() int
+	// OnSample is called after every call with its latency, the number of the calls in flight
+	// including it, and whether it was dropped, e.g. timed out or rejected by the backend as overloaded.
+	OnSample(latency time.Duration, inflight int, dropped bool)
+}
+
+// FixedLimit is a plain bulkhead, the limit doesn't change.
+type FixedLimit int
+
+func (l FixedLimit) Limit() int { return max(int(l), 1) }
+
+func (l FixedLimit) OnSample(latency time.Duration, inflight int, dropped bool) {}
+
+// AIMDLimit increases the limit by one after a successful call and multiplies it by the backoff ratio
+// after a dropped call or a call slower than the latency threshold (additive increase, multiplicative decrease).
+// The limit grows only while at least half of it is used.
+type AIMDLimit struct {
+	limit            float64
+	min              int
+	max              int
+	backoffRatio     float64
+	latencyThreshold time.Duration
+}
+
+func NewAIMDLimit(initial, min, max int) *AIMDLimit {
+	return &AIMDLimit{
+		limit:        float64(initial),
+		min:          min,
+		max:          max,
+		backoffRatio: 0.9,
+	}
+}
+
+// SetBackoffRatio sets the ratio the limit is multiplied by on the overload, 0.9 by default.
+func (l *AIMDLimit) SetBackoffRatio(ratio float64) *AIMDLimit {
+	l.backoffRatio = ratio
+	return l
+}
+
+// SetLatencyThreshold sets the latency above which a call is handled as dropped, disabled by default.
+func (l *AIMDLimit) SetLatencyThreshold(d time.Duration) *AIMDLimit {
+	l.latencyThreshold = d
+	return l
+}
+
+func (l *AIMDLimit) Limit() int { return clampLimit(l.limit, l.min, l.max) }
+
+func (l *AIMDLimit) OnSample(latency time.Duration, inflight int, dropped bool) {
+	switch {
+	case dropped || (l.latencyThreshold > 0 && latency > l.latencyThreshold):
+		l.limit = max(l.limit*l.backoffRatio, float64(l.min))
+	case float64(inflight)*2 >= l.limit:
+		l.limit = min(l.limit+1, float64(l.max))
+	}
+}
+
+// GradientLimit adjusts the limit to the ratio of the long-term average latency to the latest one:
+// while the latency stays close to the average, the limit grows by its square root, the allowed queue;
+// when the latency rises, the limit shrinks proportionally. Dropped calls halve the limit.
+type GradientLimit struct {
+	limit      float64
+	min        int
+	max        int
+	tolerance  float64
+	smoothing  float64
+	longWindow float64
+	longRTT    float64
+}
+
+func NewGradientLimit(initial, min, max int) *GradientLimit {
+	return &GradientLimit{
+		limit:      float64(initial),
+		min:        min,
+		max:        max,
+		tolerance:  1.5,
+		smoothing:  0.2,
+		longWindow: 600,
+	}
+}
+
+// SetTolerance sets how many times the latency may exceed the average before the limit shrinks, 1.5 by default.
+func (l *GradientLimit) SetTolerance(tolerance float64) *GradientLimit {
+	l.tolerance = tolerance
+	return l
+}
+
+// SetSmoothing sets the weight of a new limit, in (0, 1], 0.2 by default.
+func (l *GradientLimit) SetSmoothing(smoothing float64) *GradientLimit {
+	l.smoothing = smoothing
+	return l
+}
+
+func (l *GradientLimit) Limit() int { return clampLimit(l.limit, l.min, l.max) }
+
+func (l *GradientLimit) OnSample(latency time.Duration, inflight int, dropped bool) {
+	rtt := float64(max(latency, 1))
+	if l.longRTT == 0 {
+		l.longRTT = rtt
+	} else {
+		l.longRTT += (rtt - l.longRTT) / l.longWindow
+	}
+
+	if dropped {
+		l.limit = max(l.limit/2, float64(l.min))
+		return
+	}
+	if float64(inflight)*2 < l.limit {
+		// the limit isn't used, the latency says nothing about it
+		return
+	}
+	gradient := math.Max(0.5, math.Min(1, l.tolerance*l.longRTT/rtt))
+	newLimit := l.limit*gradient + math.Sqrt(l.limit)
+	l.limit = l.limit*(1-l.smoothing) + newLimit*l.smoothing
+	l.limit = math.Max(float64(l.min), math.Min(float64(l.max), l.limit))
+}
+
+func clampLimit(limit float64, minLimit, maxLimit int) int {
+	res := int(limit)
+	if maxLimit > 0 {
+		res = min(res, maxLimit)
+	}
+	return max(res, minLimit, 1)
+}
@@ -0,0 +1,143 @@
+// Package bulkhead limits the number of the concurrent calls, e.g. of the HTTP handlers or of the requests to an upstream.
+// The limit is either fixed or adapted to the observed latency, the calls over it wait in a queue for a while
+// and are shed when the queue is full or the wait is over.
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLimitExceeded is returned when a call is shed.
+var ErrLimitExceeded = errors.New("concurrency limit exceeded")
+
+// Config configures a Limiter:
+//
+// NewLimit creates the limit algorithm. If it's nil, the limit is FixedLimit(DefaultConfig limit).
+//
+// MaxQueue is the maximum number of the calls waiting for a slot, the calls over it are shed at once.
+// If MaxQueue is 0, the calls don't wait.
+//
+// QueueTimeout is the maximum wait for a slot. If it's 0, the calls wait until their context is done.
+//
+// RetryAfter is the Retry-After of the shed HTTP requests. If it's 0, it's 1 second.
+type Config struct {
+	NewLimit     func() Limit
+	MaxQueue     int
+	QueueTimeout time.Duration
+	RetryAfter   time.Duration
+}
+
+const defaultLimit = 100
+
+var DefaultConfig = Config{
+	NewLimit:   func() Limit { return FixedLimit(defaultLimit) },
+	RetryAfter: time.Second,
+}
+
+func prepareConfig(cfg Config) Config {
+	if cfg.NewLimit == nil {
+		cfg.NewLimit = DefaultConfig.NewLimit
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = DefaultConfig.RetryAfter
+	}
+	return cfg
+}
+
+// Limiter limits the number of the concurrent calls. The waiting calls get the slots in FIFO order.
+type Limiter struct {
+	cfg Config
+
+	mtx      sync.Mutex
+	limit    Limit
+	inflight int
+	waiters  []chan struct{}
+}
+
+func NewLimiter(cfg Config) *Limiter {
+	cfg = prepareConfig(cfg)
+	return &Limiter{cfg: cfg, limit: cfg.NewLimit()}
+}
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.limit.Limit()
+}
+
+// Inflight returns the number of the calls holding a slot.
+func (l *Limiter) Inflight() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.inflight
+}
+
+// Acquire takes a slot, waiting in the queue if there is none. It returns ErrLimitExceeded
+// if the queue is full or the queue timeout is over, and the context error if it's done.
+// The release must be called when the call is over, dropped reports whether it failed because of the overload.
+func (l *Limiter) Acquire(ctx context.Context) (release func(dropped bool), err error) {
+	l.mtx.Lock()
+	if len(l.waiters) == 0 && l.inflight < l.limit.Limit() {
+		l.inflight++
+		l.mtx.Unlock()
+		return l.releaseFunc(time.Now()), nil
+	}
+	if len(l.waiters) >= l.cfg.MaxQueue {
+		l.mtx.Unlock()
+		return nil, ErrLimitExceeded
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	l.mtx.Unlock()
+
+	var timeout <-chan time.Time
+	if l.cfg.QueueTimeout > 0 {
+		timer := time.NewTimer(l.cfg.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-ready:
+		return l.releaseFunc(time.Now()), nil
+	case <-timeout:
+		err = ErrLimitExceeded
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for i, w := range l.waiters {
+		if w == ready {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return nil, err
+		}
+	}
+	// the slot has been granted meanwhile
+	return l.releaseFunc(time.Now()), nil
+}
+
+func (l *Limiter) releaseFunc(start time.Time) func(dropped bool) {
+	var once sync.Once
+	return func(dropped bool) {
+		once.Do(func() {
+			l.release(time.Since(start), dropped)
+		})
+	}
+}
+
+func (l *Limiter) release(latency time.Duration, dropped bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.limit.OnSample(latency, l.inflight, dropped)
+	l.inflight--
+	for len(l.waiters) > 0 && l.inflight < l.limit.Limit() {
+		l.inflight++
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+	}
+}
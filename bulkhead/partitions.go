@@ -0,0 +1,37 @@
+package bulkhead
+
+import (
+	"context"
+	"sync"
+)
+
+// Partitions keeps a separate Limiter per key, e.g. per route or per upstream, so an overloaded
+// partition doesn't take the slots of the others. The limiters are created on the first use with the same config
+// and are never evicted, so the keys must come from a bounded set.
+type Partitions struct {
+	cfg Config
+
+	mtx      sync.Mutex
+	limiters map[string]*Limiter
+}
+
+func NewPartitions(cfg Config) *Partitions {
+	return &Partitions{cfg: prepareConfig(cfg), limiters: make(map[string]*Limiter)}
+}
+
+// Get returns the limiter of the partition, creating it if needed.
+func (p *Partitions) Get(key string) *Limiter {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	l, ok := p.limiters[key]
+	if !ok {
+		l = NewLimiter(p.cfg)
+		p.limiters[key] = l
+	}
+	return l
+}
+
+// Acquire takes a slot of the partition, see Limiter.Acquire.
+func (p *Partitions) Acquire(ctx context.Context, key string) (release func(dropped bool), err error) {
+	return p.Get(key).Acquire(ctx)
+}
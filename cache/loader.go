@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bldsoft/gost/log"
+	"golang.org/x/sync/singleflight"
+)
+
+// LoadFunc loads the value missing in the cache from the backend.
+type LoadFunc[T any] func(ctx context.Context) (T, error)
+
+// Loader implements the "get or load" pattern on top of Repository.
+// Concurrent loads of the same key within the process are collapsed into one backend call.
+type Loader[T any] struct {
+	rep   Repository[T]
+	group singleflight.Group
+}
+
+func NewLoader[T any](rep Repository[T]) *Loader[T] {
+	return &Loader[T]{rep: rep}
+}
+
+// GetOrLoad returns the cached value or loads it and stores it in the cache for ttl. 0 ttl means no expiration.
+// The loader gets a context that isn't canceled with ctx, because its result is shared by all waiting callers.
+func (l *Loader[T]) GetOrLoad(ctx context.Context, key string, loader LoadFunc[T], ttl time.Duration) (res T, err error) {
+	if res, err = l.get(ctx, key); err == nil {
+		return res, nil
+	}
+
+	resC := l.group.DoChan(key, func() (any, error) {
+		// the value could be stored by the flight that has just finished
+		if res, err := l.get(ctx, key); err == nil {
+			return res, nil
+		}
+		res, err := loader(context.WithoutCancel(ctx))
+		if err != nil {
+			return res, err
+		}
+		if ttl > 0 {
+			err = l.rep.SetFor(key, res, ttl)
+		} else {
+			err = l.rep.Set(key, res)
+		}
+		if err != nil {
+			log.FromContext(ctx).WarnWithFields(log.Fields{"key": key, "error": err}, "Failed to cache loaded value")
+		}
+		return res, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return res, ctx.Err()
+	case r := <-resC:
+		if r.Err != nil {
+			return res, r.Err
+		}
+		return r.Val.(T), nil
+	}
+}
+
+func (l *Loader[T]) get(ctx context.Context, key string) (T, error) {
+	res, err := l.rep.Get(key)
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
+		log.FromContext(ctx).WarnWithFields(log.Fields{"key": key, "error": err}, "Failed to get cached value")
+	}
+	return res, err
+}
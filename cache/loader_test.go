@@ -0,0 +1,69 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/cache"
+	"github.com/bldsoft/gost/cache/bigcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoaderGetOrLoad(t *testing.T) {
+	rep := cache.Typed[int](bigcache.NewExpiringRepository("{}"))
+	loader := cache.NewLoader[int](rep)
+
+	var calls atomic.Int32
+	load := func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := loader.GetOrLoad(context.Background(), "key", load, time.Minute)
+			assert.NoError(t, err)
+			assert.Equal(t, 42, v)
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, calls.Load())
+
+	v, err := rep.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+
+	_, err = loader.GetOrLoad(context.Background(), "key", load, time.Minute)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+func TestLoaderError(t *testing.T) {
+	rep := cache.Typed[int](bigcache.NewExpiringRepository("{}"))
+	loader := cache.NewLoader[int](rep)
+
+	errLoad := errors.New("load failed")
+	_, err := loader.GetOrLoad(context.Background(), "key", func(ctx context.Context) (int, error) {
+		return 0, errLoad
+	}, time.Minute)
+	assert.ErrorIs(t, err, errLoad)
+	_, err = rep.Get("key")
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = loader.GetOrLoad(ctx, "key", func(ctx context.Context) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 1, nil
+	}, time.Minute)
+	assert.ErrorIs(t, err, context.Canceled)
+}
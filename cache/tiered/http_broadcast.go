@@ -0,0 +1,144 @@
+package tiered
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bldsoft/gost/controller"
+	"github.com/bldsoft/gost/discovery"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// DefaultInvalidationPath is the path the HTTPBroadcast controller is expected to be mounted on.
+	DefaultInvalidationPath = "/cache/invalidate"
+
+	defaultBroadcastTimeout = 5 * time.Second
+)
+
+// HTTPBroadcast is a PubSub that POSTs invalidations to every healthy instance of the service found by discovery.
+// Mount it on every instance at the same path to receive the invalidations.
+// The endpoint drops the local caches, so mount it behind the service auth middleware
+// and set a client that authenticates the requests, see SetClient.
+type HTTPBroadcast struct {
+	controller.BaseController
+
+	discovery   discovery.Discovery
+	serviceName string
+	path        string
+	client      *http.Client
+
+	handlersMtx sync.RWMutex
+	handlers    []func(inv Invalidation)
+}
+
+func NewHTTPBroadcast(d discovery.Discovery, serviceName string) *HTTPBroadcast {
+	return &HTTPBroadcast{
+		discovery:   d,
+		serviceName: serviceName,
+		path:        DefaultInvalidationPath,
+		client:      &http.Client{Timeout: defaultBroadcastTimeout},
+	}
+}
+
+func (b *HTTPBroadcast) SetPath(path string) *HTTPBroadcast {
+	b.path = path
+	return b
+}
+
+// SetClient sets the client the invalidations are sent with, e.g. with a transport adding the service credentials.
+func (b *HTTPBroadcast) SetClient(client *http.Client) *HTTPBroadcast {
+	b.client = client
+	return b
+}
+
+func (b *HTTPBroadcast) Path() string {
+	return b.path
+}
+
+func (b *HTTPBroadcast) Publish(ctx context.Context, inv Invalidation) error {
+	service, err := b.discovery.ServiceByName(ctx, b.serviceName)
+	if err != nil {
+		return fmt.Errorf("cache invalidation: %w", err)
+	}
+	body, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg      sync.WaitGroup
+		errsMtx sync.Mutex
+		errs    []error
+	)
+	for _, instance := range service.Instances {
+		if !instance.Healthy {
+			continue
+		}
+		wg.Add(1)
+		go func(instance discovery.ServiceInstanceInfo) {
+			defer wg.Done()
+			if err := b.send(ctx, instance, body); err != nil {
+				errsMtx.Lock()
+				defer errsMtx.Unlock()
+				errs = append(errs, fmt.Errorf("%s: %w", instance.ID, err))
+			}
+		}(instance)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (b *HTTPBroadcast) send(ctx context.Context, instance discovery.ServiceInstanceInfo, body []byte) error {
+	scheme := instance.Address.Scheme()
+	if scheme == "" {
+		scheme = "http"
+	}
+	url := scheme + "://" + instance.Address.HostPort() + "/" + strings.TrimPrefix(b.path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (b *HTTPBroadcast) Subscribe(handler func(inv Invalidation)) {
+	b.handlersMtx.Lock()
+	defer b.handlersMtx.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *HTTPBroadcast) invalidateHandler(w http.ResponseWriter, r *http.Request) {
+	var inv Invalidation
+	if !b.GetObjectFromBody(w, r, &inv) {
+		return
+	}
+
+	b.handlersMtx.RLock()
+	defer b.handlersMtx.RUnlock()
+	for _, handler := range b.handlers {
+		handler(inv)
+	}
+	b.ResponseOK(w)
+}
+
+// Mount it with r.With(authMiddleware).Route(b.Path(), b.Mount). The endpoint has no auth of its own.
+func (b *HTTPBroadcast) Mount(r chi.Router) {
+	r.Post("/", b.invalidateHandler)
+}
@@ -0,0 +1,23 @@
+package tiered
+
+import "context"
+
+// Invalidation tells the other instances to drop the keys from their local caches.
+type Invalidation struct {
+	Source string   `json:"source"` // id of the repository that published the message
+	Keys   []string `json:"keys,omitempty"`
+	Reset  bool     `json:"reset,omitempty"` // drop all keys
+}
+
+// PubSub delivers invalidations between instances. Publish may deliver the message back to the publisher,
+// the repository ignores its own messages.
+type PubSub interface {
+	Publish(ctx context.Context, inv Invalidation) error
+	Subscribe(handler func(inv Invalidation))
+}
+
+// NopPubSub is used when there is only one instance.
+type NopPubSub struct{}
+
+func (NopPubSub) Publish(ctx context.Context, inv Invalidation) error { return nil }
+func (NopPubSub) Subscribe(handler func(inv Invalidation))            {}
@@ -0,0 +1,231 @@
+// Package tiered provides a two-tier cache: a local (near) cache in front of a distributed (far) one.
+package tiered
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bldsoft/gost/cache"
+	cacheV2 "github.com/bldsoft/gost/cache/v2"
+	"github.com/bldsoft/gost/log"
+)
+
+type WriteMode int
+
+const (
+	// WriteThrough writes the value to both tiers.
+	WriteThrough WriteMode = iota
+	// WriteAround writes the value to the distributed tier only and drops the local copy,
+	// so it's loaded on the next read. Use it for values that are rarely read after a write.
+	WriteAround
+)
+
+const (
+	publishTimeout = 10 * time.Second
+	// publishQueueSize limits the invalidations waiting to be published, the ones over the limit are dropped.
+	publishQueueSize = 1024
+	// DefaultLocalTTL is the local entries lifetime if neither LocalTTL nor the distributed TTL is known.
+	DefaultLocalTTL = 5 * time.Minute
+)
+
+type Config struct {
+	WriteMode WriteMode
+	// LocalTTL limits the local entries lifetime, so the instances don't serve stale data forever
+	// if an invalidation is lost. 0 means the local entries expire with the distributed ones, or after
+	// DefaultLocalTTL if the distributed TTL is unknown, e.g. it's the repository default or memcached is used.
+	LocalTTL time.Duration
+	// DistrTTL is used by Set. 0 means the distributed repository default.
+	DistrTTL time.Duration
+	// NegativeTTL is how long a miss of the distributed tier is cached locally. 0 disables negative caching.
+	NegativeTTL time.Duration
+}
+
+// local entries are prefixed with the entry kind to tell cached misses from values
+const (
+	entryValue byte = iota
+	entryMiss
+)
+
+// Repository is a cache.IExpiringCacheRepository that reads through the local tier to the distributed one.
+// Writes and deletes go to the distributed tier and are published to the other instances via PubSub,
+// so they drop their local copies. The invalidations are published one at a time in the background,
+// the ones queued meanwhile are merged into one message.
+type Repository struct {
+	id     string
+	cfg    Config
+	local  cache.IExpiringCacheRepository
+	distr  cacheV2.IDistrCacheRepository
+	pubsub PubSub
+
+	publishC    chan Invalidation
+	publishOnce sync.Once
+}
+
+// NewRepository creates a two-tier repository. If local isn't a cache.IExpiringCacheRepository,
+// it's wrapped by cache.NewExpiringRepository.
+func NewRepository(local cache.ILocalCacheRepository, distr cacheV2.IDistrCacheRepository, cfg Config) *Repository {
+	expiring, ok := local.(cache.IExpiringCacheRepository)
+	if !ok {
+		expiring = cache.NewExpiringRepository(local)
+	}
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return &Repository{
+		id:       hex.EncodeToString(id),
+		cfg:      cfg,
+		local:    expiring,
+		distr:    distr,
+		pubsub:   NopPubSub{},
+		publishC: make(chan Invalidation, publishQueueSize),
+	}
+}
+
+// SetPubSub sets the channel the invalidations are published to and received from.
+func (r *Repository) SetPubSub(pubsub PubSub) *Repository {
+	r.pubsub = pubsub
+	pubsub.Subscribe(r.onInvalidation)
+	r.publishOnce.Do(func() {
+		go r.runPublisher()
+	})
+	return r
+}
+
+// Get returns the value from the local tier or loads it from the distributed one.
+// cache.ErrCacheMiss is returned if the key is found in neither.
+func (r *Repository) Get(key string) ([]byte, error) {
+	data, err := r.local.Get(key)
+	if err == nil && len(data) > 0 {
+		if data[0] == entryMiss {
+			return nil, cache.ErrCacheMiss
+		}
+		return data[1:], nil
+	}
+
+	item, err := r.distr.Get(key)
+	switch {
+	case errors.Is(err, cacheV2.ErrCacheMiss):
+		if r.cfg.NegativeTTL > 0 {
+			r.setLocal(key, entryMiss, nil, r.cfg.NegativeTTL)
+		}
+		return nil, cache.ErrCacheMiss
+	case err != nil:
+		return nil, err
+	}
+	r.setLocal(key, entryValue, item.Value, r.localTTL(item.TTL))
+	return item.Value, nil
+}
+
+func (r *Repository) Set(key string, value []byte) error {
+	return r.SetFor(key, value, r.cfg.DistrTTL)
+}
+
+// SetFor writes the value with the ttl in the distributed tier. 0 ttl means the distributed repository default.
+func (r *Repository) SetFor(key string, value []byte, ttl time.Duration) error {
+	var opts []cacheV2.ItemF
+	if ttl > 0 {
+		opts = append(opts, cacheV2.WithTTL(ttl))
+	}
+	if err := r.distr.Set(key, value, opts...); err != nil {
+		return err
+	}
+
+	switch r.cfg.WriteMode {
+	case WriteAround:
+		_ = r.local.Delete(key)
+	default:
+		r.setLocal(key, entryValue, value, r.localTTL(ttl))
+	}
+	r.publish(Invalidation{Keys: []string{key}})
+	return nil
+}
+
+// Delete removes the key from both tiers on all instances.
+func (r *Repository) Delete(key string) error {
+	if err := r.distr.Delete(key); err != nil && !errors.Is(err, cacheV2.ErrCacheMiss) {
+		return err
+	}
+	_ = r.local.Delete(key)
+	r.publish(Invalidation{Keys: []string{key}})
+	return nil
+}
+
+// Reset clears the local tier on all instances. The distributed tier isn't affected.
+func (r *Repository) Reset() {
+	r.local.Reset()
+	r.publish(Invalidation{Reset: true})
+}
+
+func (r *Repository) localTTL(distrTTL time.Duration) time.Duration {
+	if distrTTL > 0 && (r.cfg.LocalTTL == 0 || distrTTL < r.cfg.LocalTTL) {
+		return distrTTL
+	}
+	return cmp.Or(r.cfg.LocalTTL, DefaultLocalTTL)
+}
+
+func (r *Repository) setLocal(key string, kind byte, value []byte, ttl time.Duration) {
+	data := append([]byte{kind}, value...)
+	if err := r.local.SetFor(key, data, ttl); err != nil {
+		log.TraceWithFields(log.Fields{"key": key, "error": err}, "tiered cache: failed to set local entry")
+	}
+}
+
+// publish queues the invalidation. If the queue is full, the invalidation is dropped,
+// the other instances drop the stale local entries after LocalTTL.
+func (r *Repository) publish(inv Invalidation) {
+	if _, ok := r.pubsub.(NopPubSub); ok {
+		return
+	}
+	select {
+	case r.publishC <- inv:
+	default:
+		log.ErrorWithFields(log.Fields{"keys": inv.Keys, "reset": inv.Reset}, "tiered cache: invalidation queue is full, invalidation is dropped")
+	}
+}
+
+func (r *Repository) runPublisher() {
+	for inv := range r.publishC {
+		inv = r.mergeQueued(inv)
+		inv.Source = r.id
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		if err := r.pubsub.Publish(ctx, inv); err != nil {
+			log.ErrorWithFields(log.Fields{"keys": inv.Keys, "reset": inv.Reset, "error": err}, "tiered cache: failed to publish invalidation")
+		}
+		cancel()
+	}
+}
+
+// mergeQueued merges the queued invalidations into inv.
+func (r *Repository) mergeQueued(inv Invalidation) Invalidation {
+	for {
+		select {
+		case next := <-r.publishC:
+			inv.Keys = append(inv.Keys, next.Keys...)
+			inv.Reset = inv.Reset || next.Reset
+		default:
+			if inv.Reset {
+				inv.Keys = nil
+			}
+			return inv
+		}
+	}
+}
+
+func (r *Repository) onInvalidation(inv Invalidation) {
+	if inv.Source == r.id {
+		return
+	}
+	if inv.Reset {
+		r.local.Reset()
+		return
+	}
+	for _, key := range inv.Keys {
+		_ = r.local.Delete(key)
+	}
+}
+
+var _ cache.IExpiringCacheRepository = (*Repository)(nil)
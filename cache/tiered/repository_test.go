@@ -0,0 +1,233 @@
+package tiered
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bldsoft/gost/cache"
+	"github.com/bldsoft/gost/cache/v2/redis"
+	"github.com/bldsoft/gost/config"
+	"github.com/bldsoft/gost/discovery"
+	"github.com/bldsoft/gost/discovery/fake"
+	"github.com/bldsoft/gost/server"
+	"github.com/go-chi/chi/v5"
+	goredis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapRepository struct {
+	mtx  sync.Mutex
+	data map[string][]byte
+}
+
+func newMapRepository() *mapRepository {
+	return &mapRepository{data: make(map[string][]byte)}
+}
+
+func (r *mapRepository) Get(key string) ([]byte, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if v, ok := r.data[key]; ok {
+		return v, nil
+	}
+	return nil, cache.ErrCacheMiss
+}
+
+func (r *mapRepository) Set(key string, value []byte) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.data[key] = value
+	return nil
+}
+
+func (r *mapRepository) Delete(key string) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	delete(r.data, key)
+	return nil
+}
+
+func (r *mapRepository) Reset() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	clear(r.data)
+}
+
+func (r *mapRepository) has(key string) bool {
+	_, err := r.Get(key)
+	return err == nil
+}
+
+func newDistr(t *testing.T) (*redis.Repository, *miniredis.Miniredis) {
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return redis.NewRepository(redis.NewStorageFromClient(client, ""), 0), srv
+}
+
+func TestReadThrough(t *testing.T) {
+	distr, srv := newDistr(t)
+	local := newMapRepository()
+	rep := NewRepository(local, distr, Config{NegativeTTL: time.Minute})
+
+	require.NoError(t, distr.Set("key", []byte("value")))
+	v, err := rep.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), v)
+	assert.True(t, local.has("key"))
+
+	// served by the local tier
+	srv.Del("key")
+	v, err = rep.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), v)
+
+	// negative caching
+	_, err = rep.Get("missing")
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+	require.NoError(t, distr.Set("missing", []byte("value")))
+	_, err = rep.Get("missing")
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func TestWriteModes(t *testing.T) {
+	distr, srv := newDistr(t)
+
+	local := newMapRepository()
+	rep := NewRepository(local, distr, Config{WriteMode: WriteThrough, DistrTTL: time.Hour})
+	require.NoError(t, rep.Set("through", []byte("value")))
+	assert.True(t, local.has("through"))
+	assert.Equal(t, time.Hour, srv.TTL("through"))
+
+	local = newMapRepository()
+	rep = NewRepository(local, distr, Config{WriteMode: WriteAround})
+	require.NoError(t, rep.Set("key", []byte("old")))
+	_, _ = rep.Get("key")
+	require.NoError(t, rep.SetFor("key", []byte("new"), time.Minute))
+	assert.False(t, local.has("key"))
+	assert.Equal(t, time.Minute, srv.TTL("key"))
+	v, err := rep.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new"), v)
+
+	require.NoError(t, rep.Delete("key"))
+	assert.False(t, local.has("key"))
+	assert.False(t, srv.Exists("key"))
+	require.NoError(t, rep.Delete("key"))
+}
+
+func TestLocalTTL(t *testing.T) {
+	distr, _ := newDistr(t)
+	rep := NewRepository(newMapRepository(), distr, Config{LocalTTL: time.Hour})
+	assert.Equal(t, time.Hour, rep.localTTL(0))
+	assert.Equal(t, time.Minute, rep.localTTL(time.Minute))
+	assert.Equal(t, time.Hour, rep.localTTL(2*time.Hour))
+
+	rep = NewRepository(newMapRepository(), distr, Config{})
+	assert.Equal(t, DefaultLocalTTL, rep.localTTL(0), "the local entries expire if the distributed TTL is unknown")
+	assert.Equal(t, 2*time.Hour, rep.localTTL(2*time.Hour))
+}
+
+type blockingPubSub struct {
+	release     chan struct{}
+	mtx         sync.Mutex
+	calls       int
+	inflight    int
+	maxInflight int
+	keys        []string
+}
+
+func (p *blockingPubSub) Publish(ctx context.Context, inv Invalidation) error {
+	p.mtx.Lock()
+	p.calls++
+	p.inflight++
+	p.maxInflight = max(p.maxInflight, p.inflight)
+	p.keys = append(p.keys, inv.Keys...)
+	p.mtx.Unlock()
+	<-p.release
+	p.mtx.Lock()
+	p.inflight--
+	p.mtx.Unlock()
+	return nil
+}
+
+func (p *blockingPubSub) Subscribe(handler func(inv Invalidation)) {}
+
+func TestPublishBounded(t *testing.T) {
+	distr, _ := newDistr(t)
+	pubsub := &blockingPubSub{release: make(chan struct{})}
+	rep := NewRepository(newMapRepository(), distr, Config{}).SetPubSub(pubsub)
+
+	const n = 100
+	for i := range n {
+		require.NoError(t, rep.Set(fmt.Sprint(i), []byte("v")))
+	}
+	close(pubsub.release)
+	assert.Eventually(t, func() bool {
+		pubsub.mtx.Lock()
+		defer pubsub.mtx.Unlock()
+		return len(pubsub.keys) == n
+	}, time.Second, 10*time.Millisecond)
+
+	pubsub.mtx.Lock()
+	defer pubsub.mtx.Unlock()
+	assert.Equal(t, 1, pubsub.maxInflight, "published one at a time")
+	assert.Less(t, pubsub.calls, n, "queued invalidations are merged")
+}
+
+func TestHTTPBroadcastInvalidation(t *testing.T) {
+	distr, _ := newDistr(t)
+
+	const serviceName = "svc"
+	d := fake.NewDiscovery(server.Config{ServiceName: serviceName})
+
+	type instance struct {
+		local *mapRepository
+		rep   *Repository
+	}
+	var (
+		instances []instance
+		infos     []discovery.ServiceInstanceInfo
+	)
+	for range 2 {
+		local := newMapRepository()
+		inst := instance{local: local, rep: NewRepository(local, distr, Config{})}
+		peer := NewHTTPBroadcast(d, serviceName)
+		inst.rep.SetPubSub(peer)
+		r := chi.NewRouter()
+		r.Route(peer.Path(), peer.Mount)
+		srv := httptest.NewServer(r)
+		t.Cleanup(srv.Close)
+
+		instances = append(instances, inst)
+		infos = append(infos, discovery.ServiceInstanceInfo{
+			ServiceName: serviceName,
+			ID:          srv.URL,
+			Address:     config.Address(srv.URL),
+			Healthy:     true,
+		})
+	}
+	d.AddService(&discovery.ServiceInfo{Name: serviceName, Instances: infos})
+
+	require.NoError(t, instances[0].rep.Set("key", []byte("v1")))
+	_, err := instances[1].rep.Get("key")
+	require.NoError(t, err)
+	assert.True(t, instances[1].local.has("key"))
+
+	require.NoError(t, instances[0].rep.Set("key", []byte("v2")))
+	assert.Eventually(t, func() bool { return !instances[1].local.has("key") }, time.Second, 10*time.Millisecond)
+	assert.True(t, instances[0].local.has("key"), "own invalidation must be ignored")
+
+	v, err := instances[1].rep.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v2"), v)
+
+	instances[1].rep.Reset()
+	assert.Eventually(t, func() bool { return !instances[0].local.has("key") }, time.Second, 10*time.Millisecond)
+}
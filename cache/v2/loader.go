@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"github.com/bldsoft/gost/log"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	leaseKeySuffix           = ":lease"
+	revalidateKeySuffix      = ":revalidate"
+	defaultLeasePollInterval = 50 * time.Millisecond
+)
+
+type LoaderConfig struct {
+	// LeaseTTL enables the distributed lease: only the instance that added the lease key loads the value,
+	// the others wait for it to appear in the cache. It should exceed the loading time.
+	// 0 disables the lease, so every instance loads the missing value itself.
+	LeaseTTL time.Duration
+	// LeasePollInterval is how often the waiting instances check the cache.
+	LeasePollInterval time.Duration
+	// StaleTTL enables stale-while-revalidate: the value is kept for StaleTTL after its ttl expires
+	// and is returned while it's being reloaded in the background. The values loaded with zero ttl never become stale.
+	// The time the value becomes stale is stored in Item.Flags, so the flags aren't available to the caller.
+	StaleTTL time.Duration
+}
+
+// LoadFunc loads the value missing in the cache from the backend.
+type LoadFunc func(ctx context.Context) ([]byte, error)
+
+// Loader implements the "get or load" pattern on top of IDistrCacheRepository.
+// Concurrent loads of the same key within the process are collapsed into one backend call,
+// the distributed lease and stale-while-revalidate are optional, see LoaderConfig.
+type Loader struct {
+	rep   IDistrCacheRepository
+	cfg   LoaderConfig
+	group singleflight.Group
+}
+
+func NewLoader(rep IDistrCacheRepository, cfg LoaderConfig) *Loader {
+	if cfg.LeasePollInterval <= 0 {
+		cfg.LeasePollInterval = defaultLeasePollInterval
+	}
+	return &Loader{rep: rep, cfg: cfg}
+}
+
+// GetOrLoad returns the cached value or loads it and stores it in the cache for ttl.
+// The loader gets a context that isn't canceled with ctx, because its result is shared by all waiting callers.
+func (l *Loader) GetOrLoad(ctx context.Context, key string, loader LoadFunc, ttl time.Duration) ([]byte, error) {
+	if item, ok := l.get(ctx, key); ok {
+		if l.stale(item) {
+			go l.revalidate(context.WithoutCancel(ctx), key, loader, ttl)
+		}
+		return item.Value, nil
+	}
+
+	resC := l.group.DoChan(key, func() (any, error) {
+		return l.load(context.WithoutCancel(ctx), key, loader, ttl)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-resC:
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return r.Val.([]byte), nil
+	}
+}
+
+func (l *Loader) get(ctx context.Context, key string) (*Item, bool) {
+	item, err := l.rep.Get(key)
+	if err != nil {
+		if !errors.Is(err, ErrCacheMiss) {
+			log.FromContext(ctx).WarnWithFields(log.Fields{"key": key, "error": err}, "Failed to get cached value")
+		}
+		return nil, false
+	}
+	return item, true
+}
+
+func (l *Loader) stale(item *Item) bool {
+	return l.cfg.StaleTTL > 0 && item.Flags != 0 && time.Now().Unix() >= int64(item.Flags)
+}
+
+// load is called once per key at a time within the process.
+func (l *Loader) load(ctx context.Context, key string, loader LoadFunc, ttl time.Duration) ([]byte, error) {
+	for {
+		// the value could be stored by the flight that has just finished or by another instance
+		if item, ok := l.get(ctx, key); ok {
+			return item.Value, nil
+		}
+
+		release, acquired := l.acquireLease(ctx, key)
+		if acquired {
+			defer release()
+			return l.loadAndSet(ctx, key, loader, ttl)
+		}
+		if err := l.waitForValue(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// revalidate reloads the stale value unless it's being reloaded by another instance.
+// It has its own flight, because it returns no value when the lease is taken, so GetOrLoad must not join it.
+func (l *Loader) revalidate(ctx context.Context, key string, loader LoadFunc, ttl time.Duration) {
+	_, _, _ = l.group.Do(key+revalidateKeySuffix, func() (any, error) {
+		release, acquired := l.acquireLease(ctx, key)
+		if !acquired {
+			return nil, nil
+		}
+		defer release()
+		return l.loadAndSet(ctx, key, loader, ttl)
+	})
+}
+
+func (l *Loader) loadAndSet(ctx context.Context, key string, loader LoadFunc, ttl time.Duration) ([]byte, error) {
+	value, err := loader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []ItemF
+	if l.cfg.StaleTTL > 0 && ttl > 0 {
+		opts = append(opts, WithFlags(uint32(time.Now().Add(ttl).Unix())), WithTTL(ttl+l.cfg.StaleTTL))
+	} else if ttl > 0 {
+		opts = append(opts, WithTTL(ttl))
+	}
+	if err := l.rep.Set(key, value, opts...); err != nil {
+		log.FromContext(ctx).WarnWithFields(log.Fields{"key": key, "error": err}, "Failed to cache loaded value")
+	}
+	return value, nil
+}
+
+// acquireLease returns true if the lease is acquired or the lease is disabled.
+// If the distributed cache fails, the value is loaded without the lease.
+func (l *Loader) acquireLease(ctx context.Context, key string) (release func(), acquired bool) {
+	if l.cfg.LeaseTTL <= 0 {
+		return func() {}, true
+	}
+
+	leaseKey := key + leaseKeySuffix
+	token := make([]byte, 8)
+	_, _ = rand.Read(token)
+	err := l.rep.Add(leaseKey, token, WithTTL(l.cfg.LeaseTTL))
+	switch {
+	case errors.Is(err, ErrExists):
+		return nil, false
+	case err != nil:
+		log.FromContext(ctx).WarnWithFields(log.Fields{"key": leaseKey, "error": err}, "Failed to acquire cache lease")
+		return func() {}, true
+	}
+	return func() {
+		// don't delete the lease that expired and was acquired by another instance
+		if item, err := l.rep.Get(leaseKey); err == nil && bytes.Equal(item.Value, token) {
+			_ = l.rep.Delete(leaseKey)
+		}
+	}, true
+}
+
+// waitForValue waits until the value is loaded by the lease holder or the lease is released.
+func (l *Loader) waitForValue(ctx context.Context, key string) error {
+	ticker := time.NewTicker(l.cfg.LeasePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			// Get instead of Exist, because memcached Exist touches the key and prolongs the lease
+			if _, err := l.rep.Get(key); err == nil {
+				return nil
+			}
+			if _, err := l.rep.Get(key + leaseKeySuffix); errors.Is(err, ErrCacheMiss) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package cache_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bldsoft/gost/cache/v2"
+	"github.com/bldsoft/gost/cache/v2/redis"
+	goredis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedisRepository(t *testing.T) (*redis.Repository, *miniredis.Miniredis) {
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return redis.NewRepository(redis.NewStorageFromClient(client, ""), 0), srv
+}
+
+func TestLoaderLease(t *testing.T) {
+	rep, srv := newRedisRepository(t)
+
+	var calls atomic.Int32
+	load := func(ctx context.Context) ([]byte, error) {
+		calls.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		return []byte("value"), nil
+	}
+
+	// different loaders emulate different instances
+	var wg sync.WaitGroup
+	for range 5 {
+		loader := cache.NewLoader(rep, cache.LoaderConfig{LeaseTTL: time.Second, LeasePollInterval: 10 * time.Millisecond})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := loader.GetOrLoad(context.Background(), "key", load, time.Minute)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("value"), v)
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, calls.Load())
+	assert.Equal(t, time.Minute, srv.TTL("key"))
+	assert.False(t, srv.Exists("key:lease"))
+}
+
+func TestLoaderStaleWhileRevalidate(t *testing.T) {
+	rep, srv := newRedisRepository(t)
+	loader := cache.NewLoader(rep, cache.LoaderConfig{LeaseTTL: time.Second, StaleTTL: time.Hour})
+
+	var version atomic.Int32
+	load := func(ctx context.Context) ([]byte, error) {
+		return []byte{byte(version.Add(1))}, nil
+	}
+
+	v, err := loader.GetOrLoad(context.Background(), "key", load, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1}, v)
+	assert.Equal(t, time.Minute+time.Hour, srv.TTL("key"))
+
+	// make the value stale
+	item, err := rep.Get("key")
+	require.NoError(t, err)
+	require.NoError(t, rep.Set("key", item.Value, cache.WithFlags(uint32(time.Now().Add(-time.Second).Unix()))))
+
+	v, err = loader.GetOrLoad(context.Background(), "key", load, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1}, v, "stale value is returned")
+
+	assert.Eventually(t, func() bool {
+		item, err := rep.Get("key")
+		return err == nil && item.Value[0] == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+// leaseBlockingRepository blocks the first lease acquisition until unblock is closed.
+type leaseBlockingRepository struct {
+	cache.IDistrCacheRepository
+	once    sync.Once
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (r *leaseBlockingRepository) Add(key string, val []byte, opts ...cache.ItemF) error {
+	if strings.HasSuffix(key, ":lease") {
+		r.once.Do(func() {
+			close(r.started)
+			<-r.unblock
+		})
+	}
+	return r.IDistrCacheRepository.Add(key, val, opts...)
+}
+
+func TestLoaderRevalidateLeaseLost(t *testing.T) {
+	redisRep, _ := newRedisRepository(t)
+	rep := &leaseBlockingRepository{IDistrCacheRepository: redisRep, started: make(chan struct{}), unblock: make(chan struct{})}
+	loader := cache.NewLoader(rep, cache.LoaderConfig{LeaseTTL: time.Minute, LeasePollInterval: 10 * time.Millisecond, StaleTTL: time.Hour})
+	load := func(ctx context.Context) ([]byte, error) { return []byte("new"), nil }
+
+	require.NoError(t, rep.Set("key", []byte("stale"), cache.WithFlags(uint32(time.Now().Add(-time.Second).Unix()))))
+	// another instance holds the lease
+	require.NoError(t, redisRep.Add("key:lease", []byte("other")))
+
+	v, err := loader.GetOrLoad(context.Background(), "key", load, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("stale"), v)
+	<-rep.started
+
+	// the value expires while the revalidation is in flight
+	require.NoError(t, rep.Delete("key"))
+	res := make(chan []byte)
+	go func() {
+		v, err := loader.GetOrLoad(context.Background(), "key", load, time.Minute)
+		assert.NoError(t, err)
+		res <- v
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(rep.unblock) // the revalidation doesn't get the lease
+
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, rep.Delete("key:lease"))
+	select {
+	case v := <-res:
+		assert.Equal(t, []byte("new"), v)
+	case <-time.After(time.Second):
+		t.Fatal("GetOrLoad didn't load the value")
+	}
+}
+
+func TestLoaderZeroTTLIsNeverStale(t *testing.T) {
+	rep, _ := newRedisRepository(t)
+	loader := cache.NewLoader(rep, cache.LoaderConfig{StaleTTL: time.Hour})
+	_, err := loader.GetOrLoad(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+		return []byte("value"), nil
+	}, 0)
+	require.NoError(t, err)
+	item, err := rep.Get("key")
+	require.NoError(t, err)
+	assert.Zero(t, item.Flags)
+}
@@ -84,7 +84,7 @@ func (r *MemcacheRepository) CompareAndSwap(key string, handler func(value *cach
 		item, err := r.cache.Get(key)
 
 		if err != nil || item == nil {
-			return err
+			return r.mapError(err)
 		}
 
 		data, err := handler(&cache.Item{
@@ -100,13 +100,18 @@ func (r *MemcacheRepository) CompareAndSwap(key string, handler func(value *cach
 		if data.Flags != 0 {
 			item.Flags = data.Flags
 		}
+		item.Expiration = truncExpiration(r.liveTime)
+		if data.TTL != 0 {
+			item.Expiration = truncExpiration(data.TTL)
+		}
 		err = r.cache.CompareAndSwap(item)
 
 		switch err {
 		case memcache.ErrCASConflict:
 			time.Sleep(casSleepTime * time.Millisecond)
 		default:
-			return err
+			// the item is deleted or expired after Get
+			return r.mapError(err)
 		}
 	}
 
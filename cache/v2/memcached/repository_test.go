@@ -0,0 +1,166 @@
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/cache/v2"
+	"github.com/bldsoft/gost/ratelimit"
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeItem struct {
+	value []byte
+	flags string
+	exp   string
+	cas   uint64
+}
+
+// fakeServer implements the part of the memcached text protocol used by the repository.
+type fakeServer struct {
+	mtx   sync.Mutex
+	items map[string]fakeItem
+	cas   uint64
+}
+
+func newFakeRepository(t *testing.T) (*MemcacheRepository, *fakeServer) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	srv := &fakeServer{items: make(map[string]fakeItem)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return NewMemcacheRepository(&Storage{Client: memcache.New(ln.Addr().String())}, time.Hour), srv
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		var resp string
+		switch args[0] {
+		case "gets", "get":
+			resp = s.get(args[1:])
+		case "set", "add", "cas":
+			size, _ := strconv.Atoi(args[4])
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return
+			}
+			resp = s.store(args, data[:size])
+		case "delete":
+			resp = s.delete(args[1])
+		default:
+			resp = "ERROR\r\n"
+		}
+		if _, err := io.WriteString(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) get(keys []string) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var res strings.Builder
+	for _, key := range keys {
+		if it, ok := s.items[key]; ok {
+			fmt.Fprintf(&res, "VALUE %s %s %d %d\r\n%s\r\n", key, it.flags, len(it.value), it.cas, it.value)
+		}
+	}
+	return res.String() + "END\r\n"
+}
+
+func (s *fakeServer) store(args []string, value []byte) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	key := args[1]
+	cur, exists := s.items[key]
+	switch args[0] {
+	case "add":
+		if exists {
+			return "NOT_STORED\r\n"
+		}
+	case "cas":
+		if !exists {
+			return "NOT_FOUND\r\n"
+		}
+		if strconv.FormatUint(cur.cas, 10) != args[5] {
+			return "EXISTS\r\n"
+		}
+	}
+	s.cas++
+	s.items[key] = fakeItem{value: value, flags: args[2], exp: args[3], cas: s.cas}
+	return "STORED\r\n"
+}
+
+func (s *fakeServer) delete(key string) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.items[key]; !ok {
+		return "NOT_FOUND\r\n"
+	}
+	delete(s.items, key)
+	return "DELETED\r\n"
+}
+
+func (s *fakeServer) expiration(key string) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.items[key].exp
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	rep, srv := newFakeRepository(t)
+	handler := func(item *cache.Item) (*cache.Item, error) {
+		return &cache.Item{Value: append(item.Value, '!'), TTL: time.Minute}, nil
+	}
+
+	err := rep.CompareAndSwap("key", handler)
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	require.NoError(t, rep.Add("key", []byte("value")))
+	require.NoError(t, rep.CompareAndSwap("key", handler))
+	item, err := rep.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value!"), item.Value)
+	assert.Equal(t, "60", srv.expiration("key"), "the ttl of the swapped item is set")
+}
+
+func TestRateLimiter(t *testing.T) {
+	rep, _ := newFakeRepository(t)
+	alg := ratelimit.TokenBucket{Burst: 1, Interval: time.Minute}
+	l, other := ratelimit.NewLimiter(alg).SetCache(rep), ratelimit.NewLimiter(alg).SetCache(rep)
+
+	res, err := l.Allow(context.Background(), "key")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	res, err = other.Allow(context.Background(), "key")
+	require.NoError(t, err)
+	assert.False(t, res.Allowed, "the limit is shared")
+}
@@ -32,6 +32,8 @@ const (
 
 // NewDistrMutex creates an implementation of distributed lock.
 // If the gouritine locks m and then finishes running without calling Unlock(), m unlocks after unlockTime.
+//
+// Deprecated: use distlock.NewMutex with distlock.NewCacheBackend, it detects an evicted lock and provides fencing tokens.
 func NewDistrMutex(cache IDistrCacheRepository, lockKey string, unlockTime time.Duration) *DistrMutex {
 	uniqueID := make([]byte, 4)
 	rand.Read(uniqueID)
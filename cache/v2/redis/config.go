@@ -0,0 +1,21 @@
+package redis
+
+type Config struct {
+	Addrs      []string `mapstructure:"ADDRS" description:"single address or seed list of cluster/sentinel nodes"`
+	MasterName string   `mapstructure:"MASTER_NAME" description:"sentinel master name"`
+	Password   string   `mapstructure:"PASSWORD"`
+	DB         int      `mapstructure:"DB"`
+	TimeoutMs  int      `mapstructure:"TIMEOUT_MS"`
+	PoolSize   int      `mapstructure:"POOL_SIZE"`
+	KeyPrefix  string   `mapstructure:"KEY_PREFIX"`
+}
+
+// SetDefaults ...
+func (c *Config) SetDefaults() {
+	c.Addrs = []string{"127.0.0.1:6379"}
+}
+
+// Validate ...
+func (c *Config) Validate() error {
+	return nil
+}
@@ -0,0 +1,241 @@
+package redis
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bldsoft/gost/cache/v2"
+	"github.com/bldsoft/gost/log"
+	"github.com/go-redis/redis"
+)
+
+const (
+	casRetryLimit = 5
+	casSleepTime  = 10
+
+	flagsSize = 4
+)
+
+// Repository stores items as strings: big-endian flags followed by the value.
+type Repository struct {
+	cache    *Storage
+	liveTime time.Duration
+}
+
+func NewRepository(storage *Storage, liveTime time.Duration) *Repository {
+	rep := &Repository{cache: storage}
+	rep.SetLiveTimeMin(liveTime)
+	return rep
+}
+
+// SetLiveTimeMin sets the default TTL. Zero means items don't expire.
+func (r *Repository) SetLiveTimeMin(liveTime time.Duration) {
+	r.liveTime = liveTime
+}
+
+func (r *Repository) Get(key string) (*cache.Item, error) {
+	var (
+		get  *redis.StringCmd
+		pttl *redis.DurationCmd
+	)
+	_, err := r.cache.Pipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(r.cache.PrepareKey(key))
+		pttl = pipe.PTTL(r.cache.PrepareKey(key))
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	return r.itemFromCmds(get, pttl)
+}
+
+// getWatched reads the item without a pipeline, because a pipeline in a transaction executes MULTI/EXEC and resets WATCH.
+func (r *Repository) getWatched(tx *redis.Tx, key string) (*cache.Item, error) {
+	get := tx.Get(key)
+	if get.Err() != nil {
+		return nil, r.mapError(get.Err())
+	}
+	return r.itemFromCmds(get, tx.PTTL(key))
+}
+
+func (r *Repository) itemFromCmds(get *redis.StringCmd, pttl *redis.DurationCmd) (*cache.Item, error) {
+	data, err := get.Bytes()
+	if err != nil {
+		return nil, r.mapError(err)
+	}
+	item, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if ttl := pttl.Val(); ttl > 0 {
+		item.TTL = ttl
+	}
+	return item, nil
+}
+
+func (r *Repository) Exist(key string) bool {
+	n, err := r.cache.Exists(r.cache.PrepareKey(key)).Result()
+	return err == nil && n > 0
+}
+
+// Set writes the given item, unconditionally.
+func (r *Repository) Set(key string, val []byte, opts ...cache.ItemF) error {
+	it := r.item(val, opts...)
+	return r.cache.Set(r.cache.PrepareKey(key), encode(it), it.TTL).Err()
+}
+
+// Add writes the given item if the key doesn't exist. Otherwise cache.ErrExists is returned.
+func (r *Repository) Add(key string, val []byte, opts ...cache.ItemF) error {
+	it := r.item(val, opts...)
+	added, err := r.cache.SetNX(r.cache.PrepareKey(key), encode(it), it.TTL).Result()
+	if err != nil {
+		return err
+	}
+	if !added {
+		return cache.ErrExists
+	}
+	return nil
+}
+
+func (r *Repository) AddOrGet(key string, val []byte, opts ...cache.ItemF) (*cache.Item, bool, error) {
+	for i := 0; i < casRetryLimit; i++ {
+		err := r.Add(key, val, opts...)
+		if !errors.Is(err, cache.ErrExists) {
+			return nil, err == nil, err
+		}
+		item, err := r.Get(key)
+		if errors.Is(err, cache.ErrCacheMiss) {
+			// expired or deleted between Add and Get
+			continue
+		}
+		return item, false, err
+	}
+	return nil, false, fmt.Errorf("redis: AddOrGet retry limit exceeded")
+}
+
+// Delete deletes the item with the provided key.
+func (r *Repository) Delete(key string) error {
+	n, err := r.cache.Del(r.cache.PrepareKey(key)).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return cache.ErrCacheMiss
+	}
+	return nil
+}
+
+// CompareAndSwap watches the key, passes the current item to the handler and writes the result in a transaction.
+// The transaction is retried if the key is modified concurrently.
+// The remaining TTL is kept unless the handler sets a new one, zero flags keep the current flags.
+func (r *Repository) CompareAndSwap(key string, handler func(value *cache.Item) (*cache.Item, error), sleepDur ...time.Duration) error {
+	sleep := casSleepTime * time.Millisecond
+	if len(sleepDur) > 0 {
+		sleep = sleepDur[0]
+	}
+	key = r.cache.PrepareKey(key)
+
+	for i := 0; i < casRetryLimit; i++ {
+		err := r.cache.Watch(func(tx *redis.Tx) error {
+			item, err := r.getWatched(tx, key)
+			if err != nil {
+				return err
+			}
+
+			data, err := handler(&cache.Item{
+				Value: item.Value,
+				TTL:   item.TTL,
+				Flags: item.Flags,
+			})
+			if err != nil || data == nil {
+				return err
+			}
+
+			if data.Flags != 0 {
+				item.Flags = data.Flags
+			}
+			if data.TTL != 0 {
+				item.TTL = data.TTL
+			}
+			item.Value = data.Value
+			_, err = tx.TxPipelined(func(pipe redis.Pipeliner) error {
+				pipe.Set(key, encode(item), item.TTL)
+				return nil
+			})
+			return err
+		}, key)
+
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+		time.Sleep(sleep)
+	}
+
+	return fmt.Errorf("redis: CAS retry limit exceeded")
+}
+
+// Reset deletes all keys with the storage key prefix, or the whole database if the prefix is empty.
+func (r *Repository) Reset() {
+	if len(r.cache.keyPrefix) == 0 {
+		if err := r.cache.FlushDB().Err(); err != nil {
+			log.WarnWithFields(log.Fields{"err": err}, "failed to reset redis cache")
+		}
+		return
+	}
+
+	var cursor uint64
+	for {
+		keys, next, err := r.cache.Scan(cursor, r.cache.keyPrefix+"*", 1000).Result()
+		if err == nil && len(keys) > 0 {
+			err = r.cache.Del(keys...).Err()
+		}
+		if err != nil {
+			log.WarnWithFields(log.Fields{"err": err}, "failed to reset redis cache")
+			return
+		}
+		if cursor = next; cursor == 0 {
+			return
+		}
+	}
+}
+
+func (r *Repository) item(val []byte, opts ...cache.ItemF) *cache.Item {
+	it := cache.CollectItem(opts...)
+	it.Value = val
+	if it.TTL == 0 {
+		it.TTL = r.liveTime
+	}
+	return it
+}
+
+func (r *Repository) mapError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, redis.Nil):
+		return cache.ErrCacheMiss
+	default:
+		return err
+	}
+}
+
+func encode(it *cache.Item) []byte {
+	data := make([]byte, flagsSize+len(it.Value))
+	binary.BigEndian.PutUint32(data, it.Flags)
+	copy(data[flagsSize:], it.Value)
+	return data
+}
+
+func decode(data []byte) (*cache.Item, error) {
+	if len(data) < flagsSize {
+		return nil, fmt.Errorf("redis: malformed cache item of %d bytes", len(data))
+	}
+	return &cache.Item{
+		Flags: binary.BigEndian.Uint32(data),
+		Value: data[flagsSize:],
+	}, nil
+}
+
+var _ cache.IDistrCacheRepository = (*Repository)(nil)
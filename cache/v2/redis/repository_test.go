@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bldsoft/gost/cache/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T) (*Repository, *miniredis.Miniredis) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRepository(NewStorageFromClient(client, "test:"), time.Minute), srv
+}
+
+func TestSetGet(t *testing.T) {
+	rep, srv := newTestRepository(t)
+
+	_, err := rep.Get("key")
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+	assert.False(t, rep.Exist("key"))
+
+	require.NoError(t, rep.Set("key", []byte("value"), cache.WithFlags(7), cache.WithTTL(time.Hour)))
+	assert.True(t, srv.Exists("test:key"))
+	assert.True(t, rep.Exist("key"))
+
+	item, err := rep.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), item.Value)
+	assert.Equal(t, uint32(7), item.Flags)
+	assert.Equal(t, time.Hour, item.TTL)
+
+	require.NoError(t, rep.Set("default-ttl", nil))
+	assert.Equal(t, time.Minute, srv.TTL("test:default-ttl"))
+
+	srv.FastForward(2 * time.Hour)
+	_, err = rep.Get("key")
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	require.NoError(t, rep.Set("key", []byte("value")))
+	require.NoError(t, rep.Delete("key"))
+	assert.ErrorIs(t, rep.Delete("key"), cache.ErrCacheMiss)
+}
+
+func TestAdd(t *testing.T) {
+	rep, _ := newTestRepository(t)
+
+	require.NoError(t, rep.Add("key", []byte("first")))
+	assert.ErrorIs(t, rep.Add("key", []byte("second")), cache.ErrExists)
+
+	item, added, err := rep.AddOrGet("key", []byte("third"))
+	require.NoError(t, err)
+	assert.False(t, added)
+	assert.Equal(t, []byte("first"), item.Value)
+
+	item, added, err = rep.AddOrGet("other", []byte("value"), cache.WithFlags(1))
+	require.NoError(t, err)
+	assert.True(t, added)
+	assert.Nil(t, item)
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	rep, srv := newTestRepository(t)
+
+	err := rep.CompareAndSwap("counter", func(value *cache.Item) (*cache.Item, error) {
+		return value, nil
+	})
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	require.NoError(t, rep.Set("counter", []byte{0}, cache.WithFlags(3), cache.WithTTL(time.Hour)))
+
+	const n = 20
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := rep.CompareAndSwap("counter", func(value *cache.Item) (*cache.Item, error) {
+					return &cache.Item{Value: []byte{value.Value[0] + 1}}, nil
+				}, time.Millisecond)
+				if err == nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	item, err := rep.Get("counter")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{n}, item.Value)
+	assert.Equal(t, uint32(3), item.Flags)
+	assert.Equal(t, time.Hour, srv.TTL("test:counter"))
+}
+
+func TestMutex(t *testing.T) {
+	rep, _ := newTestRepository(t)
+
+	m1 := cache.NewDistrMutex(rep, "lock", time.Minute)
+	m2 := cache.NewDistrMutex(rep, "lock", time.Minute)
+	require.True(t, m1.TryLock())
+	assert.False(t, m2.TryLock())
+	m1.Unlock()
+	assert.True(t, m2.TryLock())
+	m2.Unlock()
+}
+
+func TestReset(t *testing.T) {
+	rep, srv := newTestRepository(t)
+	require.NoError(t, srv.Set("other:key", "value"))
+	require.NoError(t, rep.Set("a", nil))
+	require.NoError(t, rep.Set("b", nil))
+
+	rep.Reset()
+	assert.False(t, rep.Exist("a"))
+	assert.False(t, rep.Exist("b"))
+	assert.True(t, srv.Exists("other:key"))
+}
+
+func TestStats(t *testing.T) {
+	rep, _ := newTestRepository(t)
+	_, err := rep.cache.Stats(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"redis_version": "7.0.0", "used_memory": "1024"},
+		parseInfo("# Server\r\nredis_version:7.0.0\r\n\r\n# Memory\r\nused_memory:1024\r\n"))
+}
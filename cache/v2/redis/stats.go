@@ -0,0 +1,25 @@
+package redis
+
+type Stats struct {
+	Version          string `json:"version" mapstructure:"redis_version"`
+	Mode             string `json:"mode" mapstructure:"redis_mode"`
+	Uptime           uint64 `json:"uptime" mapstructure:"uptime_in_seconds"`
+	ConnectedClients uint64 `json:"connectedClients" mapstructure:"connected_clients"`
+	BlockedClients   uint64 `json:"blockedClients" mapstructure:"blocked_clients"`
+
+	UsedMemory      uint64 `json:"usedMemory" mapstructure:"used_memory"`
+	UsedMemoryPeak  uint64 `json:"usedMemoryPeak" mapstructure:"used_memory_peak"`
+	MaxMemory       uint64 `json:"maxMemory" mapstructure:"maxmemory"`
+	MaxMemoryPolicy string `json:"maxMemoryPolicy" mapstructure:"maxmemory_policy"`
+
+	TotalConnections uint64 `json:"totalConnections" mapstructure:"total_connections_received"`
+	TotalCommands    uint64 `json:"totalCommands" mapstructure:"total_commands_processed"`
+	RejectedConns    uint64 `json:"rejectedConnections" mapstructure:"rejected_connections"`
+
+	Hits        uint64 `json:"hits" mapstructure:"keyspace_hits"`
+	Misses      uint64 `json:"misses" mapstructure:"keyspace_misses"`
+	ExpiredKeys uint64 `json:"expiredKeys" mapstructure:"expired_keys"`
+	EvictedKeys uint64 `json:"evictedKeys" mapstructure:"evicted_keys"`
+
+	Role string `json:"role" mapstructure:"role"`
+}
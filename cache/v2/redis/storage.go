@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/mitchellh/mapstructure"
+)
+
+type Storage struct {
+	redis.UniversalClient
+	keyPrefix string
+}
+
+func NewStorage(cfg Config) (*Storage, error) {
+	opts := &redis.UniversalOptions{
+		Addrs:      cfg.Addrs,
+		MasterName: cfg.MasterName,
+		Password:   cfg.Password,
+		DB:         cfg.DB,
+		PoolSize:   cfg.PoolSize,
+	}
+	if cfg.TimeoutMs != 0 {
+		timeout := time.Duration(cfg.TimeoutMs) * time.Millisecond
+		opts.DialTimeout = timeout
+		opts.ReadTimeout = timeout
+		opts.WriteTimeout = timeout
+	}
+	client := redis.NewUniversalClient(opts)
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redis connection failed: %w", err)
+	}
+	return NewStorageFromClient(client, cfg.KeyPrefix), nil
+}
+
+// NewStorageFromClient wraps the existing client, e.g. the one used by auth/store.RedisSessionStore.
+func NewStorageFromClient(client redis.UniversalClient, keyPrefix string) *Storage {
+	return &Storage{UniversalClient: client, keyPrefix: keyPrefix}
+}
+
+func (s *Storage) Stats(ctx context.Context) (*Stats, error) {
+	info, err := s.Info().Result()
+	if err != nil {
+		return nil, err
+	}
+	var stats Stats
+	if err := mapstructure.WeakDecode(parseInfo(info), &stats); err != nil {
+		return nil, fmt.Errorf("failed to decode redis stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// parseInfo parses the "key:value" lines of the INFO command output, skipping the section headers.
+func parseInfo(info string) map[string]string {
+	res := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			res[key] = value
+		}
+	}
+	return res
+}
+
+func (s *Storage) PrepareKey(key string) string {
+	if len(s.keyPrefix) == 0 {
+		return key
+	}
+	return s.keyPrefix + key
+}
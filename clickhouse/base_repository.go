@@ -9,6 +9,7 @@ import (
 	sq "github.com/Masterminds/squirrel"
 	"github.com/bldsoft/gost/entity/stat"
 	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/tracing"
 )
 
 const (
@@ -32,6 +33,44 @@ func (r *BaseRepository) RunSelect(ctx context.Context, query sq.SelectBuilder)
 	return query.RunWith(r.Storage().Db).QueryContext(ctx)
 }
 
+// TracedRows are the rows of RunSelectTraced. The query span ends when the rows are closed, so they must be closed.
+type TracedRows struct {
+	*sql.Rows
+	span *tracing.Span
+}
+
+func (r *TracedRows) Close() error {
+	defer r.span.End()
+	r.span.RecordError(r.Rows.Err())
+	return r.Rows.Close()
+}
+
+// RunSelectTraced is RunSelect recording the query as a child span if ctx is traced.
+// The span covers reading the rows and ends when they are closed.
+func (r *BaseRepository) RunSelectTraced(ctx context.Context, query sq.SelectBuilder) (*TracedRows, error) {
+	r.LogQuery(ctx, query)
+	ctx, span := r.startSpan(ctx, query)
+	rows, err := query.RunWith(r.Storage().Db).QueryContext(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	return &TracedRows{Rows: rows, span: span}, nil
+}
+
+// startSpan creates a child span for the query if ctx is traced.
+func (r *BaseRepository) startSpan(ctx context.Context, query sq.SelectBuilder) (context.Context, *tracing.Span) {
+	if tracing.SpanFromContext(ctx) == nil {
+		return ctx, nil
+	}
+	str, _, _ := query.ToSql()
+	return tracing.Start(ctx, "clickhouse.select", tracing.WithKind(tracing.SpanKindClient), tracing.WithAttributes(map[string]any{
+		"db.system":    "clickhouse",
+		"db.statement": str,
+	}))
+}
+
 func (r *BaseRepository) LogQuery(ctx context.Context, query sq.SelectBuilder) {
 	str, params, _ := query.ToSql()
 	log.FromContext(ctx).TracefWithFields(log.Fields{"params": params}, "Query: %s", str)
@@ -61,7 +100,7 @@ func (r *BaseRepository) getCustomChartValues(ctx context.Context, query sq.Sele
 	}
 
 	data := &stat.SeriesData{}
-	rows, err := r.RunSelect(ctx, query)
+	rows, err := r.RunSelectTraced(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -318,7 +318,7 @@ func (e *ClickHouseLogExporter) Logs(
 		Offset(uint64(params.Offset)).
 		Limit(uint64(params.Limit))
 
-	rows, err := e.RunSelect(ctx, query)
+	rows, err := e.RunSelectTraced(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -396,7 +396,7 @@ func (e *ClickHouseLogExporter) distinctValues(
 		From(e.config.TableName).
 		Where(e.filter(&filter)).
 		OrderBy(column)
-	rows, err := e.RunSelect(ctx, query)
+	rows, err := e.RunSelectTraced(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -430,7 +430,7 @@ func (e *ClickHouseLogExporter) RequestIDs(
 		if limit != nil {
 			query = query.Limit(uint64(*limit))
 		}
-		rows, err := e.RunSelect(ctx, query)
+		rows, err := e.RunSelectTraced(ctx, query)
 		if err != nil {
 			return err
 		}
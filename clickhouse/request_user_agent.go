@@ -0,0 +1,24 @@
+package clickhouse
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bldsoft/gost/log"
+)
+
+// UserAgentColumnsMigration returns the migration adding the log.UserAgentInfo columns to the request log table.
+// Use it with Storage.AddMigration if the exported request info contains log.UserAgentInfo.
+func UserAgentColumnsMigration(table string) (up, down string) {
+	columns := columnNamesFromType(reflect.TypeOf(log.UserAgentInfo{}))
+	add := make([]string, 0, len(columns))
+	drop := make([]string, 0, len(columns))
+	for _, column := range columns {
+		add = append(add, fmt.Sprintf("ADD COLUMN IF NOT EXISTS %s LowCardinality(String)", column))
+		drop = append(drop, fmt.Sprintf("DROP COLUMN IF EXISTS %s", column))
+	}
+	up = fmt.Sprintf("ALTER TABLE %s %s", table, strings.Join(add, ", "))
+	down = fmt.Sprintf("ALTER TABLE %s %s", table, strings.Join(drop, ", "))
+	return up, down
+}
@@ -2,8 +2,10 @@ package consul
 
 import (
 	"context"
+	"maps"
 	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/bldsoft/gost/config"
@@ -28,6 +30,10 @@ type Discovery struct {
 	cfg          Config
 	consulClient *api.Client
 	server.AsyncRunner
+
+	// metaMtx guards the metadata updated at runtime
+	metaMtx    sync.Mutex
+	registered bool
 }
 
 func (d *Discovery) ApiClient() *api.Client {
@@ -76,6 +82,21 @@ func (d *Discovery) initClient() (err error) {
 	return err
 }
 
+// UpdateMetadata sets the metadata and re-registers the service if it's already registered.
+func (d *Discovery) UpdateMetadata(ctx context.Context, key, value string) error {
+	d.metaMtx.Lock()
+	meta := maps.Clone(d.base.ServiceInfo.Meta)
+	meta[key] = value
+	d.base.ServiceInfo.Meta = meta
+	registered := d.registered
+	d.metaMtx.Unlock()
+
+	if !registered {
+		return nil
+	}
+	return d.Register()
+}
+
 func (d *Discovery) Register() error {
 	check := &api.AgentServiceCheck{
 		TTL:     d.cfg.HealthCheckTTL.String(),
@@ -86,6 +107,8 @@ func (d *Discovery) Register() error {
 		check.DeregisterCriticalServiceAfter = d.cfg.DeregisterTTL.String()
 	}
 
+	d.metaMtx.Lock()
+	defer d.metaMtx.Unlock()
 	reg := &api.AgentServiceRegistration{
 		ID:      d.base.ServiceInfo.ID,
 		Name:    d.base.ServiceInfo.ServiceName,
@@ -95,10 +118,17 @@ func (d *Discovery) Register() error {
 		Meta:    d.base.ServiceInfo.Meta,
 	}
 
-	return d.consulClient.Agent().ServiceRegister(reg)
+	if err := d.consulClient.Agent().ServiceRegister(reg); err != nil {
+		return err
+	}
+	d.registered = true
+	return nil
 }
 
 func (d *Discovery) Deregister() error {
+	d.metaMtx.Lock()
+	d.registered = false
+	d.metaMtx.Unlock()
 	return d.consulClient.Agent().ServiceDeregister(d.base.ServiceInfo.ID)
 }
 
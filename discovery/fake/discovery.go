@@ -2,6 +2,8 @@ package fake
 
 import (
 	"context"
+	"maps"
+	"slices"
 	"sort"
 	"sync"
 
@@ -36,6 +38,25 @@ func (d *Discovery) AddService(serviceInfo *discovery.ServiceInfo) {
 	}
 }
 
+// UpdateMetadata sets the metadata of the own instance, the change is visible through Services and ServiceByName.
+func (d *Discovery) UpdateMetadata(ctx context.Context, key, value string) error {
+	d.servicesMtx.Lock()
+	defer d.servicesMtx.Unlock()
+	meta := maps.Clone(d.ServiceInfo.Meta)
+	meta[key] = value
+	d.ServiceInfo.Meta = meta
+	if s, ok := d.services[d.ServiceInfo.ServiceName]; ok {
+		instances := slices.Clone(s.Instances)
+		for i := range instances {
+			if instances[i].ID == d.ServiceInfo.ID {
+				instances[i].Meta = meta
+			}
+		}
+		d.services[s.Name] = &discovery.ServiceInfo{Name: s.Name, Instances: instances}
+	}
+	return nil
+}
+
 func (d *Discovery) Services(ctx context.Context) ([]*discovery.ServiceInfo, error) {
 	d.servicesMtx.RLock()
 	defer d.servicesMtx.RUnlock()
@@ -59,4 +80,7 @@ func (d *Discovery) ServiceByName(ctx context.Context, name string) (*discovery.
 	return s, nil
 }
 
-var _ discovery.NotifyingDiscovery = &Discovery{}
+var (
+	_ discovery.NotifyingDiscovery = &Discovery{}
+	_ discovery.MetadataUpdater    = &Discovery{}
+)
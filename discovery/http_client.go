@@ -2,20 +2,32 @@ package discovery
 
 import (
 	"net/http"
+
+	"github.com/bldsoft/gost/retry"
+	"github.com/bldsoft/gost/tracing"
 )
 
 func NewHttpClient(d Discovery, sticky ...bool) *http.Client {
 	client := *http.DefaultClient
-	client.Transport = newTransport(d, sticky...)
+	client.Transport = tracing.NewTransport(newTransport(d, false, sticky...))
+	return &client
+}
+
+// NewHttpClientWithRetry is NewHttpClient retrying the requests according to the policy.
+// Each attempt resolves the service again, so a retry can go to another instance.
+// The attempts go to a single instance, so the instances aren't tried in turn on top of the policy.
+func NewHttpClientWithRetry(d Discovery, policy retry.Policy, sticky ...bool) *http.Client {
+	client := *http.DefaultClient
+	client.Transport = tracing.NewTransport(retry.NewTransport(newTransport(d, true, sticky...), policy))
 	return &client
 }
 
-func newTransport(d Discovery, sticky ...bool) http.RoundTripper {
+func newTransport(d Discovery, singleAttempt bool, sticky ...bool) http.RoundTripper {
 	if len(sticky) > 0 && sticky[0] {
 		return &http.Transport{
 			Proxy:       http.ProxyFromEnvironment,
 			DialContext: DefaultDialer(d).DialContext,
 		}
 	}
-	return NewTransport(http.DefaultTransport, d)
+	return &transport{base: http.DefaultTransport, resolver: NewResolver(d), singleAttempt: singleAttempt}
 }
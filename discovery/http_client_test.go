@@ -0,0 +1,40 @@
+package discovery_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bldsoft/gost/config"
+	"github.com/bldsoft/gost/discovery"
+	"github.com/bldsoft/gost/discovery/fake"
+	"github.com/bldsoft/gost/retry"
+	"github.com/bldsoft/gost/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpClientWithRetryAttempts(t *testing.T) {
+	var calls atomic.Int32
+	d := fake.NewDiscovery(server.Config{ServiceName: "client"})
+	var instances []discovery.ServiceInstanceInfo
+	for range 3 {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			panic(http.ErrAbortHandler) // the connection is closed, the client gets an error
+		}))
+		defer srv.Close()
+		instances = append(instances, discovery.ServiceInstanceInfo{
+			ServiceName: "svc",
+			Address:     config.Address(strings.TrimPrefix(srv.URL, "http://")),
+		})
+	}
+	d.AddService(&discovery.ServiceInfo{Name: "svc", Instances: instances})
+
+	client := discovery.NewHttpClientWithRetry(d, retry.Policy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+	_, err := client.Get("http://svc/")
+	assert.Error(t, err)
+	assert.EqualValues(t, 2, calls.Load(), "the attempts of the policy only")
+}
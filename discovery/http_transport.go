@@ -13,9 +13,12 @@ func NewTransport(t http.RoundTripper, d Discovery) http.RoundTripper {
 	}
 }
 
+// transport tries the idempotent requests on the service instances in turn until one responds.
+// With singleAttempt it sends the request to the first instance only, the caller retries it.
 type transport struct {
-	base     http.RoundTripper
-	resolver *Resolver
+	base          http.RoundTripper
+	resolver      *Resolver
+	singleAttempt bool
 }
 
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -46,7 +49,7 @@ func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		req.URL.Host = addr
 		res, err = t.base.RoundTrip(req)
 
-		if err == nil || !isIdempotent(req.Method) {
+		if err == nil || !isIdempotent(req.Method) || t.singleAttempt {
 			break
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"sort"
 	"strings"
 	"sync"
@@ -30,6 +31,8 @@ type Discovery struct {
 
 	cfg  Config
 	list *memberlist.Memberlist
+	// metaMtx guards the metadata updated at runtime and list for UpdateMetadata
+	metaMtx sync.Mutex
 
 	services                  map[string]*discovery.ServiceInfo
 	instanceIDToDownTimestamp map[instanceKey]time.Time
@@ -87,10 +90,13 @@ func (d *Discovery) run(ctx context.Context) error {
 		go d.transport.Run()
 	}
 
-	d.list, err = memberlist.Create(cfg)
+	list, err := memberlist.Create(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create memberlist: %w", err)
 	}
+	d.metaMtx.Lock()
+	d.list = list
+	d.metaMtx.Unlock()
 	d.join(ctx, true, d.cfg.ClusterMembers...)
 
 	checkExpiredInterval := min(d.cfg.DeregisterServiceAfter/2, 5*time.Minute)
@@ -228,6 +234,25 @@ func (d *Discovery) Stop(ctx context.Context) error {
 	return d.list.Shutdown()
 }
 
+// UpdateMetadata sets the metadata and re-advertises the local node if the discovery is running.
+func (d *Discovery) UpdateMetadata(ctx context.Context, key, value string) error {
+	d.metaMtx.Lock()
+	meta := maps.Clone(d.ServiceInfo.Meta)
+	meta[key] = value
+	d.ServiceInfo.Meta = meta
+	list := d.list
+	d.metaMtx.Unlock()
+
+	if list == nil {
+		return nil
+	}
+	timeout := 5 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	return list.UpdateNode(timeout)
+}
+
 func (d *Discovery) Services(ctx context.Context) ([]*discovery.ServiceInfo, error) {
 	d.servicesMtx.RLock()
 	defer d.servicesMtx.RUnlock()
@@ -264,7 +289,9 @@ func (d *Discovery) Mount(r chi.Router) {
 // when broadcasting an alive message. It's length is limited to
 // the given byte size. This metadata is available in the Node structure.
 func (d *Discovery) NodeMeta(limit int) []byte {
+	d.metaMtx.Lock()
 	res, err := json.Marshal(d.BaseDiscovery.ServiceInfo)
+	d.metaMtx.Unlock()
 	if err != nil {
 		log.Error("Discovery: failed to encode service info: %w")
 		return nil
@@ -17,6 +17,11 @@ type Discovery interface {
 	SetMetadata(key, value string)
 }
 
+// MetadataUpdater is implemented by discoveries able to publish metadata changes while running.
+type MetadataUpdater interface {
+	UpdateMetadata(ctx context.Context, key, value string) error
+}
+
 type NotifyingDiscovery interface {
 	Discovery
 	Subscribe(handler *EventHandler, handlers ...*EventHandler)
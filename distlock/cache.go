@@ -0,0 +1,199 @@
+package distlock
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	cacheV1 "github.com/bldsoft/gost/cache"
+	cache "github.com/bldsoft/gost/cache/v2"
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+const (
+	fenceKeySuffix = ":fence"
+	fenceTTL       = 30 * 24 * time.Hour
+	fenceRetries   = 5
+)
+
+var errCacheMiss = errors.New("cache miss")
+
+// leaseStore is the subset of the cache repositories used by cacheBackend.
+type leaseStore interface {
+	add(key string, value []byte, ttl time.Duration) error // errExists if the key exists
+	get(key string) ([]byte, error)                        // errCacheMiss if the key doesn't exist
+	set(key string, value []byte, ttl time.Duration) error
+	delete(key string) error
+	compareAndSwap(key string, handler func(value []byte) ([]byte, error)) error
+}
+
+// cacheBackend stores the leases in a distributed cache (memcached, aerospike, redis).
+//
+// A cache can evict the lease (e.g. memcached LRU) before it expires. It breaks mutual exclusion,
+// but the eviction is detected on the next renewal and reported by Mutex.LostLock.
+// The fencing counter can be evicted too, so the token is never less than the current time in microseconds:
+// it keeps the tokens increasing as long as the instance clocks are synchronized.
+// TTLs are truncated to seconds by memcached and aerospike, so the lease TTL must be at least a few seconds.
+type cacheBackend struct {
+	store leaseStore
+}
+
+// NewCacheBackend stores the leases in a cache/v2 distributed cache.
+func NewCacheBackend(rep cache.IDistrCacheRepository) Backend {
+	return &cacheBackend{store: cacheV2Store{rep}}
+}
+
+// NewCacheV1Backend stores the leases in a cache (v1) distributed cache.
+func NewCacheV1Backend(rep cacheV1.IDistrCacheRepository) Backend {
+	return &cacheBackend{store: cacheV1Store{rep}}
+}
+
+func (b *cacheBackend) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (uint64, error) {
+	err := b.store.add(key, []byte(owner), ttl)
+	if errors.Is(err, errExists) {
+		return 0, ErrNotAcquired
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	token, err := b.nextToken(key + fenceKeySuffix)
+	if err != nil {
+		_ = b.Release(ctx, key, owner)
+		return 0, fmt.Errorf("fencing token: %w", err)
+	}
+	return token, nil
+}
+
+func (b *cacheBackend) nextToken(key string) (uint64, error) {
+	floor := uint64(time.Now().UnixMicro())
+	var err error
+	for range fenceRetries {
+		var token uint64
+		err = b.store.compareAndSwap(key, func(value []byte) ([]byte, error) {
+			if len(value) == 8 {
+				token = binary.BigEndian.Uint64(value) + 1
+			}
+			token = max(token, floor)
+			return binary.BigEndian.AppendUint64(nil, token), nil
+		})
+		if err == nil && token != 0 {
+			return token, nil
+		}
+
+		// the counter doesn't exist yet or was evicted
+		err = b.store.add(key, binary.BigEndian.AppendUint64(nil, floor), fenceTTL)
+		if err == nil {
+			return floor, nil
+		}
+		if !errors.Is(err, errExists) {
+			return 0, err
+		}
+	}
+	return 0, err
+}
+
+func (b *cacheBackend) Renew(ctx context.Context, key, owner string, ttl time.Duration) error {
+	if err := b.checkOwner(key, owner); err != nil {
+		return err
+	}
+	return b.store.set(key, []byte(owner), ttl)
+}
+
+func (b *cacheBackend) Release(ctx context.Context, key, owner string) error {
+	if err := b.checkOwner(key, owner); err != nil {
+		return err
+	}
+	return b.store.delete(key)
+}
+
+func (b *cacheBackend) checkOwner(key, owner string) error {
+	value, err := b.store.get(key)
+	switch {
+	case errors.Is(err, errCacheMiss):
+		return ErrLockLost
+	case err != nil:
+		return err
+	case string(value) != owner:
+		return ErrLockLost
+	}
+	return nil
+}
+
+var errExists = errors.New("already exists")
+
+type cacheV2Store struct {
+	rep cache.IDistrCacheRepository
+}
+
+func (s cacheV2Store) add(key string, value []byte, ttl time.Duration) error {
+	err := s.rep.Add(key, value, cache.WithTTL(ttl))
+	if errors.Is(err, cache.ErrExists) {
+		return errExists
+	}
+	return err
+}
+
+func (s cacheV2Store) get(key string) ([]byte, error) {
+	item, err := s.rep.Get(key)
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return nil, errCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.Value, nil
+}
+
+func (s cacheV2Store) set(key string, value []byte, ttl time.Duration) error {
+	return s.rep.Set(key, value, cache.WithTTL(ttl))
+}
+
+func (s cacheV2Store) delete(key string) error {
+	return s.rep.Delete(key)
+}
+
+func (s cacheV2Store) compareAndSwap(key string, handler func(value []byte) ([]byte, error)) error {
+	return s.rep.CompareAndSwap(key, func(item *cache.Item) (*cache.Item, error) {
+		value, err := handler(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &cache.Item{Value: value}, nil
+	})
+}
+
+type cacheV1Store struct {
+	rep cacheV1.IDistrCacheRepository
+}
+
+func (s cacheV1Store) add(key string, value []byte, ttl time.Duration) error {
+	err := s.rep.AddFor(key, value, ttl)
+	// memcached repository returns the client error
+	if errors.Is(err, cacheV1.ErrExists) || errors.Is(err, memcache.ErrNotStored) {
+		return errExists
+	}
+	return err
+}
+
+func (s cacheV1Store) get(key string) ([]byte, error) {
+	value, err := s.rep.Get(key)
+	if errors.Is(err, cacheV1.ErrCacheMiss) {
+		return nil, errCacheMiss
+	}
+	return value, err
+}
+
+func (s cacheV1Store) set(key string, value []byte, ttl time.Duration) error {
+	return s.rep.SetFor(key, value, ttl)
+}
+
+func (s cacheV1Store) delete(key string) error {
+	return s.rep.Delete(key)
+}
+
+func (s cacheV1Store) compareAndSwap(key string, handler func(value []byte) ([]byte, error)) error {
+	return s.rep.CompareAndSwap(key, handler)
+}
@@ -1,10 +1,38 @@
 package distlock
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
+// DistrMutex is the legacy lock interface.
+//
+// Deprecated: use Mutex, it reports context cancellation, provides fencing tokens and detects lost locks.
 type DistrMutex interface {
 	Lock(ctx context.Context)
 	TryLock() bool
 	Unlock()
 	Quit() <-chan struct{}
 }
+
+var (
+	// ErrNotAcquired is returned by Backend.Acquire if the lock is held by another owner.
+	ErrNotAcquired = errors.New("lock is held by another owner")
+	// ErrLockLost is returned if the lease expired or was taken by another owner.
+	ErrLockLost = errors.New("lock is lost")
+	// ErrNotLocked is returned by Mutex.Unlock if the mutex isn't locked.
+	ErrNotLocked = errors.New("mutex is not locked")
+)
+
+// Backend stores the lock leases. Mutex uses it to acquire, renew and release a lease.
+type Backend interface {
+	// Acquire takes the lease on key for owner if it's free and returns a fencing token.
+	// Tokens of the successive acquisitions of the same key increase monotonically.
+	// ErrNotAcquired is returned if the lease is held by another owner.
+	Acquire(ctx context.Context, key, owner string, ttl time.Duration) (token uint64, err error)
+	// Renew prolongs the lease. ErrLockLost is returned if the lease isn't held by owner anymore.
+	Renew(ctx context.Context, key, owner string, ttl time.Duration) error
+	// Release frees the lease. ErrLockLost is returned if the lease isn't held by owner anymore.
+	Release(ctx context.Context, key, owner string) error
+}
@@ -2,6 +2,7 @@ package distlock
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/bldsoft/gost/log"
@@ -31,6 +32,9 @@ type mongoDistLock struct {
 	quit       chan struct{}
 }
 
+// NewMongoDistLock creates a legacy lock.
+//
+// Deprecated: use NewMutex with NewMongoBackend.
 func NewMongoDistLock(db *mongo.Storage, lockID string, ttl time.Duration) DistrMutex {
 	col := db.Db.Collection(collName)
 	client := lock.NewClient(col)
@@ -121,3 +125,54 @@ func (l *mongoDistLock) updateLock(ctx context.Context) {
 		}
 	}
 }
+
+type mongoBackend struct {
+	client *lock.Client
+}
+
+// NewMongoBackend stores the leases in Mongo using mongo-lock exclusive locks.
+// The fencing token is a counter stored in the lock document.
+// mongo-lock TTLs are in seconds and a lease can't be renewed during the last second,
+// so the lease TTL must be at least a few seconds.
+func NewMongoBackend(db *mongo.Storage) Backend {
+	client := lock.NewClient(db.Db.Collection(collName))
+	if err := client.CreateIndexes(context.Background()); err != nil {
+		log.WarnWithFields(log.Fields{"err": err}, "distlock: failed creating mongo indexes")
+	}
+	return &mongoBackend{client: client}
+}
+
+func (b *mongoBackend) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (uint64, error) {
+	token, err := b.client.XLockFenced(ctx, key, owner, lock.LockDetails{
+		TTL:   ttlSeconds(ttl),
+		Owner: owner,
+		Host:  utils.Hostname(),
+	})
+	if errors.Is(err, lock.ErrAlreadyLocked) {
+		return 0, ErrNotAcquired
+	}
+	return uint64(token), err
+}
+
+func (b *mongoBackend) Renew(ctx context.Context, key, owner string, ttl time.Duration) error {
+	_, err := b.client.Renew(ctx, owner, ttlSeconds(ttl))
+	if errors.Is(err, lock.ErrLockNotFound) {
+		return ErrLockLost
+	}
+	return err
+}
+
+func (b *mongoBackend) Release(ctx context.Context, key, owner string) error {
+	unlocked, err := b.client.Unlock(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if len(unlocked) == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func ttlSeconds(ttl time.Duration) uint {
+	return uint(max(1, (ttl+time.Second-1)/time.Second))
+}
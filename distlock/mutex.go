@@ -0,0 +1,209 @@
+package distlock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/utils"
+)
+
+const defaultRetryInterval = 200 * time.Millisecond
+
+// MinTTL is the minimum lease ttl, a shorter one leaves no time to renew the lease.
+const MinTTL = 100 * time.Millisecond
+
+// Mutex is a distributed lock with automatic lease renewal.
+//
+// The lease is renewed every ttl/3 while the mutex is locked. If the lease can't be renewed
+// because it's lost (e.g. expired, taken by another owner or evicted from the cache),
+// the LostLock channel is closed. Pass Token to the protected storage to reject
+// the writes of a holder that has lost the lock but doesn't know it yet.
+//
+// The goroutines sharing the mutex wait for each other like with sync.Mutex, so locking it twice
+// without Unlock blocks until ctx is done.
+type Mutex struct {
+	backend       Backend
+	key           string
+	ttl           time.Duration
+	retryInterval time.Duration
+	local         chan struct{} // held from the local acquisition until Unlock, see Lock
+
+	mtx    sync.Mutex // guards the acquisition state below, it isn't held during the backend calls
+	owner  string
+	token  uint64
+	lost   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewMutex creates a mutex with the lease ttl, it's raised to MinTTL if it's shorter.
+func NewMutex(backend Backend, key string, ttl time.Duration) *Mutex {
+	if ttl < MinTTL {
+		log.WarnWithFields(log.Fields{"key": key, "ttl": ttl, "min": MinTTL}, "distlock: ttl is too short, MinTTL is used")
+		ttl = MinTTL
+	}
+	return &Mutex{
+		backend:       backend,
+		key:           key,
+		ttl:           ttl,
+		retryInterval: defaultRetryInterval,
+		local:         make(chan struct{}, 1),
+	}
+}
+
+// SetRetryInterval sets how often Lock and TryLock retry to acquire the lock.
+func (m *Mutex) SetRetryInterval(d time.Duration) *Mutex {
+	m.retryInterval = d
+	return m
+}
+
+// Lock blocks until the lock is acquired or ctx is done. In the latter case ctx.Err() is returned.
+// The mutex is acquired locally first, so the goroutines sharing it don't compete for the lease.
+// Backend errors are logged and the acquisition is retried.
+func (m *Mutex) Lock(ctx context.Context) error {
+	select {
+	case m.local <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	for {
+		ok, err := m.acquire(ctx)
+		switch {
+		case ok:
+			return nil
+		case err != nil:
+			log.FromContext(ctx).DebugWithFields(log.Fields{"key": m.key, "error": err}, "distlock: failed to acquire lock")
+		}
+
+		select {
+		case <-ctx.Done():
+			<-m.local
+			return ctx.Err()
+		case <-time.After(m.retryInterval):
+		}
+	}
+}
+
+// TryLock tries to acquire the lock until timeout expires. If timeout is 0, it makes a single attempt.
+// It returns false without an error if the lock is held by another owner or another goroutine.
+func (m *Mutex) TryLock(ctx context.Context, timeout time.Duration) (bool, error) {
+	if timeout <= 0 {
+		select {
+		case m.local <- struct{}{}:
+		default:
+			return false, nil
+		}
+		ok, err := m.acquire(ctx)
+		if !ok {
+			<-m.local
+		}
+		return ok, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := m.Lock(ctx)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// acquire acquires the lease, the caller must hold the local lock.
+func (m *Mutex) acquire(ctx context.Context) (bool, error) {
+	owner := utils.RandString(32)
+	token, err := m.backend.Acquire(ctx, m.key, owner, m.ttl)
+	if errors.Is(err, ErrNotAcquired) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	renewCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	lost, done := make(chan struct{}), make(chan struct{})
+	m.mtx.Lock()
+	m.owner, m.token, m.cancel = owner, token, cancel
+	m.lost, m.done = lost, done
+	m.mtx.Unlock()
+	go m.renew(renewCtx, owner, lost, done)
+	return true, nil
+}
+
+// Unlock stops the lease renewal and releases the lock.
+// ErrLockLost is returned if the lock was lost before Unlock.
+func (m *Mutex) Unlock(ctx context.Context) error {
+	m.mtx.Lock()
+	if m.cancel == nil {
+		m.mtx.Unlock()
+		return ErrNotLocked
+	}
+	owner, cancel, done := m.owner, m.cancel, m.done
+	m.owner, m.token, m.cancel = "", 0, nil
+	m.mtx.Unlock()
+	// the local lock is released after the lease, so the next goroutine doesn't retry to acquire it
+	defer func() { <-m.local }()
+
+	cancel()
+	<-done
+	return m.backend.Release(ctx, m.key, owner)
+}
+
+// Token returns the fencing token of the current acquisition or 0 if the mutex isn't locked.
+func (m *Mutex) Token() uint64 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.token
+}
+
+// LostLock returns the channel closed when the current acquisition is lost.
+// It returns nil if the mutex has never been locked.
+func (m *Mutex) LostLock() <-chan struct{} {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.lost
+}
+
+func (m *Mutex) renew(ctx context.Context, owner string, lost, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(m.ttl / 3)
+	defer ticker.Stop()
+
+	renewedAt := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		err := m.backend.Renew(ctx, m.key, owner, m.ttl)
+		switch {
+		case err == nil:
+			renewedAt = start
+			continue
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, ErrLockLost):
+			log.WarnWithFields(log.Fields{"key": m.key}, "distlock: lock is lost")
+			close(lost)
+			return
+		}
+
+		log.WarnWithFields(log.Fields{"key": m.key, "error": err}, "distlock: failed to renew lock")
+		if time.Since(renewedAt) >= m.ttl {
+			log.WarnWithFields(log.Fields{"key": m.key}, "distlock: lock lease has expired")
+			close(lost)
+			return
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package distlock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bldsoft/gost/cache/v2/redis"
+	goredis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedisStorage(t *testing.T) (*redis.Storage, *miniredis.Miniredis) {
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return redis.NewStorageFromClient(client, ""), srv
+}
+
+func testBackends(t *testing.T) map[string]func(t *testing.T) (Backend, *miniredis.Miniredis) {
+	return map[string]func(t *testing.T) (Backend, *miniredis.Miniredis){
+		"redis": func(t *testing.T) (Backend, *miniredis.Miniredis) {
+			storage, srv := newRedisStorage(t)
+			return NewRedisBackend(storage), srv
+		},
+		"cache": func(t *testing.T) (Backend, *miniredis.Miniredis) {
+			storage, srv := newRedisStorage(t)
+			return NewCacheBackend(redis.NewRepository(storage, 0)), srv
+		},
+	}
+}
+
+func TestMutexExclusion(t *testing.T) {
+	for name, newBackend := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			backend, _ := newBackend(t)
+			m1 := NewMutex(backend, "key", time.Minute)
+			m2 := NewMutex(backend, "key", time.Minute).SetRetryInterval(10 * time.Millisecond)
+			ctx := context.Background()
+
+			require.NoError(t, m1.Lock(ctx))
+			timeoutCtx, cancelTimeout := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancelTimeout()
+			assert.ErrorIs(t, m1.Lock(timeoutCtx), context.DeadlineExceeded, "locking twice blocks")
+			token1 := m1.Token()
+			assert.NotZero(t, token1)
+
+			ok, err := m2.TryLock(ctx, 0)
+			require.NoError(t, err)
+			assert.False(t, ok)
+			ok, err = m2.TryLock(ctx, 50*time.Millisecond)
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			canceled, cancel := context.WithCancel(ctx)
+			cancel()
+			assert.ErrorIs(t, m2.Lock(canceled), context.Canceled)
+
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				assert.NoError(t, m1.Unlock(ctx))
+			}()
+			require.NoError(t, m2.Lock(ctx))
+			assert.Greater(t, m2.Token(), token1)
+			assert.Zero(t, m1.Token())
+			assert.ErrorIs(t, m1.Unlock(ctx), ErrNotLocked)
+			require.NoError(t, m2.Unlock(ctx))
+		})
+	}
+}
+
+func TestMutexSharedByGoroutines(t *testing.T) {
+	backend, _ := testBackends(t)["redis"](t)
+	m := NewMutex(backend, "key", time.Minute).SetRetryInterval(time.Hour)
+	ctx := context.Background()
+
+	require.NoError(t, m.Lock(ctx))
+	ok, err := m.TryLock(ctx, 0)
+	require.NoError(t, err)
+	assert.False(t, ok, "locked by another goroutine")
+
+	locked := make(chan struct{})
+	go func() {
+		defer close(locked)
+		assert.NoError(t, m.Lock(ctx))
+	}()
+	select {
+	case <-locked:
+		t.Fatal("Lock doesn't wait for Unlock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	token := m.Token()
+	require.NoError(t, m.Unlock(ctx))
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("Lock isn't acquired after Unlock")
+	}
+	assert.Greater(t, m.Token(), token, "acquired without waiting for the retry interval")
+	require.NoError(t, m.Unlock(ctx))
+}
+
+func TestMutexLostLock(t *testing.T) {
+	for name, newBackend := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			backend, srv := newBackend(t)
+			m := NewMutex(backend, "key", 300*time.Millisecond)
+			require.NoError(t, m.Lock(context.Background()))
+
+			// renewal keeps the lock
+			time.Sleep(400 * time.Millisecond)
+			select {
+			case <-m.LostLock():
+				t.Fatal("lock is lost")
+			default:
+			}
+
+			// emulate eviction
+			srv.FlushAll()
+			select {
+			case <-m.LostLock():
+			case <-time.After(time.Second):
+				t.Fatal("lost lock isn't detected")
+			}
+			assert.ErrorIs(t, m.Unlock(context.Background()), ErrLockLost)
+		})
+	}
+}
+
+func TestCacheBackendFenceEviction(t *testing.T) {
+	storage, srv := newRedisStorage(t)
+	backend := NewCacheBackend(redis.NewRepository(storage, 0))
+	ctx := context.Background()
+
+	token1, err := backend.Acquire(ctx, "key", "owner1", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, backend.Release(ctx, "key", "owner1"))
+
+	srv.FlushAll()
+	token2, err := backend.Acquire(ctx, "key", "owner2", time.Minute)
+	require.NoError(t, err)
+	assert.Greater(t, token2, token1)
+}
+
+func TestMutexMinTTL(t *testing.T) {
+	storage, _ := newRedisStorage(t)
+	for _, ttl := range []time.Duration{0, -time.Second, time.Nanosecond} {
+		m := NewMutex(NewRedisBackend(storage), "key", ttl)
+		assert.Equal(t, MinTTL, m.ttl)
+		require.NoError(t, m.Lock(context.Background()))
+		require.NoError(t, m.Unlock(context.Background()))
+	}
+}
@@ -0,0 +1,72 @@
+package distlock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bldsoft/gost/cache/v2/redis"
+	goredis "github.com/go-redis/redis"
+)
+
+var (
+	redisAcquireScript = goredis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return false`)
+
+	redisRenewScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	redisReleaseScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+type redisBackend struct {
+	storage *redis.Storage
+}
+
+// NewRedisBackend stores the leases in Redis. The lease and the fencing counter are updated atomically by Lua scripts.
+func NewRedisBackend(storage *redis.Storage) Backend {
+	return &redisBackend{storage: storage}
+}
+
+// keys returns the lease and the fencing counter keys. The hash tag keeps them in the same Redis Cluster slot.
+func (b *redisBackend) keys(key string) []string {
+	key = b.storage.PrepareKey("{" + key + "}")
+	return []string{key, key + ":fence"}
+}
+
+func (b *redisBackend) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (uint64, error) {
+	token, err := redisAcquireScript.Run(b.storage, b.keys(key), owner, ttl.Milliseconds()).Int64()
+	if errors.Is(err, goredis.Nil) {
+		return 0, ErrNotAcquired
+	}
+	return uint64(token), err
+}
+
+func (b *redisBackend) Renew(ctx context.Context, key, owner string, ttl time.Duration) error {
+	return b.run(redisRenewScript, key, owner, ttl.Milliseconds())
+}
+
+func (b *redisBackend) Release(ctx context.Context, key, owner string) error {
+	return b.run(redisReleaseScript, key, owner)
+}
+
+func (b *redisBackend) run(script *goredis.Script, key string, args ...interface{}) error {
+	n, err := script.Run(b.storage, b.keys(key)[:1], args...).Int64()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
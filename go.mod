@@ -5,10 +5,12 @@ go 1.26.0
 require (
 	github.com/ClickHouse/clickhouse-go/v2 v2.43.1-0.20260316095946-93e89bec86f7
 	github.com/Masterminds/squirrel v1.5.3
+	github.com/alicebob/miniredis/v2 v2.39.0
 	github.com/allegro/bigcache v1.2.1
 	github.com/bldsoft/memberlist v0.0.0-20250318063233-36c35bf6fda4
 	github.com/bradfitz/gomemcache v0.0.0-20230905024940-24af94b03874
 	github.com/dgraph-io/ristretto v0.1.0
+	github.com/dlclark/regexp2 v1.12.0
 	github.com/dmarkham/enumer v1.6.3
 	github.com/evanphx/json-patch v4.9.0+incompatible
 	github.com/felixge/httpsnoop v1.0.4
@@ -26,6 +28,7 @@ require (
 	github.com/grobie/gomemcache v0.0.0-20201204163352-08d7c80fcac6
 	github.com/hashicorp/consul/api v1.20.0
 	github.com/hashicorp/go-multierror v1.1.1
+	github.com/hashicorp/golang-lru v1.0.2
 	github.com/jordan-wright/email v4.0.1-0.20210109023952-943e75fe5223+incompatible
 	github.com/lestrrat-go/jwx v1.2.18
 	github.com/mattn/go-colorable v0.1.13
@@ -101,7 +104,6 @@ require (
 	github.com/hashicorp/go-immutable-radix v1.3.1 // indirect
 	github.com/hashicorp/go-msgpack v1.1.5 // indirect
 	github.com/hashicorp/go-rootcerts v1.0.2 // indirect
-	github.com/hashicorp/golang-lru v1.0.2 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/hashicorp/serf v0.10.1 // indirect
 	github.com/klauspost/compress v1.18.3 // indirect
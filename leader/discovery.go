@@ -0,0 +1,39 @@
+package leader
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/bldsoft/gost/discovery"
+)
+
+// Current returns the service instance published as the leader of the role.
+// While the metadata is propagated, several instances may claim the role; the one with the highest fencing token wins.
+// discovery.NotFound is returned if no instance claims the role.
+func Current(ctx context.Context, d discovery.Discovery, serviceName, name string) (*discovery.ServiceInstanceInfo, error) {
+	service, err := d.ServiceByName(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		res      *discovery.ServiceInstanceInfo
+		maxToken uint64
+	)
+	for i, instance := range service.Instances {
+		if !instance.Healthy {
+			continue
+		}
+		token, err := strconv.ParseUint(instance.Meta[MetadataKey(name)], 10, 64)
+		if err != nil || token == 0 {
+			continue
+		}
+		if token > maxToken {
+			res, maxToken = &service.Instances[i], token
+		}
+	}
+	if res == nil {
+		return nil, discovery.NotFound
+	}
+	return res, nil
+}
@@ -0,0 +1,291 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bldsoft/gost/discovery"
+	"github.com/bldsoft/gost/distlock"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/mongo"
+	"github.com/bldsoft/gost/server"
+)
+
+const (
+	// MetadataKeyPrefix prefixes the discovery metadata key holding the fencing token of the leader.
+	MetadataKeyPrefix = "leader."
+
+	lockKeyPrefix        = "leader:"
+	defaultRetryInterval = time.Second
+)
+
+var (
+	ErrAlreadyLeader = errors.New("leader: already the leader")
+	ErrNotLeader     = errors.New("leader: not the leader")
+)
+
+// MetadataKey returns the discovery metadata key of the role.
+func MetadataKey(name string) string {
+	return MetadataKeyPrefix + name
+}
+
+// Elector elects a single leader among the service instances campaigning for the same role.
+//
+// The leadership is a distributed lock with lease renewal. When the lease is lost,
+// the elected context is canceled and the revoked callback is called.
+// If the discovery is set, the fencing token of the current leadership is published
+// in the instance metadata under MetadataKey(name) (empty if the instance isn't the leader).
+//
+// As a server.AsyncRunner, the elector campaigns until stopped and campaigns again after losing the leadership:
+//
+//	elector := leader.NewMongoElector(db, "notify-retry", 15*time.Second).
+//		SetDiscovery(d).
+//		OnElected(func(ctx context.Context) { notifyService.Run(ctx) })
+//	asyncJobManager.Append(elector)
+type Elector struct {
+	server.AsyncRunner
+
+	name          string
+	ttl           time.Duration
+	mutex         *distlock.Mutex
+	discovery     discovery.Discovery
+	retryInterval time.Duration
+	onElected     func(ctx context.Context)
+	onRevoked     func()
+
+	mtx       sync.Mutex
+	term      *term
+	observers []chan bool
+}
+
+type term struct {
+	token  uint64
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{} // closed when the term is over and the lock is released
+	err    error         // lock release error, set before done is closed
+}
+
+// NewElector creates an elector of the role using the backend for the leadership lock.
+func NewElector(backend distlock.Backend, name string, ttl time.Duration) *Elector {
+	e := &Elector{
+		name:          name,
+		ttl:           ttl,
+		mutex:         distlock.NewMutex(backend, lockKeyPrefix+name, ttl),
+		retryInterval: defaultRetryInterval,
+	}
+	e.mutex.SetRetryInterval(e.retryInterval)
+	e.AsyncRunner = server.NewContextAsyncRunner(e.run)
+	return e
+}
+
+// NewMongoElector creates an elector of the role backed by the Mongo lock.
+// The lease TTL must be at least a few seconds, see distlock.NewMongoBackend.
+func NewMongoElector(db *mongo.Storage, name string, ttl time.Duration) *Elector {
+	return NewElector(distlock.NewMongoBackend(db), name, ttl)
+}
+
+// SetDiscovery sets the discovery used to publish the leadership.
+func (e *Elector) SetDiscovery(d discovery.Discovery) *Elector {
+	e.discovery = d
+	return e
+}
+
+// SetRetryInterval sets how often the lock acquisition is retried while campaigning.
+func (e *Elector) SetRetryInterval(d time.Duration) *Elector {
+	e.retryInterval = d
+	e.mutex.SetRetryInterval(d)
+	return e
+}
+
+// OnElected sets the callback called in a separate goroutine when the instance becomes the leader.
+// ctx is canceled when the leadership is lost or resigned.
+func (e *Elector) OnElected(f func(ctx context.Context)) *Elector {
+	e.onElected = f
+	return e
+}
+
+// OnRevoked sets the callback called when the leadership is lost or resigned,
+// after the elected callback is returned.
+func (e *Elector) OnRevoked(f func()) *Elector {
+	e.onRevoked = f
+	return e
+}
+
+// Name returns the role name.
+func (e *Elector) Name() string {
+	return e.name
+}
+
+// IsLeader reports whether the instance is the leader.
+func (e *Elector) IsLeader() bool {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return e.term != nil
+}
+
+// Token returns the fencing token of the current leadership or 0 if the instance isn't the leader.
+// Pass it to the storage written by the leader to reject the writes of a stale leader.
+func (e *Elector) Token() uint64 {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	if e.term == nil {
+		return 0
+	}
+	return e.term.token
+}
+
+// Leader returns a channel receiving the leadership state: true when the instance becomes the leader
+// and false when it stops being the leader. The current state is sent immediately.
+// The channel keeps only the latest state, so a slow reader never blocks the elector.
+func (e *Elector) Leader() <-chan bool {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	ch := make(chan bool, 1)
+	ch <- e.term != nil
+	e.observers = append(e.observers, ch)
+	return ch
+}
+
+// Campaign blocks until the instance becomes the leader or ctx is done. In the latter case ctx.Err() is returned.
+func (e *Elector) Campaign(ctx context.Context) error {
+	if e.IsLeader() {
+		return ErrAlreadyLeader
+	}
+	if err := e.mutex.Lock(ctx); err != nil {
+		return err
+	}
+
+	termCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	t := &term{token: e.mutex.Token(), ctx: termCtx, cancel: cancel, done: make(chan struct{})}
+	e.mtx.Lock()
+	e.term = t
+	e.notify(true)
+	e.mtx.Unlock()
+
+	log.FromContext(ctx).InfoWithFields(log.Fields{"role": e.name, "token": t.token}, "leader: elected")
+	e.publish(ctx, t.token)
+	go e.serve(t, e.mutex.LostLock())
+	return nil
+}
+
+// Resign gives up the leadership. It waits for the elected callback to return.
+func (e *Elector) Resign(ctx context.Context) error {
+	e.mtx.Lock()
+	t := e.term
+	e.mtx.Unlock()
+	if t == nil {
+		return ErrNotLeader
+	}
+
+	t.cancel()
+	select {
+	case <-t.done:
+		return t.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// serve runs the term until the lock is lost or the leadership is resigned.
+func (e *Elector) serve(t *term, lost <-chan struct{}) {
+	defer close(t.done)
+
+	var wg sync.WaitGroup
+	if e.onElected != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.onElected(t.ctx)
+		}()
+	}
+
+	select {
+	case <-lost:
+		log.WarnWithFields(log.Fields{"role": e.name}, "leader: leadership is lost")
+	case <-t.ctx.Done():
+	}
+	t.cancel()
+	wg.Wait()
+
+	releaseCtx, cancel := context.WithTimeout(context.Background(), e.ttl)
+	if err := e.mutex.Unlock(releaseCtx); err != nil && !errors.Is(err, distlock.ErrLockLost) {
+		log.WarnWithFields(log.Fields{"role": e.name, "error": err}, "leader: failed to release lock")
+		t.err = err
+	}
+	cancel()
+
+	e.mtx.Lock()
+	e.term = nil
+	e.notify(false)
+	e.mtx.Unlock()
+
+	e.publish(context.Background(), 0)
+	if e.onRevoked != nil {
+		e.onRevoked()
+	}
+}
+
+// notify must be called with mtx held.
+func (e *Elector) notify(isLeader bool) {
+	for _, ch := range e.observers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- isLeader
+	}
+}
+
+func (e *Elector) publish(ctx context.Context, token uint64) {
+	if e.discovery == nil {
+		return
+	}
+	value := ""
+	if token != 0 {
+		value = strconv.FormatUint(token, 10)
+	}
+	updater, ok := e.discovery.(discovery.MetadataUpdater)
+	if !ok {
+		e.discovery.SetMetadata(MetadataKey(e.name), value)
+		return
+	}
+	if err := updater.UpdateMetadata(ctx, MetadataKey(e.name), value); err != nil {
+		log.FromContext(ctx).WarnWithFields(log.Fields{"role": e.name, "error": err}, "leader: failed to publish leadership")
+	}
+}
+
+func (e *Elector) run(ctx context.Context) error {
+	for {
+		err := e.Campaign(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil:
+			log.FromContext(ctx).WarnWithFields(log.Fields{"role": e.name, "error": err}, "leader: campaign failed")
+		default:
+			e.mtx.Lock()
+			t := e.term
+			e.mtx.Unlock()
+			if t != nil {
+				select {
+				case <-t.done:
+				case <-ctx.Done():
+					if err := e.Resign(context.WithoutCancel(ctx)); err != nil && !errors.Is(err, ErrNotLeader) {
+						log.WarnWithFields(log.Fields{"role": e.name, "error": err}, "leader: failed to resign")
+					}
+					return nil
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(e.retryInterval):
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bldsoft/gost/cache/v2/redis"
+	"github.com/bldsoft/gost/discovery"
+	"github.com/bldsoft/gost/discovery/fake"
+	"github.com/bldsoft/gost/distlock"
+	"github.com/bldsoft/gost/server"
+	goredis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBackend(t *testing.T) (distlock.Backend, *miniredis.Miniredis) {
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return distlock.NewRedisBackend(redis.NewStorageFromClient(client, "")), srv
+}
+
+func waitState(t *testing.T, ch <-chan bool, want bool) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case isLeader := <-ch:
+			if isLeader == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("leadership state %v isn't reached", want)
+		}
+	}
+}
+
+func TestCampaignResign(t *testing.T) {
+	backend, _ := newBackend(t)
+	ctx := context.Background()
+
+	elected := make(chan struct{})
+	revoked := make(chan struct{})
+	e1 := NewElector(backend, "job", time.Minute).
+		OnElected(func(ctx context.Context) {
+			close(elected)
+			<-ctx.Done()
+		}).
+		OnRevoked(func() { close(revoked) })
+	e2 := NewElector(backend, "job", time.Minute).SetRetryInterval(10 * time.Millisecond)
+
+	require.NoError(t, e1.Campaign(ctx))
+	assert.True(t, e1.IsLeader())
+	assert.NotZero(t, e1.Token())
+	assert.ErrorIs(t, e1.Campaign(ctx), ErrAlreadyLeader)
+	<-elected
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, e2.Campaign(timeoutCtx), context.DeadlineExceeded)
+	assert.False(t, e2.IsLeader())
+
+	token1 := e1.Token()
+	require.NoError(t, e1.Resign(ctx))
+	<-revoked
+	assert.False(t, e1.IsLeader())
+	assert.ErrorIs(t, e1.Resign(ctx), ErrNotLeader)
+
+	require.NoError(t, e2.Campaign(ctx))
+	assert.Greater(t, e2.Token(), token1)
+	require.NoError(t, e2.Resign(ctx))
+}
+
+func TestLostLeadership(t *testing.T) {
+	backend, srv := newBackend(t)
+	ctx := context.Background()
+
+	e := NewElector(backend, "job", 300*time.Millisecond)
+	states := e.Leader()
+	waitState(t, states, false)
+
+	var electedCtx context.Context
+	e.OnElected(func(ctx context.Context) { electedCtx = ctx; <-ctx.Done() })
+	require.NoError(t, e.Campaign(ctx))
+	waitState(t, states, true)
+
+	srv.Del("{leader:job}")
+	waitState(t, states, false)
+	assert.False(t, e.IsLeader())
+	assert.Error(t, electedCtx.Err())
+
+	require.NoError(t, e.Campaign(ctx))
+	require.NoError(t, e.Resign(ctx))
+}
+
+func TestRunner(t *testing.T) {
+	backend, _ := newBackend(t)
+	const serviceName = "service"
+
+	newInstance := func(id string) (*Elector, *fake.Discovery) {
+		d := fake.NewDiscovery(server.Config{ServiceName: serviceName, ServiceInstance: id})
+		return NewElector(backend, "job", time.Minute).SetRetryInterval(10 * time.Millisecond).SetDiscovery(d), d
+	}
+	e1, d1 := newInstance("1")
+	e2, _ := newInstance("2")
+
+	states1, states2 := e1.Leader(), e2.Leader()
+	go e1.Run()
+	waitState(t, states1, true)
+	go e2.Run()
+
+	leader, err := Current(context.Background(), d1, serviceName, "job")
+	require.NoError(t, err)
+	assert.Equal(t, "1", leader.ID)
+
+	require.NoError(t, e1.Stop(context.Background()))
+	waitState(t, states1, false)
+	_, err = Current(context.Background(), d1, serviceName, "job")
+	assert.ErrorIs(t, err, discovery.NotFound)
+
+	waitState(t, states2, true)
+	require.NoError(t, e2.Stop(context.Background()))
+	waitState(t, states2, false)
+}
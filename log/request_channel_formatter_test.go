@@ -132,3 +132,28 @@ func TestChannelFormatterCustomErr(t *testing.T) {
 
 	assert.Equal(t, requestInfo.CustomField, "some value")
 }
+
+func TestChannelFormatterUserAgent(t *testing.T) {
+	type UARequestInfo struct {
+		RequestInfo
+		UserAgentInfo
+	}
+
+	router := chi.NewRouter()
+	requestC := make(chan *UARequestInfo, 1)
+	formatter := NewChannelFormatter(requestC, "")
+	formatter.SetUserAgentParser(func(userAgent string) UserAgentInfo {
+		return UserAgentInfo{Browser: userAgent}
+	})
+	router.Use(NewRequestLogger(formatter))
+	router.HandleFunc("/*", func(w http.ResponseWriter, r *http.Request) {})
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Add("User-Agent", "agent")
+	router.ServeHTTP(rw, req)
+	requestInfo := <-requestC
+
+	assert.Equal(t, "agent", requestInfo.UserAgent)
+	assert.Equal(t, "agent", requestInfo.Browser)
+}
@@ -18,6 +18,7 @@ type ExportFormatter[T any, P RequestInfoPtr[T]] struct {
 	requestExporter   exporter.Exporter[P]
 	instanceName      string
 	requestInfoCtxKey interface{}
+	userAgentParser   UserAgentParser
 }
 
 func NewExportFormatter[T any, P RequestInfoPtr[T]](requestExporter exporter.Exporter[P], instanceName string) *ExportFormatter[T, P] {
@@ -32,6 +33,11 @@ func (f *ExportFormatter[T, P]) SetRequestInfoContextKey(key interface{}) {
 	f.requestInfoCtxKey = key
 }
 
+// SetUserAgentParser enables user agent parsing. T must contain UserAgentInfo, otherwise the parser isn't used.
+func (f *ExportFormatter[T, P]) SetUserAgentParser(parser UserAgentParser) {
+	f.userAgentParser = parser
+}
+
 func (f *ExportFormatter[T, P]) GetRequestInfo(ctx context.Context) P {
 	requestInfo, _ := ctx.Value(f.requestInfoCtxKey).(P)
 	return requestInfo
@@ -66,6 +72,7 @@ func (f *ExportFormatter[T, P]) NewLogEntry(r *http.Request) (middleware.LogEntr
 	r = r.WithContext(ctx)
 	return &ContextExportFormatterLoggerEntry[T, P]{
 		requestExporter: f.requestExporter,
+		userAgentParser: f.userAgentParser,
 		errBuf:          LogRequestErrBufferFromContext(r.Context()),
 		requestInfo:     requestInfoPtr,
 		req:             r,
@@ -76,6 +83,7 @@ type ContextExportFormatterLoggerEntry[T any, P RequestInfoPtr[T]] struct {
 	requestInfo     P
 	errBuf          *bytes.Buffer
 	requestExporter exporter.Exporter[P]
+	userAgentParser UserAgentParser
 	req             *http.Request
 }
 
@@ -95,6 +103,9 @@ func (l *ContextExportFormatterLoggerEntry[T, P]) Write(status, bytes int, heade
 		}
 
 		baseRequestInfo.UserAgent = l.req.UserAgent()
+		if uaInfo, ok := any(l.requestInfo).(IUserAgentInfo); ok && l.userAgentParser != nil {
+			*uaInfo.BaseUserAgentInfo() = l.userAgentParser(baseRequestInfo.UserAgent)
+		}
 
 		l.requestExporter.Export(l.requestInfo)
 	}
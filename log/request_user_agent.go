@@ -0,0 +1,24 @@
+package log
+
+// UserAgentInfo contains the parsed user agent.
+// To export it put UserAgentInfo in your request info structure and set the parser by ExportFormatter.SetUserAgentParser.
+type UserAgentInfo struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	DeviceType     string
+	Brand          string
+	Model          string
+}
+
+func (i *UserAgentInfo) BaseUserAgentInfo() *UserAgentInfo {
+	return i
+}
+
+type IUserAgentInfo interface {
+	BaseUserAgentInfo() *UserAgentInfo
+}
+
+// UserAgentParser parses RequestInfo.UserAgent, see ua.UserAgentInfo.
+type UserAgentParser func(userAgent string) UserAgentInfo
@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"time"
+)
+
+// AlertEvaluationObserver returns a hook for alert.Manager.OnEvaluation
+// recording the evaluation latency and errors per processor. If registry is nil, Default is used.
+func AlertEvaluationObserver(registry *Registry) func(processorID string, duration time.Duration, err error) {
+	registry = orDefault(registry)
+	duration := registry.NewHistogramVec("alert_evaluation_duration_seconds",
+		"Alert source evaluation time in seconds.", DefBuckets, "processor")
+	errors := registry.NewCounterVec("alert_evaluation_errors_total",
+		"Total number of failed alert source evaluations.", "processor")
+	return func(processorID string, d time.Duration, err error) {
+		duration.With(processorID).Observe(d.Seconds())
+		if err != nil {
+			errors.With(processorID).Inc()
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"github.com/bldsoft/gost/breaker"
+)
+
+// InstrumentBreaker returns settings whose OnStateChange also records the circuit breaker state:
+// circuit_breaker_state is 0 for closed, 1 for half-open and 2 for open.
+// The original OnStateChange is still called. If registry is nil, Default is used.
+func InstrumentBreaker(registry *Registry, st breaker.Settings) breaker.Settings {
+	registry = orDefault(registry)
+	state := registry.NewGaugeVec("circuit_breaker_state",
+		"Circuit breaker state: 0 - closed, 1 - half-open, 2 - open.", "name")
+	transitions := registry.NewCounterVec("circuit_breaker_transitions_total",
+		"Total number of circuit breaker state transitions.", "name", "from", "to")
+
+	state.With(st.Name).Set(float64(breaker.StateClosed))
+	next := st.OnStateChange
+	st.OnStateChange = func(name string, from, to breaker.State) {
+		state.With(name).Set(float64(to))
+		transitions.With(name, from.String(), to.String()).Inc()
+		if next != nil {
+			next(name, from, to)
+		}
+	}
+	return st
+}
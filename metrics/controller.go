@@ -0,0 +1,19 @@
+package metrics
+
+import (
+	"github.com/go-chi/chi/v5"
+)
+
+type Controller struct {
+	registry *Registry
+}
+
+// NewController creates a controller serving the registry metrics. If registry is nil, Default is used.
+func NewController(registry *Registry) *Controller {
+	return &Controller{registry: orDefault(registry)}
+}
+
+// Mount it with r.Route("/metrics", c.Mount).
+func (c *Controller) Mount(r chi.Router) {
+	r.Method("GET", "/", Handler(c.registry))
+}
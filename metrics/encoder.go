@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentType of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WriteText writes all metrics of the registry in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.sortedFamilies() {
+		samples := f.collect()
+		if len(samples) == 0 {
+			continue
+		}
+		if f.help != "" {
+			bw.WriteString("# HELP " + f.name + " " + helpReplacer.Replace(f.help) + "\n")
+		}
+		bw.WriteString("# TYPE " + f.name + " " + string(f.typ) + "\n")
+		for _, s := range samples {
+			writeSample(bw, f.name, s)
+		}
+	}
+	return bw.Flush()
+}
+
+func writeSample(w *bufio.Writer, name string, s sample) {
+	w.WriteString(name)
+	w.WriteString(s.suffix)
+	if len(s.labels) > 0 {
+		w.WriteByte('{')
+		for i, l := range s.labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l.name)
+			w.WriteString(`="`)
+			w.WriteString(labelValueReplacer.Replace(l.value))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(s.value))
+	w.WriteByte('\n')
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Handler serves the registry metrics. If r is nil, Default is used.
+func Handler(r *Registry) http.Handler {
+	r = orDefault(r)
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = r.WriteText(w)
+	})
+}
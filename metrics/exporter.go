@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"github.com/bldsoft/gost/utils/exporter"
+)
+
+type exporterStats interface {
+	Stats() exporter.BufferedExporterStats
+}
+
+// RegisterExporter exposes the queue depth, throughput and flush errors of a buffered exporter
+// with the exporter label set to name. If registry is nil, Default is used.
+func RegisterExporter(registry *Registry, name string, e exporterStats) {
+	registry = orDefault(registry)
+	labels := Labels{"exporter": name}
+	registry.GaugeFunc("exporter_queued_items", "Number of items waiting in the exporter channel.", labels, func() float64 {
+		return float64(e.Stats().Queued)
+	})
+	registry.GaugeFunc("exporter_buffered_items", "Number of items buffered for the next flush.", labels, func() float64 {
+		return float64(e.Stats().Buffered)
+	})
+	registry.CounterFunc("exporter_exported_items_total", "Total number of exported items.", labels, func() float64 {
+		return float64(e.Stats().Exported)
+	})
+	registry.CounterFunc("exporter_dropped_items_total", "Total number of items discarded because the exporter channel was full.", labels, func() float64 {
+		return float64(e.Stats().Dropped)
+	})
+	registry.CounterFunc("exporter_flush_errors_total", "Total number of failed flushes.", labels, func() float64 {
+		return float64(e.Stats().FlushErrors)
+	})
+}
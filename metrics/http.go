@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bldsoft/gost/log"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const unmatchedRoute = "unmatched"
+
+// RequestFormatter is a log.LogFormatter collecting HTTP request metrics.
+// Combine it with other formatters by log.MultiLogger or use RequestLogger.
+type RequestFormatter struct {
+	requests CounterVec
+	duration HistogramVec
+	size     HistogramVec
+}
+
+// NewRequestFormatter registers the HTTP request metrics in the registry. If registry is nil, Default is used.
+func NewRequestFormatter(registry *Registry) *RequestFormatter {
+	registry = orDefault(registry)
+	return &RequestFormatter{
+		requests: registry.NewCounterVec("http_requests_total",
+			"Total number of HTTP requests.", "method", "route", "code"),
+		duration: registry.NewHistogramVec("http_request_duration_seconds",
+			"HTTP request handling time in seconds.", DefBuckets, "method", "route"),
+		size: registry.NewHistogramVec("http_response_size_bytes",
+			"HTTP response size in bytes.", []float64{100, 1_000, 10_000, 100_000, 1_000_000, 10_000_000}, "method", "route"),
+	}
+}
+
+// RequestLogger is a middleware collecting HTTP request metrics.
+func RequestLogger(registry *Registry) func(next http.Handler) http.Handler {
+	return log.NewRequestLogger(NewRequestFormatter(registry))
+}
+
+func (f *RequestFormatter) NewLogEntry(r *http.Request) (middleware.LogEntry, *http.Request) {
+	return &requestEntry{formatter: f, req: r}, r
+}
+
+type requestEntry struct {
+	formatter *RequestFormatter
+	req       *http.Request
+}
+
+func (e *requestEntry) Write(status, bytes int, header http.Header, elapsed time.Duration, extra interface{}) {
+	route := e.route()
+	e.formatter.requests.With(e.req.Method, route, strconv.Itoa(status)).Inc()
+	e.formatter.duration.With(e.req.Method, route).Observe(elapsed.Seconds())
+	e.formatter.size.With(e.req.Method, route).Observe(float64(bytes))
+}
+
+// route returns the route pattern to keep the label cardinality low.
+func (e *requestEntry) route() string {
+	if rctx := chi.RouteContext(e.req.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return unmatchedRoute
+}
+
+func (e *requestEntry) Panic(v interface{}, stack []byte) {}
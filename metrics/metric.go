@@ -0,0 +1,270 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type Labels map[string]string
+
+type labelPair struct {
+	name, value string
+}
+
+type sample struct {
+	suffix string
+	labels []labelPair
+	value  float64
+}
+
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) Load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+func (f *atomicFloat) Store(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+func (f *atomicFloat) Add(v float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	v atomicFloat
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Add panics if v is negative.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.v.Add(v)
+}
+
+func (c *Counter) Value() float64 {
+	return c.v.Load()
+}
+
+func (c *Counter) collect(labels []labelPair) []sample {
+	return []sample{{labels: labels, value: c.Value()}}
+}
+
+type Gauge struct {
+	v atomicFloat
+}
+
+func (g *Gauge) Set(v float64) {
+	g.v.Store(v)
+}
+
+func (g *Gauge) Add(v float64) {
+	g.v.Add(v)
+}
+
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+func (g *Gauge) Value() float64 {
+	return g.v.Load()
+}
+
+func (g *Gauge) collect(labels []labelPair) []sample {
+	return []sample{{labels: labels, value: g.Value()}}
+}
+
+// DefBuckets are the default histogram buckets, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram counts observations in cumulative buckets.
+type Histogram struct {
+	upperBounds []float64
+
+	mtx    sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		upperBounds: buckets,
+		counts:      make([]uint64, len(buckets)),
+	}
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upperBounds, v)
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *Histogram) collect(labels []labelPair) []sample {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	res := make([]sample, 0, len(h.upperBounds)+3)
+	var cumulative uint64
+	for i, upperBound := range h.upperBounds {
+		cumulative += h.counts[i]
+		res = append(res, sample{
+			suffix: "_bucket",
+			labels: append(slices.Clip(labels), labelPair{"le", formatFloat(upperBound)}),
+			value:  float64(cumulative),
+		})
+	}
+	return append(res,
+		sample{suffix: "_bucket", labels: append(slices.Clip(labels), labelPair{"le", "+Inf"}), value: float64(h.count)},
+		sample{suffix: "_sum", labels: labels, value: h.sum},
+		sample{suffix: "_count", labels: labels, value: float64(h.count)},
+	)
+}
+
+type metric interface {
+	collect(labels []labelPair) []sample
+}
+
+type vecEntry[M metric] struct {
+	labels []labelPair
+	metric M
+}
+
+// vec is a set of metrics with the same name partitioned by the label values.
+type vec[M metric] struct {
+	labelNames []string
+	newMetric  func() M
+
+	mtx     sync.RWMutex
+	entries map[string]*vecEntry[M]
+}
+
+func newVec[M metric](labelNames []string, newMetric func() M) *vec[M] {
+	return &vec[M]{
+		labelNames: labelNames,
+		newMetric:  newMetric,
+		entries:    make(map[string]*vecEntry[M]),
+	}
+}
+
+// With returns the metric for the given label values, creating it if needed.
+// It panics if the number of values differs from the number of label names.
+func (v *vec[M]) With(labelValues ...string) M {
+	if len(labelValues) != len(v.labelNames) {
+		panic("metrics: inconsistent label cardinality")
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	v.mtx.RLock()
+	e, ok := v.entries[key]
+	v.mtx.RUnlock()
+	if ok {
+		return e.metric
+	}
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if e, ok := v.entries[key]; ok {
+		return e.metric
+	}
+	labels := make([]labelPair, len(labelValues))
+	for i, value := range labelValues {
+		labels[i] = labelPair{v.labelNames[i], value}
+	}
+	e = &vecEntry[M]{labels: labels, metric: v.newMetric()}
+	v.entries[key] = e
+	return e.metric
+}
+
+// Delete removes the metric with the given label values.
+func (v *vec[M]) Delete(labelValues ...string) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	delete(v.entries, strings.Join(labelValues, "\xff"))
+}
+
+func (v *vec[M]) collect() []sample {
+	v.mtx.RLock()
+	keys := make([]string, 0, len(v.entries))
+	for key := range v.entries {
+		keys = append(keys, key)
+	}
+	v.mtx.RUnlock()
+	slices.Sort(keys)
+
+	var res []sample
+	for _, key := range keys {
+		v.mtx.RLock()
+		e, ok := v.entries[key]
+		v.mtx.RUnlock()
+		if ok {
+			res = append(res, e.metric.collect(e.labels)...)
+		}
+	}
+	return res
+}
+
+func (v *vec[M]) sameLabels(labelNames []string) bool {
+	return slices.Equal(v.labelNames, labelNames)
+}
+
+type CounterVec struct {
+	*vec[*Counter]
+}
+
+type GaugeVec struct {
+	*vec[*Gauge]
+}
+
+type HistogramVec struct {
+	*vec[*Histogram]
+	buckets []float64
+}
+
+// valueFunc is a metric that is evaluated on every scrape.
+type valueFunc struct {
+	labels []labelPair
+	f      func() float64
+}
+
+func newValueFunc(labels Labels, f func() float64) *valueFunc {
+	pairs := make([]labelPair, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, labelPair{name, value})
+	}
+	slices.SortFunc(pairs, func(a, b labelPair) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return &valueFunc{labels: pairs, f: f}
+}
+
+func (v *valueFunc) collect() []sample {
+	return []sample{{labels: v.labels, value: v.f()}}
+}
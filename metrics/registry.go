@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+type collector interface {
+	collect() []sample
+}
+
+type family struct {
+	name       string
+	help       string
+	typ        metricType
+	collectors []collector
+}
+
+func (f *family) collect() []sample {
+	var res []sample
+	for _, c := range f.collectors {
+		res = append(res, c.collect()...)
+	}
+	return res
+}
+
+// Registry holds the metrics exposed by Handler.
+// Metric constructors return the already registered metric if the name, type and label names match,
+// so the same metric can be obtained from different places.
+type Registry struct {
+	mtx      sync.Mutex
+	families map[string]*family
+}
+
+// Default is the registry used by the integrations when nil registry is passed.
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+func orDefault(r *Registry) *Registry {
+	if r == nil {
+		return Default
+	}
+	return r
+}
+
+func (r *Registry) family(name, help string, typ metricType) *family {
+	if !validName(name) {
+		panic(fmt.Errorf("metrics: invalid metric name %q", name))
+	}
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ}
+		r.families[name] = f
+	}
+	if f.typ != typ {
+		panic(fmt.Errorf("metrics: %s is already registered as %s", name, f.typ))
+	}
+	return f
+}
+
+func getOrCreateVec[V interface{ sameLabels([]string) bool }](r *Registry, name, help string, typ metricType, labelNames []string, newVec func() V) V {
+	for _, label := range labelNames {
+		if !validLabelName(label) {
+			panic(fmt.Errorf("metrics: invalid label name %q", label))
+		}
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	f := r.family(name, help, typ)
+	for _, c := range f.collectors {
+		if v, ok := c.(V); ok && v.sameLabels(labelNames) {
+			return v
+		}
+	}
+	if len(f.collectors) > 0 {
+		panic(fmt.Errorf("metrics: %s is already registered with other labels", name))
+	}
+	v := newVec()
+	f.collectors = append(f.collectors, any(v).(collector))
+	return v
+}
+
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) CounterVec {
+	return getOrCreateVec(r, name, help, typeCounter, labelNames, func() CounterVec {
+		return CounterVec{newVec(labelNames, func() *Counter { return new(Counter) })}
+	})
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) GaugeVec {
+	return getOrCreateVec(r, name, help, typeGauge, labelNames, func() GaugeVec {
+		return GaugeVec{newVec(labelNames, func() *Gauge { return new(Gauge) })}
+	})
+}
+
+// NewHistogramVec creates a histogram. If buckets are empty, DefBuckets are used.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = slices.Sorted(slices.Values(buckets))
+	return getOrCreateVec(r, name, help, typeHistogram, labelNames, func() HistogramVec {
+		return HistogramVec{newVec(labelNames, func() *Histogram { return newHistogram(buckets) }), buckets}
+	})
+}
+
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.NewCounterVec(name, help).With()
+}
+
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return r.NewGaugeVec(name, help).With()
+}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	return r.NewHistogramVec(name, help, buckets).With()
+}
+
+// GaugeFunc registers a gauge evaluated on every scrape.
+// Several functions can be registered with the same name and different labels.
+func (r *Registry) GaugeFunc(name, help string, labels Labels, f func() float64) {
+	r.registerFunc(name, help, typeGauge, labels, f)
+}
+
+// CounterFunc registers a counter evaluated on every scrape. f must return a monotonically increasing value.
+// Several functions can be registered with the same name and different labels.
+func (r *Registry) CounterFunc(name, help string, labels Labels, f func() float64) {
+	r.registerFunc(name, help, typeCounter, labels, f)
+}
+
+func (r *Registry) registerFunc(name, help string, typ metricType, labels Labels, f func() float64) {
+	for label := range labels {
+		if !validLabelName(label) {
+			panic(fmt.Errorf("metrics: invalid label name %q", label))
+		}
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	fam := r.family(name, help, typ)
+	for _, c := range fam.collectors {
+		if _, ok := c.(*valueFunc); !ok {
+			panic(fmt.Errorf("metrics: %s is already registered as a vector", name))
+		}
+	}
+	fam.collectors = append(fam.collectors, newValueFunc(labels, f))
+}
+
+func (r *Registry) sortedFamilies() []*family {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	res := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		res = append(res, &family{name: f.name, help: f.help, typ: f.typ, collectors: slices.Clone(f.collectors)})
+	}
+	slices.SortFunc(res, func(a, b *family) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return res
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !(c == '_' || c == ':' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func validLabelName(name string) bool {
+	return validName(name) && name != "le" && !strings.Contains(name, ":") && !strings.HasPrefix(name, "__")
+}
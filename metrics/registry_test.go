@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bldsoft/gost/breaker"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteText(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounterVec("requests_total", "Total requests.", "code").With("200").Add(3)
+	reg.NewCounterVec("requests_total", "Total requests.", "code").With("500").Inc()
+	reg.NewGauge("temperature", "Current\ntemperature.").Set(-1.5)
+	reg.NewHistogram("latency_seconds", "", []float64{1, 0.1}).Observe(0.5)
+	reg.GaugeFunc("info", "", Labels{"version": `1.0 "beta"`}, func() float64 { return 1 })
+
+	var sb strings.Builder
+	require.NoError(t, reg.WriteText(&sb))
+	assert.Equal(t, `# TYPE info gauge
+info{version="1.0 \"beta\""} 1
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 0
+latency_seconds_bucket{le="1"} 1
+latency_seconds_bucket{le="+Inf"} 1
+latency_seconds_sum 0.5
+latency_seconds_count 1
+# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{code="200"} 3
+requests_total{code="500"} 1
+# HELP temperature Current\ntemperature.
+# TYPE temperature gauge
+temperature -1.5
+`, sb.String())
+}
+
+func TestRegistryConflicts(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounterVec("x", "", "a")
+	assert.Panics(t, func() { reg.NewGaugeVec("x", "", "a") })
+	assert.Panics(t, func() { reg.NewCounterVec("x", "", "b") })
+	assert.Panics(t, func() { reg.NewCounterVec("x", "", "a").With("1", "2") })
+	assert.Panics(t, func() { reg.NewCounter("invalid-name", "") })
+	assert.Panics(t, func() { reg.NewCounterVec("y", "", "le") })
+}
+
+func TestRequestLogger(t *testing.T) {
+	reg := NewRegistry()
+	r := chi.NewRouter()
+	r.Use(RequestLogger(reg))
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	r.Route("/metrics", NewController(reg).Mount)
+
+	for _, path := range []string{"/items/1", "/items/2", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `http_requests_total{method="GET",route="/items/{id}",code="418"} 2`)
+	assert.Contains(t, rec.Body.String(), `http_requests_total{method="GET",route="unmatched",code="404"} 1`)
+	assert.Contains(t, rec.Body.String(), `http_request_duration_seconds_count{method="GET",route="/items/{id}"} 2`)
+}
+
+func TestInstrumentBreaker(t *testing.T) {
+	reg := NewRegistry()
+	var called bool
+	cb := breaker.NewCircuitBreaker(InstrumentBreaker(reg, breaker.Settings{
+		Name:          "db",
+		OnStateChange: func(string, breaker.State, breaker.State) { called = true },
+	}))
+	for range 6 {
+		_, _ = cb.Execute(func() (any, error) { return nil, assert.AnError })
+	}
+
+	var sb strings.Builder
+	require.NoError(t, reg.WriteText(&sb))
+	assert.True(t, called)
+	assert.Contains(t, sb.String(), `circuit_breaker_state{name="db"} 2`)
+	assert.Contains(t, sb.String(), `circuit_breaker_transitions_total{name="db",from="closed",to="open"} 1`)
+}
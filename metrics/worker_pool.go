@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	workerpool "github.com/bldsoft/gost/utils/worker_pool"
+)
+
+// RegisterWorkerPool exposes the worker pool utilization with the pool label set to name.
+// If registry is nil, Default is used.
+func RegisterWorkerPool(registry *Registry, name string, wp *workerpool.WorkerPool) {
+	registry = orDefault(registry)
+	labels := Labels{"pool": name}
+	registry.GaugeFunc("worker_pool_workers", "Number of workers in the pool.", labels, func() float64 {
+		return float64(wp.WorkerN())
+	})
+	registry.GaugeFunc("worker_pool_busy_workers", "Number of workers executing a task.", labels, func() float64 {
+		return float64(wp.BusyN())
+	})
+	registry.GaugeFunc("worker_pool_queued_tasks", "Number of tasks waiting for a worker.", labels, func() float64 {
+		return float64(wp.QueueLen())
+	})
+}
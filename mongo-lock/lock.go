@@ -160,6 +160,27 @@ func (c *Client) CreateIndexes(ctx context.Context) error {
 // provided lockId. Additional details about the lock can be supplied via
 // LockDetails.
 func (c *Client) XLock(ctx context.Context, resourceName, lockId string, ld LockDetails) error {
+	_, err := c.xLock(ctx, resourceName, lockId, ld, bson.M{})
+	return err
+}
+
+// XLockFenced is XLock that also returns a fencing token: a counter stored in
+// the resource and incremented on every exclusive lock acquisition. Pass the
+// token to the protected storage, so it can reject the writes of a holder
+// whose lock has expired.
+func (c *Client) XLockFenced(ctx context.Context, resourceName, lockId string, ld LockDetails) (int64, error) {
+	doc, err := c.xLock(ctx, resourceName, lockId, ld, bson.M{"$inc": bson.M{"fence": int64(1)}})
+	if err != nil {
+		return 0, err
+	}
+	fence, ok := doc["fence"].(int64)
+	if !ok {
+		return 0, errors.New("unexpected fence value")
+	}
+	return fence, nil
+}
+
+func (c *Client) xLock(ctx context.Context, resourceName, lockId string, ld LockDetails, change bson.M) (map[string]interface{}, error) {
 	currentTime := time.Now()
 	selector := bson.M{
 		"resource": resourceName,
@@ -170,14 +191,13 @@ func (c *Client) XLock(ctx context.Context, resourceName, lockId string, ld Lock
 		"shared.count": 0,
 	}
 
-	r := bson.M{"$set": &resource{
+	change["$set"] = &resource{
 		Name:      resourceName,
 		Exclusive: lockFromDetails(lockId, ld),
 		Shared: sharedLocks{
 			Count: 0,
 			Locks: []lock{},
 		},
-	},
 	}
 
 	// One of three things will happen when we run this change (upsert).
@@ -190,20 +210,20 @@ func (c *Client) XLock(ctx context.Context, resourceName, lockId string, ld Lock
 	result := c.collection.FindOneAndUpdate(
 		ctx,
 		selector,
-		r,
+		change,
 		options.FindOneAndUpdate().SetUpsert(UPSERT).SetReturnDocument(ReturnDoc))
 
 	rr := map[string]interface{}{}
 	err := result.Decode(rr)
 	if err != nil {
 		if isDup(err) {
-			return ErrAlreadyLocked
+			return nil, ErrAlreadyLocked
 		}
-		return err
+		return nil, err
 	}
 
 	// Acquired lock.
-	return nil
+	return rr, nil
 }
 
 // SLock creates a shared lock on a resource and associates it with the provided
@@ -51,6 +51,7 @@ func (db *Storage) Connect() {
 	monitor := &event.PoolMonitor{Event: db.poolEventMonitor}
 	clientOptions := options.Client().ApplyURI(db.config.Server.String()).
 		SetPoolMonitor(monitor).
+		SetMonitor(newCommandMonitor()).
 		SetServerSelectionTimeout(timeout)
 
 	var err error
@@ -0,0 +1,55 @@
+package mongo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bldsoft/gost/tracing"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+// commandTracer creates a child span for every command sent within a traced context,
+// so the repository queries are attached to the request span.
+type commandTracer struct {
+	spans sync.Map // request id -> *tracing.Span
+}
+
+func newCommandMonitor() *event.CommandMonitor {
+	t := &commandTracer{}
+	return &event.CommandMonitor{
+		Started:   t.started,
+		Succeeded: t.succeeded,
+		Failed:    t.failed,
+	}
+}
+
+func (t *commandTracer) started(ctx context.Context, ev *event.CommandStartedEvent) {
+	if tracing.SpanFromContext(ctx) == nil {
+		return
+	}
+	attrs := map[string]any{
+		"db.system":    "mongodb",
+		"db.name":      ev.DatabaseName,
+		"db.operation": ev.CommandName,
+	}
+	if collection, ok := ev.Command.Lookup(ev.CommandName).StringValueOK(); ok {
+		attrs["db.mongodb.collection"] = collection
+	}
+	_, span := tracing.Start(ctx, "mongo."+ev.CommandName, tracing.WithKind(tracing.SpanKindClient), tracing.WithAttributes(attrs))
+	t.spans.Store(ev.RequestID, span)
+}
+
+func (t *commandTracer) succeeded(_ context.Context, ev *event.CommandSucceededEvent) {
+	t.finish(ev.RequestID, nil)
+}
+
+func (t *commandTracer) failed(_ context.Context, ev *event.CommandFailedEvent) {
+	t.finish(ev.RequestID, ev.Failure)
+}
+
+func (t *commandTracer) finish(requestID int64, err error) {
+	if span, ok := t.spans.LoadAndDelete(requestID); ok {
+		span.(*tracing.Span).RecordError(err)
+		span.(*tracing.Span).End()
+	}
+}
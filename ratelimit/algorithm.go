@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Result is the decision on a request.
+type Result struct {
+	Allowed bool
+	// Limit is the quota of the key.
+	Limit int
+	// Remaining is the number of the requests left.
+	Remaining int
+	// Reset is the time until the quota is fully restored.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, it's 0 if the request is allowed.
+	RetryAfter time.Duration
+}
+
+// Algorithm decides whether a request is allowed given the state of its key.
+// The state is stored by the Limiter, in memory or in the distributed cache, so the algorithms are stateless.
+type Algorithm interface {
+	// Take handles a request made at now. The state is nil for a new key, the returned state replaces it.
+	Take(state []byte, now time.Time) (newState []byte, res Result, err error)
+	// TTL is how long the state of an idle key matters, after that the key is new.
+	TTL() time.Duration
+}
+
+// TokenBucket gives a key up to Burst requests at once and one more every Interval.
+type TokenBucket struct {
+	Burst    int
+	Interval time.Duration
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func (b bucket) marshal() []byte {
+	res := make([]byte, 16)
+	binary.BigEndian.PutUint64(res, math.Float64bits(b.tokens))
+	binary.BigEndian.PutUint64(res[8:], uint64(b.updatedAt.UnixNano()))
+	return res
+}
+
+func unmarshalBucket(data []byte) (bucket, error) {
+	if len(data) != 16 {
+		return bucket{}, fmt.Errorf("invalid token bucket: %x", data)
+	}
+	return bucket{
+		tokens:    math.Float64frombits(binary.BigEndian.Uint64(data)),
+		updatedAt: time.Unix(0, int64(binary.BigEndian.Uint64(data[8:]))),
+	}, nil
+}
+
+func (tb TokenBucket) burst() float64 {
+	return float64(max(tb.Burst, 1))
+}
+
+// Take refills the bucket and takes a token from it if there is one.
+func (tb TokenBucket) Take(state []byte, now time.Time) ([]byte, Result, error) {
+	b := bucket{tokens: tb.burst()}
+	if state != nil {
+		var err error
+		if b, err = unmarshalBucket(state); err != nil {
+			return nil, Result{}, err
+		}
+	}
+
+	if !b.updatedAt.IsZero() && tb.Interval > 0 {
+		b.tokens += float64(now.Sub(b.updatedAt)) / float64(tb.Interval)
+	}
+	b.tokens = min(b.tokens, tb.burst())
+	b.updatedAt = now
+	res := Result{Limit: int(tb.burst())}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) * float64(tb.Interval))
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = time.Duration((tb.burst() - b.tokens) * float64(tb.Interval))
+	return b.marshal(), res, nil
+}
+
+// TTL is the time an unused bucket takes to fill up, after that it's the same as a new one.
+func (tb TokenBucket) TTL() time.Duration {
+	return max(time.Duration(tb.burst()*float64(tb.Interval)), time.Second)
+}
+
+// SlidingWindowLog allows Limit requests within any Window. It keeps the time of every request in the window,
+// so it's exact, unlike the token bucket it doesn't allow a burst right after the quota is used, but its state grows with Limit.
+type SlidingWindowLog struct {
+	Limit  int
+	Window time.Duration
+}
+
+func unmarshalLog(data []byte) ([]int64, error) {
+	if len(data)%8 != 0 {
+		return nil, fmt.Errorf("invalid sliding window log: %x", data)
+	}
+	res := make([]int64, 0, len(data)/8+1)
+	for i := 0; i < len(data); i += 8 {
+		res = append(res, int64(binary.BigEndian.Uint64(data[i:])))
+	}
+	return res, nil
+}
+
+func marshalLog(log []int64) []byte {
+	res := make([]byte, 8*len(log))
+	for i, t := range log {
+		binary.BigEndian.PutUint64(res[8*i:], uint64(t))
+	}
+	return res
+}
+
+// Take drops the requests that left the window and logs the request if the window isn't full.
+func (w SlidingWindowLog) Take(state []byte, now time.Time) ([]byte, Result, error) {
+	log, err := unmarshalLog(state)
+	if err != nil {
+		return nil, Result{}, err
+	}
+	limit := max(w.Limit, 1)
+	windowStart := now.Add(-w.Window).UnixNano()
+	for len(log) > 0 && log[0] <= windowStart {
+		log = log[1:]
+	}
+
+	res := Result{Limit: limit}
+	if len(log) < limit {
+		log = append(log, now.UnixNano())
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration(log[0] - windowStart)
+	}
+	res.Remaining = limit - len(log)
+	res.Reset = time.Duration(log[len(log)-1] - windowStart)
+	return marshalLog(log), res, nil
+}
+
+func (w SlidingWindowLog) TTL() time.Duration {
+	return max(w.Window, time.Second)
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bldsoft/gost/auth"
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/repository"
+	"github.com/bldsoft/gost/server/middleware"
+)
+
+// KeyFunc returns the key the request is counted against. The requests with an empty key aren't limited.
+type KeyFunc func(r *http.Request) string
+
+// IPKey limits the requests per client IP. The IP is set by middleware.RealIP, the remote address is used without it.
+func IPKey(r *http.Request) string {
+	ip := middleware.GetRealIP(r.Context())
+	if ip == "" {
+		ip = middleware.TrimPort(r.RemoteAddr)
+	}
+	return "ip:" + ip
+}
+
+// UserKey limits the requests per authenticated user, identified by ID or login.
+// The requests without a user aren't limited.
+func UserKey(r *http.Request) string {
+	switch user := auth.UserFromContext(r.Context()).(type) {
+	case repository.IIDProvider:
+		if !user.IsZeroID() {
+			return "user:" + user.StringID()
+		}
+	case auth.Authenticable:
+		return "user:" + user.Login()
+	}
+	return ""
+}
+
+// HeaderKey limits the requests per value of the header, e.g. an API key.
+// The value is hashed, so the secrets aren't stored in the cache. The requests without the header aren't limited.
+func HeaderKey(name string) KeyFunc {
+	return func(r *http.Request) string {
+		value := r.Header.Get(name)
+		if value == "" {
+			return ""
+		}
+		hash := sha256.Sum256([]byte(value))
+		return "header:" + name + ":" + hex.EncodeToString(hash[:])
+	}
+}
+
+// FirstKey returns the first non-empty key, e.g. FirstKey(HeaderKey("X-API-Key"), UserKey, IPKey).
+func FirstKey(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		for _, key := range keys {
+			if k := key(r); k != "" {
+				return k
+			}
+		}
+		return ""
+	}
+}
+
+// Middleware limits the requests per key. It sets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers,
+// the requests over the limit are responded with 429 Too Many Requests and Retry-After.
+// If the limit can't be checked, the request is let through.
+func Middleware(l *Limiter, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			res, err := l.Allow(r.Context(), k)
+			if err != nil {
+				log.FromContext(r.Context()).ErrorWithFields(log.Fields{"key": k, "error": err}, "Failed to check rate limit")
+				next.ServeHTTP(w, r)
+				return
+			}
+			SetHeaders(w.Header(), res)
+			if !res.Allowed {
+				w.Header().Set("Retry-After", seconds(res.RetryAfter))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetHeaders sets the RateLimit-* headers of the result.
+func SetHeaders(h http.Header, res Result) {
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", seconds(res.Reset))
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
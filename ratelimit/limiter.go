@@ -0,0 +1,130 @@
+// Package ratelimit limits the requests per key, e.g. per API key, user or IP, with a token bucket or a sliding window log.
+// The limits are shared across the replicas through the distributed cache or kept in memory.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	cache "github.com/bldsoft/gost/cache/v2"
+	"github.com/bldsoft/gost/log"
+)
+
+const defaultKeyPrefix = "ratelimit:"
+
+type localState struct {
+	state     []byte
+	expiresAt time.Time
+}
+
+// Limiter keeps the state of the algorithm per key. If the cache is set, the state is kept there and updated
+// with CompareAndSwap, so the limits are shared across the replicas. If the cache fails, the limiter falls back
+// to the state kept in memory, i.e. to a limit per replica.
+type Limiter struct {
+	alg    Algorithm
+	prefix string
+	rep    cache.IDistrCacheRepository
+
+	mtx     sync.Mutex
+	local   map[string]localState
+	sweepAt time.Time
+}
+
+func NewLimiter(alg Algorithm) *Limiter {
+	return &Limiter{
+		alg:    alg,
+		prefix: defaultKeyPrefix,
+		local:  make(map[string]localState),
+	}
+}
+
+// SetCache makes the limiter keep the state in the distributed cache.
+func (l *Limiter) SetCache(rep cache.IDistrCacheRepository) *Limiter {
+	l.rep = rep
+	return l
+}
+
+// SetKeyPrefix sets the prefix of the cache keys, "ratelimit:" by default.
+// The limiters with different quotas sharing the cache must have different prefixes.
+func (l *Limiter) SetKeyPrefix(prefix string) *Limiter {
+	l.prefix = prefix
+	return l
+}
+
+// Allow handles a request of the key.
+func (l *Limiter) Allow(ctx context.Context, key string) (Result, error) {
+	return l.AllowAt(ctx, key, time.Now())
+}
+
+// AllowAt handles a request of the key made at the given time.
+func (l *Limiter) AllowAt(ctx context.Context, key string, now time.Time) (Result, error) {
+	if l.rep != nil {
+		res, err := l.allowDistributed(key, now)
+		if err == nil {
+			return res, nil
+		}
+		log.FromContext(ctx).WarnWithFields(log.Fields{"key": key, "error": err}, "Failed to check the rate limit in the cache, checking it locally")
+	}
+	return l.allowLocal(key, now)
+}
+
+func (l *Limiter) allowLocal(key string, now time.Time) (Result, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.sweep(now)
+
+	var state []byte
+	if s, ok := l.local[key]; ok && now.Before(s.expiresAt) {
+		state = s.state
+	}
+	state, res, err := l.alg.Take(state, now)
+	if err != nil {
+		return Result{}, err
+	}
+	l.local[key] = localState{state: state, expiresAt: now.Add(l.alg.TTL())}
+	return res, nil
+}
+
+// sweep drops the expired states once per TTL, so the idle keys don't pile up.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Before(l.sweepAt) {
+		return
+	}
+	for key, s := range l.local {
+		if !now.Before(s.expiresAt) {
+			delete(l.local, key)
+		}
+	}
+	l.sweepAt = now.Add(l.alg.TTL())
+}
+
+func (l *Limiter) allowDistributed(key string, now time.Time) (Result, error) {
+	key = l.prefix + key
+	ttl := l.alg.TTL()
+	for {
+		var res Result
+		err := l.rep.CompareAndSwap(key, func(item *cache.Item) (*cache.Item, error) {
+			state, r, err := l.alg.Take(item.Value, now)
+			if err != nil {
+				return nil, err
+			}
+			res = r
+			return &cache.Item{Value: state, TTL: ttl}, nil
+		})
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			return res, err
+		}
+
+		state, res, err := l.alg.Take(nil, now)
+		if err != nil {
+			return Result{}, err
+		}
+		err = l.rep.Add(key, state, cache.WithTTL(ttl))
+		if !errors.Is(err, cache.ErrExists) {
+			return res, err
+		}
+		// added concurrently, take the request from it
+	}
+}
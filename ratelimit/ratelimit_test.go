@@ -0,0 +1,196 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bldsoft/gost/auth"
+	cache "github.com/bldsoft/gost/cache/v2"
+	"github.com/bldsoft/gost/cache/v2/redis"
+	"github.com/bldsoft/gost/server/middleware"
+	goredis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedisRepository(t *testing.T) cache.IDistrCacheRepository {
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return redis.NewRepository(redis.NewStorageFromClient(client, ""), 0)
+}
+
+func TestTokenBucket(t *testing.T) {
+	tb := TokenBucket{Burst: 2, Interval: time.Minute}
+	now := time.Now()
+
+	state, res, err := tb.Take(nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute}, res)
+	state, res, err = tb.Take(state, now)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Minute}, res)
+	state, res, err = tb.Take(state, now.Add(15*time.Second))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 45*time.Second, res.RetryAfter)
+
+	_, res, err = tb.Take(state, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "refilled")
+
+	_, _, err = tb.Take([]byte{1}, now)
+	assert.Error(t, err)
+}
+
+func TestSlidingWindowLog(t *testing.T) {
+	w := SlidingWindowLog{Limit: 2, Window: time.Minute}
+	now := time.Now()
+
+	state, res, err := w.Take(nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute}, res)
+	state, res, err = w.Take(state, now.Add(20*time.Second))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	state, res, err = w.Take(state, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 30*time.Second, res.RetryAfter, "until the first request leaves the window")
+
+	state, res, err = w.Take(state, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "the first request left the window")
+	_, res, err = w.Take(state, now.Add(70*time.Second))
+	require.NoError(t, err)
+	assert.False(t, res.Allowed, "no burst after the quota is used")
+}
+
+func TestLimiter(t *testing.T) {
+	rep := newRedisRepository(t)
+	algorithms := map[string]Algorithm{
+		"token bucket":       TokenBucket{Burst: 2, Interval: time.Minute},
+		"sliding window log": SlidingWindowLog{Limit: 2, Window: time.Minute},
+	}
+	for algName, alg := range algorithms {
+		limiters := map[string]func() *Limiter{
+			"local": func() *Limiter { return NewLimiter(alg) },
+			"cache": func() *Limiter { return NewLimiter(alg).SetCache(rep).SetKeyPrefix(algName + ":") },
+		}
+		for name, newLimiter := range limiters {
+			t.Run(algName+"/"+name, func(t *testing.T) {
+				l, other := newLimiter(), newLimiter()
+				now := time.Now()
+				allow := func(l *Limiter, key string, at time.Time) bool {
+					res, err := l.AllowAt(context.Background(), name+key, at)
+					require.NoError(t, err)
+					return res.Allowed
+				}
+				assert.True(t, allow(l, "a", now))
+				assert.True(t, allow(l, "a", now))
+				assert.False(t, allow(l, "a", now))
+				if name == "cache" {
+					assert.False(t, allow(other, "a", now), "the limit is shared")
+				} else {
+					assert.True(t, allow(other, "a", now))
+				}
+				assert.True(t, allow(l, "b", now), "limits are per key")
+				assert.True(t, allow(l, "a", now.Add(time.Minute)), "restored")
+			})
+		}
+	}
+}
+
+type failingCache struct {
+	cache.IDistrCacheRepository
+}
+
+func (failingCache) CompareAndSwap(key string, handler func(value *cache.Item) (*cache.Item, error), sleepDur ...time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func TestLimiterLocalFallback(t *testing.T) {
+	l := NewLimiter(TokenBucket{Burst: 1, Interval: time.Minute}).SetCache(failingCache{})
+	res, err := l.Allow(context.Background(), "a")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	res, err = l.Allow(context.Background(), "a")
+	require.NoError(t, err)
+	assert.False(t, res.Allowed, "limited locally")
+}
+
+func TestLimiterSweep(t *testing.T) {
+	l := NewLimiter(TokenBucket{Burst: 1, Interval: time.Second})
+	now := time.Now()
+	_, err := l.AllowAt(context.Background(), "a", now)
+	require.NoError(t, err)
+	_, err = l.AllowAt(context.Background(), "b", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.NotContains(t, l.local, "a")
+	assert.Contains(t, l.local, "b")
+}
+
+type testUser struct{ login string }
+
+func (u testUser) Login() string      { return u.login }
+func (u testUser) Password() string   { return "" }
+func (u testUser) SetPassword(string) {}
+func (u testUser) Active() error      { return nil }
+
+func TestKeys(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	assert.Equal(t, "ip:10.0.0.1", IPKey(r))
+	assert.Equal(t, "ip:10.0.0.2", IPKey(r.WithContext(middleware.WithRealIP(r.Context(), "10.0.0.2"))))
+
+	assert.Empty(t, UserKey(r))
+	userReq := r.WithContext(context.WithValue(r.Context(), auth.UserEntryCtxKey, testUser{login: "bob"}))
+	assert.Equal(t, "user:bob", UserKey(userReq))
+
+	apiKey := HeaderKey("X-API-Key")
+	assert.Empty(t, apiKey(r))
+	r.Header.Set("X-API-Key", "secret")
+	assert.NotContains(t, apiKey(r), "secret")
+
+	key := FirstKey(apiKey, UserKey, IPKey)
+	assert.Equal(t, apiKey(r), key(r))
+	r.Header.Del("X-API-Key")
+	assert.Equal(t, "ip:10.0.0.1", key(r))
+}
+
+func TestMiddleware(t *testing.T) {
+	l := NewLimiter(TokenBucket{Burst: 2, Interval: 30 * time.Second})
+	handler := Middleware(l, HeaderKey("X-API-Key"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if apiKey != "" {
+			r.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("a")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+
+	serve("a")
+	w = serve("a")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, serve("b").Code, "limits are per key")
+	w = serve("")
+	assert.Equal(t, http.StatusOK, w.Code, "requests without a key aren't limited")
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
@@ -0,0 +1,8 @@
+package regexes
+
+import "embed"
+
+// UA contains user agent rules: ua/browser.yaml, ua/os.yaml and ua/device.yaml.
+//
+//go:embed ua/*.yaml
+var UA embed.FS
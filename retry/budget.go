@@ -0,0 +1,97 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultBudgetRatio      = 0.2
+	defaultBudgetWindow     = 10 * time.Second
+	defaultBudgetMinRetries = 10
+	budgetBuckets           = 10
+)
+
+// Budget limits the retries to a ratio of the calls made during the last window, e.g. to 20%,
+// so the retries can't multiply the load of a failing service.
+// MinRetries retries per window are always allowed, so the rarely called services are still retried.
+// A Budget can be shared by several policies.
+type Budget struct {
+	ratio      float64
+	window     time.Duration
+	minRetries int
+
+	mtx     sync.Mutex
+	buckets [budgetBuckets]budgetBucket
+}
+
+type budgetBucket struct {
+	epoch   int64
+	calls   int
+	retries int
+}
+
+// NewBudget creates a budget allowing ratio retries per call, 0.2 if ratio isn't positive.
+func NewBudget(ratio float64) *Budget {
+	if ratio <= 0 {
+		ratio = defaultBudgetRatio
+	}
+	return &Budget{
+		ratio:      ratio,
+		window:     defaultBudgetWindow,
+		minRetries: defaultBudgetMinRetries,
+	}
+}
+
+// SetWindow sets the period the calls and the retries are counted over, 10s by default.
+func (b *Budget) SetWindow(d time.Duration) *Budget {
+	b.window = d
+	return b
+}
+
+// SetMinRetries sets the number of the retries per window allowed regardless of the ratio, 10 by default.
+func (b *Budget) SetMinRetries(n int) *Budget {
+	b.minRetries = n
+	return b
+}
+
+func (b *Budget) bucket(now time.Time) (*budgetBucket, int64) {
+	span := max(b.window/budgetBuckets, 1)
+	epoch := now.UnixNano() / int64(span)
+	bucket := &b.buckets[epoch%budgetBuckets]
+	if bucket.epoch != epoch {
+		*bucket = budgetBucket{epoch: epoch}
+	}
+	return bucket, epoch
+}
+
+func (b *Budget) counts(epoch int64) (calls, retries int) {
+	for _, bucket := range b.buckets {
+		if bucket.epoch > epoch-budgetBuckets && bucket.epoch <= epoch {
+			calls += bucket.calls
+			retries += bucket.retries
+		}
+	}
+	return calls, retries
+}
+
+// onCall records a call, i.e. its first attempt.
+func (b *Budget) onCall(now time.Time) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	bucket, _ := b.bucket(now)
+	bucket.calls++
+}
+
+// withdraw records a retry if the budget allows it.
+func (b *Budget) withdraw(now time.Time) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	bucket, epoch := b.bucket(now)
+	calls, retries := b.counts(epoch)
+	if retries >= b.minRetries && float64(retries+1) > b.ratio*float64(calls) {
+		return false
+	}
+	bucket.retries++
+	return true
+}
@@ -0,0 +1,135 @@
+// Package retry retries failed calls and HTTP requests with exponential backoff and full jitter,
+// per-attempt timeouts, retry budgets and Retry-After handling.
+package retry
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// Policy configures the retries:
+//
+// MaxAttempts is the maximum number of attempts including the first one. If it's 0, it's 3.
+//
+// The delay before a retry is a random duration up to InitialBackoff * Multiplier^(retry - 1), capped by MaxBackoff
+// (exponential backoff with full jitter). The defaults are 100ms, 2 and 10s.
+//
+// AttemptTimeout, if set, limits the duration of each attempt.
+//
+// MaxRetryAfter limits the delay requested by the Retry-After header of an HTTP response,
+// the response isn't retried if the delay is longer. If it's 0, it's 1 minute.
+//
+// Budget, if set, limits the retries to a share of the calls, see Budget.
+//
+// Retryable classifies the error of an attempt of Do. If it's nil, DefaultRetryable is used.
+//
+// RetryableResponse classifies the result of an HTTP attempt. If it's nil, DefaultRetryableResponse is used.
+type Policy struct {
+	MaxAttempts       int
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	Multiplier        float64
+	AttemptTimeout    time.Duration
+	MaxRetryAfter     time.Duration
+	Budget            *Budget
+	Retryable         func(err error) bool
+	RetryableResponse func(req *http.Request, resp *http.Response, err error) bool
+}
+
+var DefaultPolicy = Policy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	MaxRetryAfter:  time.Minute,
+}
+
+func preparePolicy(p Policy) Policy {
+	p.MaxAttempts = cmp.Or(p.MaxAttempts, DefaultPolicy.MaxAttempts)
+	p.InitialBackoff = cmp.Or(p.InitialBackoff, DefaultPolicy.InitialBackoff)
+	p.MaxBackoff = cmp.Or(p.MaxBackoff, DefaultPolicy.MaxBackoff)
+	p.Multiplier = cmp.Or(p.Multiplier, DefaultPolicy.Multiplier)
+	p.MaxRetryAfter = cmp.Or(p.MaxRetryAfter, DefaultPolicy.MaxRetryAfter)
+	if p.Retryable == nil {
+		p.Retryable = DefaultRetryable
+	}
+	if p.RetryableResponse == nil {
+		p.RetryableResponse = DefaultRetryableResponse
+	}
+	return p
+}
+
+// Backoff returns the delay before the retry, retry is 1 for the first one.
+func (p Policy) Backoff(retry int) time.Duration {
+	p = preparePolicy(p)
+	ceiling := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(retry-1))
+	ceiling = min(ceiling, float64(p.MaxBackoff))
+	if ceiling < 1 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks the error as not retryable by DefaultRetryable.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// DefaultRetryable retries all errors except the Permanent ones and the context cancellation.
+func DefaultRetryable(err error) bool {
+	var permanent *permanentError
+	return !errors.As(err, &permanent) && !errors.Is(err, context.Canceled)
+}
+
+// DefaultRetryableResponse retries the idempotent requests on errors and 502, 503 and 504 responses,
+// and all requests on 429 responses. A request is idempotent if its method is
+// GET, HEAD, PUT, DELETE, OPTIONS or TRACE, or if it has the Idempotency-Key header.
+func DefaultRetryableResponse(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return isIdempotent(req) && DefaultRetryable(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(req)
+	}
+	return false
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,66 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// RetryAfter makes Do wait for the delay instead of the backoff before the next attempt.
+func RetryAfter(err error, delay time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, delay: delay}
+}
+
+// Do calls fn until it succeeds, its error isn't retryable, the attempts or the budget are exhausted or ctx is done.
+// The error of the last attempt is returned.
+func Do(ctx context.Context, policy Policy, fn func(ctx context.Context) error) error {
+	policy = preparePolicy(policy)
+	if policy.Budget != nil {
+		policy.Budget.onCall(time.Now())
+	}
+	for attempt := 1; ; attempt++ {
+		err := policy.attempt(ctx, fn)
+		if err == nil || attempt >= policy.MaxAttempts || ctx.Err() != nil || !policy.Retryable(err) {
+			return err
+		}
+
+		delay := policy.Backoff(attempt)
+		var retryAfter *retryAfterError
+		if errors.As(err, &retryAfter) {
+			if retryAfter.delay > policy.MaxRetryAfter {
+				return err
+			}
+			delay = retryAfter.delay
+		}
+		if !policy.withdraw() {
+			return err
+		}
+		if sleep(ctx, delay) != nil {
+			return err
+		}
+	}
+}
+
+func (p Policy) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p.AttemptTimeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.AttemptTimeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+func (p Policy) withdraw() bool {
+	return p.Budget == nil || p.Budget.withdraw(time.Now())
+}
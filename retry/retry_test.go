@@ -0,0 +1,163 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPolicy = Policy{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	for range 100 {
+		assert.LessOrEqual(t, p.Backoff(1), 100*time.Millisecond)
+		assert.LessOrEqual(t, p.Backoff(3), 400*time.Millisecond)
+		assert.LessOrEqual(t, p.Backoff(10), time.Second)
+	}
+}
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+	var calls int
+	err := Do(ctx, testPolicy, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = Do(ctx, testPolicy, func(ctx context.Context) error {
+		calls++
+		return errors.New("temporary")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 3, calls, "attempts are exhausted")
+
+	calls = 0
+	permanent := errors.New("permanent")
+	err = Do(ctx, testPolicy, func(ctx context.Context) error {
+		calls++
+		return Permanent(permanent)
+	})
+	assert.ErrorIs(t, err, permanent)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	err = Do(ctx, testPolicy, func(ctx context.Context) error {
+		calls++
+		return RetryAfter(errors.New("later"), time.Hour)
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls, "Retry-After is over the max")
+}
+
+func TestDoAttemptTimeout(t *testing.T) {
+	policy := testPolicy
+	policy.AttemptTimeout = 10 * time.Millisecond
+	var calls int
+	err := Do(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestBudget(t *testing.T) {
+	b := NewBudget(0.2).SetMinRetries(1)
+	now := time.Now()
+	for range 10 {
+		b.onCall(now)
+	}
+	assert.True(t, b.withdraw(now))
+	assert.True(t, b.withdraw(now))
+	assert.False(t, b.withdraw(now), "20% of 10 calls")
+
+	later := now.Add(defaultBudgetWindow)
+	assert.True(t, b.withdraw(later), "the min retries of the new window")
+	assert.False(t, b.withdraw(later))
+}
+
+func TestTransport(t *testing.T) {
+	var calls atomic.Int32
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		switch r.URL.Path {
+		case "/flaky":
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte("ok"))
+		case "/later":
+			calls.Add(1)
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			calls.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: NewTransport(nil, testPolicy)}
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/flaky", strings.NewReader("body"))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, []string{"body", "body"}, bodies, "body is rewound")
+
+	calls.Store(0)
+	resp, err = client.Post(srv.URL+"/unavailable", "text/plain", strings.NewReader("body"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, int32(1), calls.Load(), "POST isn't idempotent")
+
+	calls.Store(0)
+	resp, err = client.Get(srv.URL + "/unavailable")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+
+	calls.Store(0)
+	resp, err = client.Get(srv.URL + "/later")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, int32(1), calls.Load(), "Retry-After is over the max")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d, ok := parseRetryAfter("3", now)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, d)
+	d, ok = parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, d)
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+}
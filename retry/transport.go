@@ -0,0 +1,132 @@
+package retry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDrainSize is the max number of bytes read from a discarded response, so its connection can be reused.
+const maxDrainSize = 4096
+
+// NewTransport returns a RoundTripper retrying the requests sent with base according to the policy.
+func NewTransport(base http.RoundTripper, policy Policy) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, policy: preparePolicy(policy)}
+}
+
+type transport struct {
+	base   http.RoundTripper
+	policy Policy
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return RoundTrip(req, t.policy, t.base.RoundTrip)
+}
+
+// RoundTrip sends the request with send, retrying it according to the policy.
+// Every attempt sends a clone of the request, the body is rewound with req.GetBody,
+// so the requests with a body but without GetBody aren't retried.
+// The delay requested by the Retry-After header of a retried response is used instead of the backoff.
+func RoundTrip(req *http.Request, policy Policy, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	policy = preparePolicy(policy)
+	ctx := req.Context()
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if policy.Budget != nil {
+		policy.Budget.onCall(time.Now())
+	}
+	for attempt := 1; ; attempt++ {
+		attemptReq, err := attemptRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := policy.roundTrip(attemptReq, send)
+		if attempt >= policy.MaxAttempts || !rewindable || ctx.Err() != nil || !policy.RetryableResponse(req, resp, err) {
+			return resp, err
+		}
+
+		delay := policy.Backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if retryAfter > policy.MaxRetryAfter {
+					return resp, err
+				}
+				delay = retryAfter
+			}
+		}
+		if !policy.withdraw() {
+			return resp, err
+		}
+		if resp != nil {
+			drain(resp)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func attemptRequest(req *http.Request, attempt int) (*http.Request, error) {
+	res := req.Clone(req.Context())
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		res.Body = body
+	}
+	return res, nil
+}
+
+// roundTrip sends a single attempt. The attempt timeout lasts until the response body is closed.
+func (p Policy) roundTrip(req *http.Request, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if p.AttemptTimeout <= 0 {
+		return send(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), p.AttemptTimeout)
+	resp, err := send(req.WithContext(ctx))
+	if err != nil || resp == nil || resp.Body == nil {
+		cancel()
+		return resp, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func drain(resp *http.Response) {
+	if resp.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainSize))
+	_ = resp.Body.Close()
+}
+
+// parseRetryAfter parses the Retry-After header, either the delay in seconds or the date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
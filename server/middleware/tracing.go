@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/bldsoft/gost/tracing"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Tracing starts a server span for every request.
+// The span is a child of the W3C traceparent of the incoming request, if any.
+func Tracing(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracing.Start(ctx, "HTTP "+r.Method, tracing.WithKind(tracing.SpanKindServer), tracing.WithAttributes(map[string]any{
+			"http.method": r.Method,
+			"http.target": r.URL.Path,
+		}))
+		defer span.End()
+		if reqID := middleware.GetReqID(ctx); reqID != "" {
+			span.SetAttribute("request_id", reqID)
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			// route pattern is known only after routing
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetName(fmt.Sprintf("HTTP %s %s", r.Method, rctx.RoutePattern()))
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttribute("http.status_code", status)
+			if status >= http.StatusInternalServerError {
+				span.RecordError(fmt.Errorf("%d %s", status, http.StatusText(status)))
+			}
+		}()
+		next.ServeHTTP(ww, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(fn)
+}
@@ -12,6 +12,8 @@ import (
 
 	"github.com/bldsoft/gost/log"
 	gost_middleware "github.com/bldsoft/gost/server/middleware"
+	"github.com/bldsoft/gost/tracing"
+	"github.com/bldsoft/gost/utils/exporter"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -87,6 +89,15 @@ func (s *Server) SetMiddlewares(middlewares ...func(http.Handler) http.Handler)
 	return s
 }
 
+// UseTracing starts a server span for every request and sets the default tracer, so the gost integrations
+// create the child spans. The spans are exported with e, nil e only propagates the trace context.
+// The tracing middleware is appended to the common ones, call it after UseDefaultMiddlewares or SetMiddlewares.
+func (s *Server) UseTracing(e exporter.Exporter[tracing.SpanData]) *Server {
+	tracing.SetDefault(tracing.NewTracer(s.config.ServiceName, e))
+	s.commonMiddlewares = append(s.commonMiddlewares, gost_middleware.Tracing)
+	return s
+}
+
 func (s *Server) SetRouterWrapper(middleware func(http.Handler) http.Handler) *Server {
 	s.routerWrapper = middleware
 	return s
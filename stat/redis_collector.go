@@ -0,0 +1,20 @@
+package stat
+
+import (
+	"context"
+
+	"github.com/bldsoft/gost/cache/v2/redis"
+)
+
+type RedisCollector struct {
+	db *redis.Storage
+}
+
+func NewRedisCollector(db *redis.Storage) *RedisCollector {
+	return &RedisCollector{db: db}
+}
+
+func (c *RedisCollector) Stat(ctx context.Context) Stat {
+	stats, err := c.db.Stats(ctx)
+	return NewStat("redis", stats, err)
+}
@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"net/http"
+)
+
+// NewTransport wraps base so that every outgoing request gets a client span
+// and the traceparent header. If base is nil, http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := Start(req.Context(), "HTTP "+req.Method, WithKind(SpanKindClient), WithAttributes(map[string]any{
+		"http.method": req.Method,
+		"http.url":    req.URL.String(),
+	}))
+	defer span.End()
+
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	return resp, nil
+}
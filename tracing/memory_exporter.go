@@ -0,0 +1,36 @@
+package tracing
+
+import (
+	"slices"
+	"sync"
+)
+
+// InMemoryExporter keeps exported spans in memory. It's intended for tests.
+type InMemoryExporter struct {
+	mtx   sync.Mutex
+	spans []SpanData
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) Export(spans ...SpanData) (n int, err error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.spans = append(e.spans, spans...)
+	return len(spans), nil
+}
+
+// Spans returns the exported spans in the order they ended.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return slices.Clone(e.spans)
+}
+
+func (e *InMemoryExporter) Reset() {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.spans = nil
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func newTraceID() (id TraceID) {
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+// SpanContext is the part of a span propagated across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	Remote  bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+const (
+	TraceparentHeader = "traceparent"
+
+	traceparentVersion = "00"
+	flagSampled        = 0x01
+)
+
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// Traceparent formats the span context as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return traceparentVersion + "-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent parses a W3C traceparent header value.
+// Versions other than 00 are accepted as long as they start with the version 00 fields.
+func ParseTraceparent(s string) (SpanContext, error) {
+	// version(2) - trace-id(32) - parent-id(16) - flags(2)
+	const size = 2 + 1 + 32 + 1 + 16 + 1 + 2
+	if len(s) < size || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	version, err := hex.DecodeString(s[:2])
+	if err != nil || version[0] == 0xff || version[0] == 0 && len(s) != size || len(s) > size && s[size] != '-' {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+
+	var sc SpanContext
+	if _, err := hex.Decode(sc.TraceID[:], []byte(s[3:35])); err != nil {
+		return SpanContext{}, fmt.Errorf("%w: %w", ErrInvalidTraceparent, err)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(s[36:52])); err != nil {
+		return SpanContext{}, fmt.Errorf("%w: %w", ErrInvalidTraceparent, err)
+	}
+	flags, err := hex.DecodeString(s[53:55])
+	if err != nil {
+		return SpanContext{}, fmt.Errorf("%w: %w", ErrInvalidTraceparent, err)
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	sc.Sampled = flags[0]&flagSampled != 0
+	sc.Remote = true
+	return sc, nil
+}
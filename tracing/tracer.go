@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/utils/exporter"
+)
+
+// Tracer creates spans and passes the ended ones to the exporter.
+type Tracer struct {
+	service  string
+	exporter exporter.Exporter[SpanData]
+}
+
+// NewTracer creates a tracer. If e is nil, spans are created and propagated, but not exported.
+func NewTracer(service string, e exporter.Exporter[SpanData]) *Tracer {
+	return &Tracer{service: service, exporter: e}
+}
+
+func (t *Tracer) export(data SpanData) {
+	if t.exporter == nil {
+		return
+	}
+	data.Service = t.service
+	if _, err := t.exporter.Export(data); err != nil {
+		log.ErrorWithFields(log.Fields{"error": err, "span": data.Name}, "Failed to export span")
+	}
+}
+
+type StartOption func(*SpanData)
+
+func WithKind(kind SpanKind) StartOption {
+	return func(d *SpanData) {
+		d.Kind = kind
+	}
+}
+
+func WithAttributes(attrs map[string]any) StartOption {
+	return func(d *SpanData) {
+		if d.Attributes == nil {
+			d.Attributes = make(map[string]any, len(attrs))
+		}
+		for k, v := range attrs {
+			d.Attributes[k] = v
+		}
+	}
+}
+
+// Start creates a span that is a child of the span (or the remote span context) stored in ctx.
+// If there is no parent, a new trace is started.
+func (t *Tracer) Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	span := &Span{tracer: t, data: SpanData{
+		Name:  name,
+		Start: time.Now(),
+	}}
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		span.data.SpanContext.TraceID = parent.TraceID
+		span.data.SpanContext.Sampled = parent.Sampled
+		span.data.ParentSpanID = parent.SpanID
+	} else {
+		span.data.SpanContext.TraceID = newTraceID()
+		span.data.SpanContext.Sampled = true
+	}
+	span.data.SpanContext.SpanID = newSpanID()
+	for _, opt := range opts {
+		opt(&span.data)
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+var defaultTracer atomic.Pointer[Tracer]
+
+func init() {
+	defaultTracer.Store(NewTracer("", nil))
+}
+
+// SetDefault sets the tracer used by Start and the gost integrations.
+func SetDefault(t *Tracer) {
+	defaultTracer.Store(t)
+}
+
+func Default() *Tracer {
+	return defaultTracer.Load()
+}
+
+// Start creates a span with the default tracer.
+func Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	return Default().Start(ctx, name, opts...)
+}
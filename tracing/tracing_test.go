@@ -0,0 +1,100 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bldsoft/gost/server/middleware"
+	"github.com/bldsoft/gost/tracing"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled)
+	assert.True(t, sc.Remote)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	sc, err = tracing.ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+	require.NoError(t, err)
+	assert.False(t, sc.Sampled)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01",
+	} {
+		_, err := tracing.ParseTraceparent(invalid)
+		assert.ErrorIs(t, err, tracing.ErrInvalidTraceparent, invalid)
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	exporter := tracing.NewInMemoryExporter()
+	prev := tracing.Default()
+	tracing.SetDefault(tracing.NewTracer("test", exporter))
+	defer tracing.SetDefault(prev)
+
+	var downstreamTraceparent string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstreamTraceparent = r.Header.Get(tracing.TraceparentHeader)
+	}))
+	defer downstream.Close()
+
+	client := &http.Client{Transport: tracing.NewTransport(nil)}
+	r := chi.NewRouter()
+	r.Use(middleware.Tracing)
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, downstream.URL, nil)
+		resp, err := client.Do(req)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 2)
+	clientSpan, server := spans[0], spans[1]
+
+	assert.Equal(t, "HTTP GET /items/{id}", server.Name)
+	assert.Equal(t, tracing.SpanKindServer, server.Kind)
+	assert.Equal(t, "test", server.Service)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", server.ParentSpanID.String())
+	assert.Equal(t, http.StatusOK, server.Attributes["http.status_code"])
+
+	assert.Equal(t, tracing.SpanKindClient, clientSpan.Kind)
+	assert.Equal(t, server.SpanContext.TraceID, clientSpan.SpanContext.TraceID)
+	assert.Equal(t, server.SpanContext.SpanID, clientSpan.ParentSpanID)
+	assert.Equal(t, clientSpan.SpanContext.Traceparent(), downstreamTraceparent)
+}
+
+func TestSpanNotSampled(t *testing.T) {
+	exporter := tracing.NewInMemoryExporter()
+	tracer := tracing.NewTracer("test", exporter)
+
+	sc, err := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.NoError(t, err)
+	_, span := tracer.Start(tracing.ContextWithRemoteSpanContext(context.Background(), sc), "op")
+	span.End()
+	assert.Empty(t, exporter.Spans())
+
+	_, span = tracer.Start(context.Background(), "op")
+	span.End()
+	span.End()
+	assert.Len(t, exporter.Spans(), 1)
+}
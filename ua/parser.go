@@ -0,0 +1,143 @@
+package ua
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"github.com/bldsoft/gost/log"
+	"github.com/bldsoft/gost/regexes"
+	"github.com/ghodss/yaml"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const DefaultCacheSize = 10_000
+
+// RuleFiles are read in this order. Every rule fills only the fields that aren't set by the previous ones,
+// e.g. the rule `browser: ""` prevents the next rules from setting the browser.
+var RuleFiles = []string{"browser.yaml", "os.yaml", "device.yaml"}
+
+type ParsedUA struct {
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browserVersion,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"osVersion,omitempty"`
+	DeviceType     string `json:"deviceType,omitempty"`
+	Brand          string `json:"brand,omitempty"`
+	Model          string `json:"model,omitempty"`
+}
+
+type Parser struct {
+	rules []*rule
+	cache *lru.Cache
+}
+
+// NewParser creates a parser using the rules embedded from regexes/ua.
+func NewParser(cacheSize int) (*Parser, error) {
+	uaFS, err := fs.Sub(regexes.UA, "ua")
+	if err != nil {
+		return nil, err
+	}
+	return NewParserFS(uaFS, cacheSize)
+}
+
+// NewParserFS creates a parser using the RuleFiles from fsys.
+func NewParserFS(fsys fs.FS, cacheSize int) (*Parser, error) {
+	if cacheSize <= 0 {
+		cacheSize = DefaultCacheSize
+	}
+	cache, err := lru.New(cacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Parser{cache: cache}
+	for _, name := range RuleFiles {
+		rules, err := loadRules(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		p.rules = append(p.rules, rules...)
+	}
+	return p, nil
+}
+
+func loadRules(fsys fs.FS, name string) ([]*rule, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	var configs []ruleConfig
+	if err := yaml.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("ua: %s: %w", name, err)
+	}
+	rules, err := compileRules(configs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return rules, nil
+}
+
+func (p *Parser) Parse(userAgent string) ParsedUA {
+	if parsed, ok := p.cache.Get(userAgent); ok {
+		return parsed.(ParsedUA)
+	}
+	parsed := p.parse(userAgent)
+	p.cache.Add(userAgent, parsed)
+	return parsed
+}
+
+func (p *Parser) parse(userAgent string) ParsedUA {
+	var res result
+	if userAgent != "" {
+		for _, r := range p.rules {
+			if r.apply(userAgent, &res, nil) && res.complete() {
+				break
+			}
+		}
+	}
+	return ParsedUA{
+		Browser:        res.values[fieldBrowser],
+		BrowserVersion: res.values[fieldBrowserVersion],
+		OS:             res.values[fieldOS],
+		OSVersion:      joinVersion(res.values[fieldOSv1 : fieldOSv4+1]...),
+		DeviceType:     res.values[fieldDeviceType],
+		Brand:          res.values[fieldBrand],
+		Model:          res.values[fieldModel],
+	}
+}
+
+// joinVersion joins the version parts until the first empty one.
+func joinVersion(parts ...string) string {
+	for i, part := range parts {
+		if part == "" {
+			parts = parts[:i]
+			break
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// UserAgentInfo is a log.UserAgentParser, see log.ExportFormatter.SetUserAgentParser.
+func (p *Parser) UserAgentInfo(userAgent string) log.UserAgentInfo {
+	return log.UserAgentInfo(p.Parse(userAgent))
+}
+
+var defaultParser = sync.OnceValue(func() *Parser {
+	p, err := NewParser(DefaultCacheSize)
+	if err != nil {
+		panic(err)
+	}
+	return p
+})
+
+// Parse parses userAgent using the default parser with the embedded rules.
+func Parse(userAgent string) ParsedUA {
+	return defaultParser().Parse(userAgent)
+}
+
+// UserAgentInfo parses userAgent using the default parser, it is a log.UserAgentParser.
+func UserAgentInfo(userAgent string) log.UserAgentInfo {
+	return defaultParser().UserAgentInfo(userAgent)
+}
@@ -0,0 +1,133 @@
+package ua
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      ParsedUA
+	}{
+		{
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want:      ParsedUA{Browser: "Chrome", BrowserVersion: "120.0.0.0", OS: "Windows", OSVersion: "10", DeviceType: "DESKTOP"},
+		},
+		{
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+			want:      ParsedUA{Browser: "Safari", BrowserVersion: "17.1", OS: "iOS", OSVersion: "17.1", DeviceType: "MOBILE", Brand: "Apple", Model: "iPhone"},
+		},
+		{
+			userAgent: "Mozilla/5.0 (Linux; Android 10; SM-G973F) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Mobile Safari/537.36",
+			want:      ParsedUA{Browser: "Chrome", BrowserVersion: "119.0.0.0", OS: "Android", OSVersion: "10", DeviceType: "MOBILE", Brand: "Samsung", Model: "SM-G973F"},
+		},
+		{
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want:      ParsedUA{Browser: "Firefox", BrowserVersion: "121.0", OS: "Mac OS X", OSVersion: "10.15", DeviceType: "DESKTOP", Brand: "Apple", Model: "Mac"},
+		},
+		{
+			userAgent: "Mozilla/5.0 (SMART-TV; LINUX; Tizen 6.0) AppleWebKit/537.36 (KHTML, like Gecko) 76.0.3809.146/6.0 TV Safari/537.36",
+			want:      ParsedUA{OS: "Tizen", OSVersion: "6.0", DeviceType: "TV", Brand: "Samsung", Model: "SMART-TV"},
+		},
+		{
+			userAgent: "curl/7.81.0",
+			want:      ParsedUA{DeviceType: "DESKTOP"},
+		},
+		{
+			userAgent: "",
+			want:      ParsedUA{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.userAgent, func(t *testing.T) {
+			assert.Equal(t, tt.want, Parse(tt.userAgent))
+		})
+	}
+}
+
+func TestParserRules(t *testing.T) {
+	fsys := fstest.MapFS{
+		"browser.yaml": {Data: []byte(`
+- regex: 'Tizen'
+  browser: ""
+- regex: 'Chrome/\d+'
+  browser: Chrome
+- regex: 'Safari/(\d+)'
+  browser: Safari
+  version: $1
+- regex: 'Chrome/(\d+)'
+  browser: Chrome
+  version: $1
+`)},
+		"os.yaml": {Data: []byte(`
+- regex: 'Android (\d+)'
+  os: Android
+  os_v1: $1
+  os_v2: 0
+`)},
+		"device.yaml": {Data: []byte(`
+- regex: 'iphone'
+  regex_flag: i
+  brand_replacement: Apple
+  device_type: MOBILE
+  versions:
+    regex: iPhone(\d+),(\d+)
+    model_replacement: iPhone$1$2
+- brand: Amazon
+  models:
+    - regex: AFTKM
+      model: Fire TV Stick 4K
+      device_type: STB
+  regex: AFT
+  device_type: TV
+`)},
+	}
+	p, err := NewParserFS(fsys, 0)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		userAgent string
+		want      ParsedUA
+	}{
+		{
+			name:      "version of another browser is ignored",
+			userAgent: "Chrome/120 Safari/537",
+			want:      ParsedUA{Browser: "Chrome", BrowserVersion: "120"},
+		},
+		{
+			name:      "empty value prevents next rules",
+			userAgent: "Tizen Chrome/76",
+		},
+		{
+			name:      "numeric values",
+			userAgent: "Android 10",
+			want:      ParsedUA{OS: "Android", OSVersion: "10.0"},
+		},
+		{
+			name:      "versions",
+			userAgent: "IPHONE iPhone12,1",
+			want:      ParsedUA{DeviceType: "MOBILE", Brand: "Apple", Model: "iPhone121"},
+		},
+		{
+			name:      "models",
+			userAgent: "AFTKM",
+			want:      ParsedUA{DeviceType: "STB", Brand: "Amazon", Model: "Fire TV Stick 4K"},
+		},
+		{
+			name:      "no matching models",
+			userAgent: "AFTXX",
+			want:      ParsedUA{DeviceType: "TV", Brand: "Amazon"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, p.Parse(tt.userAgent))
+			assert.Equal(t, tt.want, p.Parse(tt.userAgent)) // cached
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package ua
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"time"
+
+	"github.com/dlclark/regexp2"
+)
+
+type field int
+
+const (
+	fieldBrowser field = iota
+	fieldBrowserVersion
+	fieldOS
+	fieldOSv1
+	fieldOSv2
+	fieldOSv3
+	fieldOSv4
+	fieldDeviceType
+	fieldBrand
+	fieldModel
+
+	fieldCount
+)
+
+// value is a rule field value. YAML numbers (e.g. os_v1: 7) are read as strings.
+type value string
+
+func (v *value) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = value(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("ua: rule value should be a string or a number, got %s", data)
+	}
+	*v = value(n.String())
+	return nil
+}
+
+// versions is either a single rule or a list of rules.
+type versions []ruleConfig
+
+func (v *versions) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var r ruleConfig
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		*v = versions{r}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]ruleConfig)(v))
+}
+
+type ruleConfig struct {
+	Regex     string `json:"regex"`
+	RegexFlag string `json:"regex_flag"`
+
+	Browser          *value `json:"browser"`
+	Version          *value `json:"version"`
+	OS               *value `json:"os"`
+	OSv1             *value `json:"os_v1"`
+	OSv2             *value `json:"os_v2"`
+	OSv3             *value `json:"os_v3"`
+	OSv4             *value `json:"os_v4"`
+	DeviceType       *value `json:"device_type"`
+	Brand            *value `json:"brand"`
+	BrandReplacement *value `json:"brand_replacement"`
+	Model            *value `json:"model"`
+	ModelReplacement *value `json:"model_replacement"`
+
+	Models   []ruleConfig `json:"models"`
+	Versions versions     `json:"versions"`
+}
+
+func (c *ruleConfig) values() (res [fieldCount]*value) {
+	res[fieldBrowser] = c.Browser
+	res[fieldBrowserVersion] = c.Version
+	res[fieldOS] = c.OS
+	res[fieldOSv1] = c.OSv1
+	res[fieldOSv2] = c.OSv2
+	res[fieldOSv3] = c.OSv3
+	res[fieldOSv4] = c.OSv4
+	res[fieldDeviceType] = c.DeviceType
+	res[fieldBrand] = c.Brand
+	if c.BrandReplacement != nil {
+		res[fieldBrand] = c.BrandReplacement
+	}
+	res[fieldModel] = c.Model
+	if c.ModelReplacement != nil {
+		res[fieldModel] = c.ModelReplacement
+	}
+	return res
+}
+
+type matcher interface {
+	FindStringSubmatch(s string) []string
+}
+
+// backtrackingMatcher is used for the rules with lookarounds, RE2 doesn't support them.
+type backtrackingMatcher struct {
+	re *regexp2.Regexp
+}
+
+const backtrackingMatchTimeout = 10 * time.Millisecond
+
+func compileBacktracking(expr string) (*backtrackingMatcher, error) {
+	re, err := regexp2.Compile(expr, regexp2.None)
+	if err != nil {
+		return nil, err
+	}
+	re.MatchTimeout = backtrackingMatchTimeout
+	return &backtrackingMatcher{re: re}, nil
+}
+
+func (m *backtrackingMatcher) FindStringSubmatch(s string) []string {
+	match, err := m.re.FindStringMatch(s)
+	if err != nil || match == nil {
+		return nil
+	}
+	groups := match.Groups()
+	res := make([]string, len(groups))
+	for i, g := range groups {
+		res[i] = g.String()
+	}
+	return res
+}
+
+type rule struct {
+	re       matcher
+	values   [fieldCount]*value
+	models   []*rule
+	versions []*rule
+}
+
+func compile(expr string) (matcher, error) {
+	re, err := regexp.Compile(expr)
+	if err == nil {
+		return re, nil
+	}
+	if isLookaround(err) {
+		return compileBacktracking(expr)
+	}
+	return nil, err
+}
+
+func compileRule(c ruleConfig) (*rule, error) {
+	expr := c.Regex
+	if c.RegexFlag == "i" {
+		expr = "(?i)" + expr
+	}
+	re, err := compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	r := &rule{re: re, values: c.values()}
+	if r.models, err = compileRules(c.Models); err != nil {
+		return nil, err
+	}
+	if r.versions, err = compileRules(c.Versions); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func compileRules(configs []ruleConfig) ([]*rule, error) {
+	rules := make([]*rule, 0, len(configs))
+	for _, c := range configs {
+		r, err := compileRule(c)
+		if err != nil {
+			return nil, fmt.Errorf("ua: rule %q: %w", c.Regex, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func isLookaround(err error) bool {
+	var syntaxErr *syntax.Error
+	if !errors.As(err, &syntaxErr) {
+		return false
+	}
+	switch syntaxErr.Code {
+	case syntax.ErrInvalidPerlOp, syntax.ErrInvalidNamedCapture:
+		return strings.HasPrefix(syntaxErr.Expr, "(?!") ||
+			strings.HasPrefix(syntaxErr.Expr, "(?=") ||
+			strings.HasPrefix(syntaxErr.Expr, "(?<!") ||
+			strings.HasPrefix(syntaxErr.Expr, "(?<=")
+	}
+	return false
+}
+
+// dependsOn maps the field to the field it describes, e.g. the browser version makes sense only with its browser.
+var dependsOn = map[field]field{
+	fieldBrowserVersion: fieldBrowser,
+	fieldOSv1:           fieldOS,
+	fieldOSv2:           fieldOS,
+	fieldOSv3:           fieldOS,
+	fieldOSv4:           fieldOS,
+	fieldModel:          fieldBrand,
+}
+
+// apply fills the unset fields of res if the rule matches userAgent.
+// Values of the first matching model and version take precedence over the rule values.
+// A dependent field isn't filled if the field it depends on is already set to another value,
+// so the version of one browser isn't attributed to another one.
+func (r *rule) apply(userAgent string, res *result, parentValues *[fieldCount]*string) bool {
+	match := r.re.FindStringSubmatch(userAgent)
+	if match == nil {
+		return false
+	}
+
+	var values [fieldCount]*string
+	for f, v := range r.values {
+		switch {
+		case v != nil:
+			expanded := strings.TrimSpace(expand(string(*v), match))
+			values[f] = &expanded
+		case parentValues != nil:
+			values[f] = parentValues[f]
+		}
+	}
+
+	for _, sub := range [][]*rule{r.models, r.versions} {
+		for _, subRule := range sub {
+			if subRule.apply(userAgent, res, &values) {
+				break
+			}
+		}
+	}
+
+	for f, v := range values {
+		if v == nil || r.values[f] == nil || res.set[f] {
+			continue
+		}
+		if dep, ok := dependsOn[field(f)]; ok && values[dep] != nil && res.set[dep] && *values[dep] != res.values[dep] {
+			continue
+		}
+		res.values[f] = *v
+		res.set[f] = true
+	}
+	return true
+}
+
+// expand replaces $1..$9 in template with the corresponding submatches.
+func expand(template string, match []string) string {
+	if !strings.Contains(template, "$") {
+		return template
+	}
+	var sb strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] == '$' && i+1 < len(template) && '1' <= template[i+1] && template[i+1] <= '9' {
+			if n := int(template[i+1] - '0'); n < len(match) {
+				sb.WriteString(match[n])
+			}
+			i++
+			continue
+		}
+		sb.WriteByte(template[i])
+	}
+	return sb.String()
+}
+
+type result struct {
+	values [fieldCount]string
+	set    [fieldCount]bool
+}
+
+func (r *result) complete() bool {
+	for _, set := range r.set {
+		if !set {
+			return false
+		}
+	}
+	return true
+}
@@ -3,6 +3,7 @@ package exporter
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/bldsoft/gost/utils/ringbuf"
@@ -45,6 +46,30 @@ type BufferedExporter[T any] struct {
 
 	stop    chan struct{}
 	stopped chan struct{}
+
+	buffered    atomic.Int64
+	exported    atomic.Uint64
+	dropped     atomic.Uint64
+	flushErrors atomic.Uint64
+}
+
+// BufferedExporterStats is a snapshot of the exporter state.
+type BufferedExporterStats struct {
+	Queued      int    // items in the channel between writers and the exporting goroutine
+	Buffered    int    // items in the ring buffer and the batch being exported
+	Exported    uint64 // items exported since start
+	Dropped     uint64 // items discarded because the channel was full
+	FlushErrors uint64
+}
+
+func (be *BufferedExporter[T]) Stats() BufferedExporterStats {
+	return BufferedExporterStats{
+		Queued:      len(be.writeC),
+		Buffered:    int(be.buffered.Load()),
+		Exported:    be.exported.Load(),
+		Dropped:     be.dropped.Load(),
+		FlushErrors: be.flushErrors.Load(),
+	}
 }
 
 func NewBuffered[T any](
@@ -105,6 +130,7 @@ func (be *BufferedExporter[T]) writeToChan(items ...T) (n int) {
 		case be.writeC <- item:
 		default:
 			// discard: channel is full
+			be.dropped.Add(uint64(len(items) - i))
 			return i
 		}
 	}
@@ -160,6 +186,11 @@ func (be *BufferedExporter[T]) Run() error {
 
 	flush := func() error {
 		n, err := be.flush()
+		be.exported.Add(uint64(n))
+		if err != nil {
+			be.flushErrors.Add(1)
+		}
+		be.buffered.Store(int64(be.ringBuf.Len() + be.batch.Len()))
 		be.cfg.Logger.TraceOrErrorfWithFields(err, Fields{
 			"queued":   len(be.writeC),
 			"ring buf": be.ringBuf.Len(),
@@ -183,6 +214,7 @@ func (be *BufferedExporter[T]) Run() error {
 		select {
 		case item := <-be.writeC:
 			_ = be.ringBuf.Push(item)
+			be.buffered.Store(int64(be.ringBuf.Len() + be.batch.Len()))
 
 			if be.ringBuf.Len() == be.MaxBatchSize() {
 				_ = flush()
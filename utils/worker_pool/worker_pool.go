@@ -13,6 +13,7 @@ const (
 
 type WorkerPool struct {
 	workerN   int64
+	busyN     atomic.Int64
 	taskC     chan func()
 	taskCOnce sync.Once
 
@@ -54,6 +55,16 @@ func (wp *WorkerPool) WorkerN() int64 {
 	return atomic.LoadInt64(&wp.workerN)
 }
 
+// BusyN returns the number of workers executing a task.
+func (wp *WorkerPool) BusyN() int64 {
+	return wp.busyN.Load()
+}
+
+// QueueLen returns the number of tasks waiting for a worker.
+func (wp *WorkerPool) QueueLen() int {
+	return len(wp.taskChan())
+}
+
 func (wp *WorkerPool) taskChan() chan func() {
 	wp.taskCOnce.Do(func() {
 		if wp.taskC == nil {
@@ -85,12 +96,19 @@ func (wp *WorkerPool) startWorker() {
 				if !ok {
 					return
 				}
-				f()
+				wp.run(f)
 			}
 		}
 	}()
 }
 
+// run executes the task, the busy workers counter is restored even if the task panics.
+func (wp *WorkerPool) run(f func()) {
+	wp.busyN.Add(1)
+	defer wp.busyN.Add(-1)
+	f()
+}
+
 func (wp *WorkerPool) CloseAndWait() {
 	wp.Close()
 	wp.Wait()